@@ -29,8 +29,17 @@ func NewApp() (*App, error) {
 	}
 
 	// Initialize
-	services := initServices(store)
+	services, err := initServices(store)
+	if err != nil {
+		return nil, err
+	}
+
 	handlers := initHandlers(services)
+	middleware.UseSessionValidator(services.SessionService)
+	services.NotificationService.StartPruning()
+	services.WebhookService.StartDelivering()
+	services.EventBus.StartDispatching()
+	services.AttachmentService.StartCollecting()
 	router := setupRouter(handlers)
 
 	return &App{
@@ -55,30 +64,120 @@ func initStorage() (*storage.PostgresStore, error) {
 }
 
 type Services struct {
-	IdeaService *service.IdeaService
-	UserService *service.UserService
-	VoteService *service.VoteService
+	IdeaService         *service.IdeaService
+	UserService         *service.UserService
+	VoteService         *service.VoteService
+	OIDCService         *service.OIDCService
+	SessionService      *service.SessionService
+	InviteService       *service.InviteService
+	CommentService      *service.CommentService
+	RoadmapService      *service.RoadmapService
+	TechStackService    *service.TechStackService
+	TagService          *service.TagService
+	FollowService       *service.FollowService
+	NotificationService *service.NotificationService
+	WebhookService      *service.WebhookService
+	FeedService         *service.FeedService
+	AttachmentService   *service.AttachmentService
+	BoardService        *service.BoardService
+	MilestoneService    *service.MilestoneService
+	AssigneeService     *service.AssigneeService
+	EventBus            *service.EventBus
 }
 
-func initServices(store *storage.PostgresStore) *Services {
-	return &Services{
-		IdeaService: service.NewIdeaService(store),
-		UserService: service.NewUserService(store),
-		VoteService: service.NewVoteService(store),
+func initServices(store *storage.PostgresStore) (*Services, error) {
+	passwordService, err := service.NewPasswordService(config.NewPasswordConfig())
+	if err != nil {
+		return nil, err
 	}
+
+	attachmentConfig := config.NewAttachmentConfig()
+	blobs, err := storage.NewLocalBlobStore(attachmentConfig.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	eventBus := service.NewEventBus(store, config.NewEventConfig())
+	sessionService := service.NewSessionService(store)
+	techStackService := service.NewTechStackService(store)
+	userService := service.NewUserService(store, sessionService, passwordService, eventBus, config.NewAuthConfig())
+	notificationService := service.NewNotificationService(store, store, store, store, config.NewNotificationConfig())
+	webhookService := service.NewWebhookService(store, store, nil, config.NewWebhookConfig())
+	followService := service.NewFollowService(store, store, store)
+	boardService := service.NewBoardService(store, store)
+
+	// Subscribers react to domain events, the services raising them don't
+	// know about them
+	followService.Subscribe(eventBus)
+	notificationService.Subscribe(eventBus)
+	webhookService.Subscribe(eventBus)
+
+	return &Services{
+		IdeaService:         service.NewIdeaService(store, store, store, boardService, techStackService, eventBus, config.NewWorkflowConfig()),
+		UserService:         userService,
+		VoteService:         service.NewVoteService(store, store, boardService, eventBus),
+		OIDCService:         service.NewOIDCService(store, userService, config.NewOIDCConfig(), nil),
+		SessionService:      sessionService,
+		InviteService:       service.NewInviteService(store),
+		CommentService:      service.NewCommentService(store, store, eventBus),
+		RoadmapService:      service.NewRoadmapService(store, store, config.NewRoadmapConfig()),
+		TechStackService:    techStackService,
+		TagService:          service.NewTagService(store),
+		FollowService:       followService,
+		NotificationService: notificationService,
+		WebhookService:      webhookService,
+		FeedService:         service.NewFeedService(store, store, config.NewFeedConfig()),
+		AttachmentService:   service.NewAttachmentService(store, store, blobs, attachmentConfig),
+		BoardService:        boardService,
+		MilestoneService:    service.NewMilestoneService(store, store, config.NewFeedConfig()),
+		AssigneeService:     service.NewAssigneeService(store, store, store),
+		EventBus:            eventBus,
+	}, nil
 }
 
 type Handlers struct {
-	IdeaHandler *handler.IdeaHandler
-	AuthHandler *handler.AuthHandler
-	VoteHandler *handler.VoteHandler
+	IdeaHandler         *handler.IdeaHandler
+	AuthHandler         *handler.AuthHandler
+	VoteHandler         *handler.VoteHandler
+	OIDCHandler         *handler.OIDCHandler
+	SessionHandler      *handler.SessionHandler
+	InviteHandler       *handler.InviteHandler
+	CommentHandler      *handler.CommentHandler
+	RoadmapHandler      *handler.RoadmapHandler
+	TechStackHandler    *handler.TechStackHandler
+	TagHandler          *handler.TagHandler
+	FollowHandler       *handler.FollowHandler
+	NotificationHandler *handler.NotificationHandler
+	WebhookHandler      *handler.WebhookHandler
+	FeedHandler         *handler.FeedHandler
+	AttachmentHandler   *handler.AttachmentHandler
+	MarkdownHandler     *handler.MarkdownHandler
+	BoardHandler        *handler.BoardHandler
+	MilestoneHandler    *handler.MilestoneHandler
+	AssigneeHandler     *handler.AssigneeHandler
 }
 
 func initHandlers(services *Services) *Handlers {
 	return &Handlers{
-		IdeaHandler: handler.NewIdeaHandler(services.IdeaService),
-		AuthHandler: handler.NewAuthHandler(services.UserService),
-		VoteHandler: handler.NewVoteHandler(services.VoteService),
+		IdeaHandler:         handler.NewIdeaHandler(services.IdeaService),
+		AuthHandler:         handler.NewAuthHandler(services.UserService),
+		VoteHandler:         handler.NewVoteHandler(services.VoteService),
+		OIDCHandler:         handler.NewOIDCHandler(services.OIDCService),
+		SessionHandler:      handler.NewSessionHandler(services.SessionService, services.UserService),
+		InviteHandler:       handler.NewInviteHandler(services.InviteService),
+		CommentHandler:      handler.NewCommentHandler(services.CommentService),
+		RoadmapHandler:      handler.NewRoadmapHandler(services.RoadmapService),
+		TechStackHandler:    handler.NewTechStackHandler(services.TechStackService),
+		TagHandler:          handler.NewTagHandler(services.TagService),
+		FollowHandler:       handler.NewFollowHandler(services.FollowService),
+		NotificationHandler: handler.NewNotificationHandler(services.NotificationService),
+		WebhookHandler:      handler.NewWebhookHandler(services.WebhookService),
+		FeedHandler:         handler.NewFeedHandler(services.FeedService),
+		AttachmentHandler:   handler.NewAttachmentHandler(services.AttachmentService),
+		MarkdownHandler:     handler.NewMarkdownHandler(),
+		BoardHandler:        handler.NewBoardHandler(services.BoardService),
+		MilestoneHandler:    handler.NewMilestoneHandler(services.MilestoneService),
+		AssigneeHandler:     handler.NewAssigneeHandler(services.AssigneeService),
 	}
 }
 
@@ -86,7 +185,7 @@ func setupRouter(handlers *Handlers) *http.ServeMux {
 	router := http.NewServeMux()
 
 	// API routes
-	v1Routes := rt.SetupRoutes(handlers.IdeaHandler, handlers.AuthHandler, handlers.VoteHandler)
+	v1Routes := rt.SetupRoutes(handlers.IdeaHandler, handlers.AuthHandler, handlers.VoteHandler, handlers.OIDCHandler, handlers.SessionHandler, handlers.InviteHandler, handlers.CommentHandler, handlers.RoadmapHandler, handlers.TechStackHandler, handlers.TagHandler, handlers.FollowHandler, handlers.NotificationHandler, handlers.WebhookHandler, handlers.FeedHandler, handlers.AttachmentHandler, handlers.MarkdownHandler, handlers.BoardHandler, handlers.MilestoneHandler, handlers.AssigneeHandler)
 	router.Handle("/v1/", http.StripPrefix("/v1", middleware.CORS(middleware.Logging(v1Routes))))
 
 	// Swagger documentation
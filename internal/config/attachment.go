@@ -0,0 +1,31 @@
+package config
+
+import (
+	utils "test_project/test/pkg"
+	"time"
+)
+
+const defaultAttachmentTypes = "image/png,image/jpeg,image/gif,image/webp,application/pdf,text/plain"
+
+// AttachmentConfig limits uploads to ideas. Types are checked against the
+// sniffed content type. Blobs no attachment refers to are deleted once they
+// are older than GCGrace, which covers uploads still being recorded.
+type AttachmentConfig struct {
+	Dir          string
+	MaxSize      int64
+	MaxPerIdea   int
+	AllowedTypes []string
+	GCInterval   time.Duration
+	GCGrace      time.Duration
+}
+
+func NewAttachmentConfig() AttachmentConfig {
+	return AttachmentConfig{
+		Dir:          utils.GetEnvOrDefault("ATTACHMENT_DIR", "data/attachments"),
+		MaxSize:      int64(parseInt("ATTACHMENT_MAX_SIZE", 10<<20)),
+		MaxPerIdea:   parseInt("ATTACHMENT_MAX_PER_IDEA", 20),
+		AllowedTypes: parseList(utils.GetEnvOrDefault("ATTACHMENT_TYPES", defaultAttachmentTypes)),
+		GCInterval:   parseDuration("ATTACHMENT_GC_INTERVAL", time.Hour),
+		GCGrace:      parseDuration("ATTACHMENT_GC_GRACE", time.Hour),
+	}
+}
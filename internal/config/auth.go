@@ -0,0 +1,96 @@
+package config
+
+import (
+	"strings"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"time"
+)
+
+type AuthConfig struct {
+	TokenTTL          time.Duration
+	TOTPIssuer        string
+	TOTPChallengeTTL  time.Duration
+	TOTPRequiredRoles []model.Role
+	RecoveryCodeCount int
+	// Wrong 2FA codes in a row before the account's 2FA is locked for TOTPLockout
+	TOTPMaxFailures int
+	TOTPLockout     time.Duration
+	// What happens to ideas and votes of deleted accounts, and who receives
+	// them when the policy is reassign
+	DeletionPolicy     model.DeletionPolicy
+	DeletionReassignTo string
+	// Who may register. In domain-restricted mode the email domain must be in
+	// AllowedEmailDomains, a valid invite code lets anyone in.
+	RegistrationMode    model.RegistrationMode
+	AllowedEmailDomains []string
+}
+
+func NewAuthConfig() AuthConfig {
+	return AuthConfig{
+		TokenTTL:            parseDuration("AUTH_TOKEN_TTL", 24*time.Hour),
+		TOTPIssuer:          utils.GetEnvOrDefault("TOTP_ISSUER", "Go Ideas API"),
+		TOTPChallengeTTL:    parseDuration("TOTP_CHALLENGE_TTL", 5*time.Minute),
+		TOTPRequiredRoles:   parseRoles(utils.GetEnvOrDefault("TOTP_REQUIRED_ROLES", "admin")),
+		RecoveryCodeCount:   10,
+		TOTPMaxFailures:     parseInt("TOTP_MAX_FAILURES", 5),
+		TOTPLockout:         parseDuration("TOTP_LOCKOUT", 15*time.Minute),
+		DeletionPolicy:      model.DeletionPolicy(utils.GetEnvOrDefault("ACCOUNT_DELETION_POLICY", string(model.DeletionAnonymize))),
+		DeletionReassignTo:  utils.GetEnvOrDefault("ACCOUNT_DELETION_REASSIGN_TO", ""),
+		RegistrationMode:    model.RegistrationMode(utils.GetEnvOrDefault("REGISTRATION_MODE", string(model.RegistrationOpen))),
+		AllowedEmailDomains: parseList(strings.ToLower(utils.GetEnvOrDefault("REGISTRATION_ALLOWED_DOMAINS", ""))),
+	}
+}
+
+// RequiresTOTP reports whether users with this role must have 2FA enabled
+func (c AuthConfig) RequiresTOTP(role model.Role) bool {
+	for _, r := range c.TOTPRequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// EmailDomainAllowed matches the domain exactly or as a parent domain, so
+// "example.com" also allows "eu.example.com"
+func (c AuthConfig) EmailDomainAllowed(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range c.AllowedEmailDomains {
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseDuration(key string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(utils.GetEnvOrDefault(key, fallback.String()))
+	if err != nil {
+		return fallback
+	}
+	return d
+}
+
+func parseRoles(value string) []model.Role {
+	var roles []model.Role
+	for _, r := range parseList(value) {
+		roles = append(roles, model.Role(r))
+	}
+	return roles
+}
+
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+// EventConfig controls dispatching of the outbox. A failed event is retried
+// after RetryBackoff * 2^(attempts-1), at most MaxRetryBackoff, until every
+// subscriber handled it. Dispatched events are kept for Retention.
+type EventConfig struct {
+	PollInterval    time.Duration
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	Retention       time.Duration
+}
+
+func NewEventConfig() EventConfig {
+	return EventConfig{
+		PollInterval:    parseDuration("EVENT_POLL_INTERVAL", time.Second),
+		RetryBackoff:    parseDuration("EVENT_RETRY_BACKOFF", 5*time.Second),
+		MaxRetryBackoff: parseDuration("EVENT_MAX_RETRY_BACKOFF", 10*time.Minute),
+		Retention:       parseDuration("EVENT_RETENTION", 7*24*time.Hour),
+	}
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"strings"
+	utils "test_project/test/pkg"
+)
+
+// FeedConfig describes the Atom and RSS feeds. Feeds need absolute links, so
+// BaseURL is where the API is reachable from outside.
+type FeedConfig struct {
+	BaseURL string
+	Title   string
+	Limit   int
+}
+
+func NewFeedConfig() FeedConfig {
+	limit := parseInt("FEED_LIMIT", 50)
+	if limit < 1 {
+		limit = 50
+	}
+
+	return FeedConfig{
+		BaseURL: strings.TrimRight(utils.GetEnvOrDefault("FEED_BASE_URL", "http://localhost:8080"), "/"),
+		Title:   utils.GetEnvOrDefault("FEED_TITLE", "Go Ideas"),
+		Limit:   limit,
+	}
+}
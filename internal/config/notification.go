@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+// NotificationConfig is the retention policy of the inbox. Read notifications
+// go after ReadRetention, all of them after Retention.
+type NotificationConfig struct {
+	Retention     time.Duration
+	ReadRetention time.Duration
+	PruneInterval time.Duration
+}
+
+func NewNotificationConfig() NotificationConfig {
+	return NotificationConfig{
+		Retention:     parseDuration("NOTIFICATION_RETENTION", 90*24*time.Hour),
+		ReadRetention: parseDuration("NOTIFICATION_READ_RETENTION", 30*24*time.Hour),
+		PruneInterval: parseDuration("NOTIFICATION_PRUNE_INTERVAL", time.Hour),
+	}
+}
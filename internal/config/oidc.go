@@ -0,0 +1,30 @@
+package config
+
+import (
+	utils "test_project/test/pkg"
+	"time"
+)
+
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	StateTTL     time.Duration
+}
+
+func NewOIDCConfig() OIDCConfig {
+	return OIDCConfig{
+		Issuer:       utils.GetEnvOrDefault("OIDC_ISSUER", ""),
+		ClientID:     utils.GetEnvOrDefault("OIDC_CLIENT_ID", ""),
+		ClientSecret: utils.GetEnvOrDefault("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  utils.GetEnvOrDefault("OIDC_REDIRECT_URL", "http://localhost:8080/v1/auth/oidc/callback"),
+		Scopes:       parseList(utils.GetEnvOrDefault("OIDC_SCOPES", "openid,email,profile")),
+		StateTTL:     parseDuration("OIDC_STATE_TTL", 10*time.Minute),
+	}
+}
+
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != "" && c.ClientID != ""
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"strconv"
+	utils "test_project/test/pkg"
+)
+
+type PasswordConfig struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	BreachedList  string
+	Algorithm     string
+	BcryptCost    int
+	Argon2Memory  uint32
+	Argon2Time    uint32
+	Argon2Threads uint8
+	Argon2KeyLen  uint32
+	Argon2SaltLen uint32
+}
+
+func NewPasswordConfig() PasswordConfig {
+	return PasswordConfig{
+		MinLength:     parseInt("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:     parseInt("PASSWORD_MAX_LENGTH", 128),
+		RequireUpper:  utils.GetEnvOrDefault("PASSWORD_REQUIRE_UPPER", "false") == "true",
+		RequireLower:  utils.GetEnvOrDefault("PASSWORD_REQUIRE_LOWER", "false") == "true",
+		RequireDigit:  utils.GetEnvOrDefault("PASSWORD_REQUIRE_DIGIT", "false") == "true",
+		RequireSymbol: utils.GetEnvOrDefault("PASSWORD_REQUIRE_SYMBOL", "false") == "true",
+		BreachedList:  utils.GetEnvOrDefault("PASSWORD_BREACHED_LIST", ""),
+		Algorithm:     utils.GetEnvOrDefault("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		BcryptCost:    parseInt("PASSWORD_BCRYPT_COST", 12),
+		// RFC 9106 second recommended option, 64 MiB and 3 passes
+		Argon2Memory:  uint32(parseInt("PASSWORD_ARGON2_MEMORY_KIB", 64*1024)),
+		Argon2Time:    uint32(parseInt("PASSWORD_ARGON2_TIME", 3)),
+		Argon2Threads: uint8(parseInt("PASSWORD_ARGON2_THREADS", 4)),
+		Argon2KeyLen:  32,
+		Argon2SaltLen: 16,
+	}
+}
+
+func parseInt(key string, fallback int) int {
+	value, err := strconv.Atoi(utils.GetEnvOrDefault(key, strconv.Itoa(fallback)))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
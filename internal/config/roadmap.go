@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+)
+
+const defaultRoadmapColumns = "Under review=requested,reviewing;" +
+	"Planned=planned;" +
+	"In progress=in-progress;" +
+	"Shipped=published"
+
+// Orderings for ideas that have not been ranked by hand
+const (
+	RoadmapSortVotes   = "votes"
+	RoadmapSortNewest  = "newest"
+	RoadmapSortUpdated = "updated"
+)
+
+type RoadmapColumn struct {
+	Key      string
+	Name     string
+	Statuses []model.RequestStatus
+}
+
+// RoadmapConfig describes the public roadmap. Ideas whose status is in no
+// column, rejected ones by default, are left out.
+type RoadmapConfig struct {
+	Columns []RoadmapColumn
+	Sort    string
+}
+
+// NewRoadmapConfig reads ROADMAP_COLUMNS, written as
+// "Name=status,status;Name=status", and ROADMAP_SORT. A malformed definition
+// is logged and the default columns are used instead.
+func NewRoadmapConfig() RoadmapConfig {
+	value := utils.GetEnvOrDefault("ROADMAP_COLUMNS", defaultRoadmapColumns)
+	columns, err := parseRoadmapColumns(value)
+	if err != nil {
+		log.Printf("ROADMAP_COLUMNS %q rejected, using the default columns: %v", value, err)
+		columns, _ = parseRoadmapColumns(defaultRoadmapColumns)
+	}
+
+	sort := utils.GetEnvOrDefault("ROADMAP_SORT", RoadmapSortVotes)
+	if sort != RoadmapSortNewest && sort != RoadmapSortUpdated {
+		sort = RoadmapSortVotes
+	}
+
+	return RoadmapConfig{Columns: columns, Sort: sort}
+}
+
+// Column finds a column by its key
+func (c RoadmapConfig) Column(key string) (RoadmapColumn, bool) {
+	for _, column := range c.Columns {
+		if column.Key == key {
+			return column, true
+		}
+	}
+	return RoadmapColumn{}, false
+}
+
+func parseRoadmapColumns(value string) ([]RoadmapColumn, error) {
+	var columns []RoadmapColumn
+	for _, def := range strings.Split(value, ";") {
+		if strings.TrimSpace(def) == "" {
+			continue
+		}
+
+		name, statuses, found := strings.Cut(def, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("column %q is not written as Name=status", def)
+		}
+
+		column := RoadmapColumn{Key: columnKey(name), Name: name}
+		for _, s := range parseList(statuses) {
+			status := model.RequestStatus(s)
+			if !utils.IsValidRequestStatus(status) {
+				return nil, fmt.Errorf("unknown status %q", status)
+			}
+			column.Statuses = append(column.Statuses, status)
+		}
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("no columns")
+	}
+	return columns, nil
+}
+
+// columnKey turns "In progress" into "in-progress" for use in URLs
+func columnKey(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), "-")
+}
@@ -0,0 +1,26 @@
+package config
+
+import "time"
+
+// WebhookConfig controls delivery of webhooks. Attempt n is retried after
+// RetryBackoff * 2^(n-1), at most MaxRetryBackoff. After DisableAfter
+// deliveries in a row used up their attempts the webhook is disabled.
+type WebhookConfig struct {
+	Timeout         time.Duration
+	MaxAttempts     int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	DisableAfter    int
+	PollInterval    time.Duration
+}
+
+func NewWebhookConfig() WebhookConfig {
+	return WebhookConfig{
+		Timeout:         parseDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		MaxAttempts:     parseInt("WEBHOOK_MAX_ATTEMPTS", 6),
+		RetryBackoff:    parseDuration("WEBHOOK_RETRY_BACKOFF", 30*time.Second),
+		MaxRetryBackoff: parseDuration("WEBHOOK_MAX_RETRY_BACKOFF", time.Hour),
+		DisableAfter:    parseInt("WEBHOOK_DISABLE_AFTER", 5),
+		PollInterval:    parseDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+	}
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+)
+
+// defaultTransitions is the idea workflow used when STATUS_TRANSITIONS is not
+// set. Rejected ideas can be reopened for review, published is final.
+const defaultTransitions = "requested>reviewing,rejected;" +
+	"reviewing>planned,rejected,requested;" +
+	"planned>in-progress,reviewing,rejected;" +
+	"in-progress>published,planned,rejected;" +
+	"rejected>reviewing"
+
+// WorkflowConfig is the state machine ideas move through. Transitions maps a
+// status to the statuses it may move to, moving to a status listed in
+// ReasonRequired needs a reason.
+type WorkflowConfig struct {
+	Transitions    map[model.RequestStatus][]model.RequestStatus
+	ReasonRequired []model.RequestStatus
+}
+
+// NewWorkflowConfig reads STATUS_TRANSITIONS, written as
+// "from>to,to;from>to", and STATUS_REASON_REQUIRED, a comma separated list.
+// A malformed definition is logged and the default workflow is used instead.
+func NewWorkflowConfig() WorkflowConfig {
+	value := utils.GetEnvOrDefault("STATUS_TRANSITIONS", defaultTransitions)
+	transitions, err := parseTransitions(value)
+	if err != nil {
+		log.Printf("STATUS_TRANSITIONS %q rejected, using the default workflow: %v", value, err)
+		transitions, _ = parseTransitions(defaultTransitions)
+	}
+
+	var reasonRequired []model.RequestStatus
+	for _, s := range parseList(utils.GetEnvOrDefault("STATUS_REASON_REQUIRED", string(model.Rejected))) {
+		reasonRequired = append(reasonRequired, model.RequestStatus(s))
+	}
+
+	return WorkflowConfig{
+		Transitions:    transitions,
+		ReasonRequired: reasonRequired,
+	}
+}
+
+// Allows reports whether an idea may move from one status to another.
+// Staying in the same status is always allowed.
+func (c WorkflowConfig) Allows(from, to model.RequestStatus) bool {
+	if from == to {
+		return true
+	}
+	for _, s := range c.Transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (c WorkflowConfig) RequiresReason(to model.RequestStatus) bool {
+	for _, s := range c.ReasonRequired {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTransitions(value string) (map[model.RequestStatus][]model.RequestStatus, error) {
+	transitions := map[model.RequestStatus][]model.RequestStatus{}
+	for _, rule := range strings.Split(value, ";") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+
+		from, targets, found := strings.Cut(rule, ">")
+		status := model.RequestStatus(strings.TrimSpace(from))
+		if !found {
+			return nil, fmt.Errorf("rule %q has no '>'", rule)
+		}
+		if !utils.IsValidRequestStatus(status) {
+			return nil, fmt.Errorf("unknown status %q", status)
+		}
+
+		for _, t := range parseList(targets) {
+			target := model.RequestStatus(t)
+			if !utils.IsValidRequestStatus(target) {
+				return nil, fmt.Errorf("unknown status %q", target)
+			}
+			transitions[status] = append(transitions[status], target)
+		}
+	}
+	return transitions, nil
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"test_project/test/internal/model"
+	"test_project/test/internal/service"
+	utils "test_project/test/pkg"
+)
+
+// GetMe godoc
+// @Summary Get the current account
+// @Tags Account
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.User
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /auth/me [get]
+func (h *AuthHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := utils.ExtractUserIDFromToken(r)
+	if err != nil {
+		h.sendError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result := h.userService.GetUserByID(userID)
+	if result.Err != nil {
+		h.sendError(w, result.Err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// UpdateMe godoc
+// @Summary Update the current account
+// @Description Changes email, display name and auto-follow preferences. Omitted fields are left as they are. Changing the email needs currentPassword and marks the new email as unverified.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.UpdateProfileRequest true "Fields to change"
+// @Success 200 {object} model.User
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized or current password is wrong"
+// @Failure 409 {object} map[string]string "Email already in use"
+// @Router /auth/me [patch]
+func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := utils.ExtractUserIDFromToken(r)
+	if err != nil {
+		h.sendError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := h.userService.UpdateProfile(userID, req)
+	if result.Err != nil {
+		if errors.Is(result.Err, service.ErrEmailTaken) {
+			h.sendError(w, result.Err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(result.Err, service.ErrInvalidCredentials) {
+			h.sendError(w, result.Err.Error(), http.StatusUnauthorized)
+			return
+		}
+		h.sendError(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// ChangePassword godoc
+// @Summary Change the current account's password
+// @Description Other sessions are logged out, the current one stays valid
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]string "Password changed"
+// @Failure 400 {object} map[string]string "Bad request or new password does not meet the policy"
+// @Failure 401 {object} map[string]string "Current password is wrong"
+// @Router /auth/me/password [post]
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := utils.ExtractUserIDFromToken(r)
+	if err != nil {
+		h.sendError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := utils.ExtractSessionIDFromToken(r)
+	if err != nil {
+		h.sendError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userService.ChangePassword(userID, sessionID, req); err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			h.sendError(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, service.ErrWeakPassword) {
+			h.sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password changed successfully"})
+}
+
+// DeleteMe godoc
+// @Summary Delete the current account
+// @Description Deletes the account. Ideas and votes are anonymized, reassigned or deleted depending on ACCOUNT_DELETION_POLICY.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.DeleteAccountRequest true "Password confirmation"
+// @Success 200 {object} map[string]string "Account deleted"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Wrong password"
+// @Router /auth/me [delete]
+func (h *AuthHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := utils.ExtractUserIDFromToken(r)
+	if err != nil {
+		h.sendError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userService.DeleteAccount(userID, req.Password); err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			h.sendError(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "User deleted successfully"})
+}
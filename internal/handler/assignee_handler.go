@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"test_project/test/internal/service"
+	utils "test_project/test/pkg"
+
+	"github.com/google/uuid"
+)
+
+type AssigneeHandler struct {
+	service *service.AssigneeService
+}
+
+func NewAssigneeHandler(service *service.AssigneeService) *AssigneeHandler {
+	return &AssigneeHandler{service: service}
+}
+
+// AssignIdea godoc
+// @Summary Assign a maintainer to an idea
+// @Description Maintainers only, and only maintainers can be assigned. Assigning twice is fine. The change is recorded in the idea's history.
+// @Tags Assignees
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Idea ID"
+// @Param username path string true "Username of the assignee"
+// @Success 200 {object} map[string]string "Assigned"
+// @Failure 400 {object} map[string]string "Invalid ID format or not a maintainer"
+// @Failure 403 {object} map[string]string "Not a maintainer"
+// @Failure 404 {object} map[string]string "Idea or user not found"
+// @Failure 409 {object} map[string]string "Idea was merged"
+// @Router /idea/{id}/assignees/{username} [put]
+func (h *AssigneeHandler) AssignIdea(w http.ResponseWriter, r *http.Request) {
+	h.setAssignee(w, r, true)
+}
+
+// UnassignIdea godoc
+// @Summary Remove an assignee from an idea
+// @Description Maintainers only. The change is recorded in the idea's history.
+// @Tags Assignees
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Idea ID"
+// @Param username path string true "Username of the assignee"
+// @Success 200 {object} map[string]string "Unassigned"
+// @Failure 400 {object} map[string]string "Invalid ID format"
+// @Failure 403 {object} map[string]string "Not a maintainer"
+// @Failure 404 {object} map[string]string "Idea or user not found"
+// @Failure 409 {object} map[string]string "Idea was merged"
+// @Router /idea/{id}/assignees/{username} [delete]
+func (h *AssigneeHandler) UnassignIdea(w http.ResponseWriter, r *http.Request) {
+	h.setAssignee(w, r, false)
+}
+
+// GetAssigned godoc
+// @Summary Ideas the current user is assigned to
+// @Description Most recently assigned first
+// @Tags Assignees
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.Idea
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /me/assigned [get]
+func (h *AssigneeHandler) GetAssigned(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result := h.service.GetAssigned(actor)
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+func (h *AssigneeHandler) setAssignee(w http.ResponseWriter, r *http.Request, assign bool) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ideaID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+	username := r.PathValue("username")
+
+	message := "Idea assigned"
+	if assign {
+		err = h.service.Assign(ideaID, username, actor)
+	} else {
+		message = "Idea unassigned"
+		err = h.service.Unassign(ideaID, username, actor)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrIdeaNotFound), errors.Is(err, service.ErrUserNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrIdeaMerged):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, service.ErrInvalidAssignee):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
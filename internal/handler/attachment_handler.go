@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"test_project/test/internal/service"
+	utils "test_project/test/pkg"
+
+	"github.com/google/uuid"
+)
+
+// Room for the multipart headers around the file in an upload
+const multipartOverhead = 64 << 10
+
+type AttachmentHandler struct {
+	service *service.AttachmentService
+}
+
+func NewAttachmentHandler(service *service.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{service: service}
+}
+
+// GetAttachments godoc
+// @Summary List the attachments of an idea
+// @Tags Attachments
+// @Produce json
+// @Param id path string true "Idea ID"
+// @Success 200 {array} model.Attachment
+// @Failure 400 {object} map[string]string "Invalid ID format"
+// @Failure 404 {object} map[string]string "Idea not found"
+// @Router /idea/{id}/attachments [get]
+func (h *AttachmentHandler) GetAttachments(w http.ResponseWriter, r *http.Request) {
+	ideaID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid idea ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	viewer, _ := utils.ExtractActorFromToken(r)
+
+	result := h.service.GetAttachments(ideaID, viewer)
+	if result.Err != nil {
+		sendAttachmentError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// UploadAttachment godoc
+// @Summary Attach a file to an idea
+// @Description Multipart upload with the file in the "file" field. The content type is sniffed from the content and has to be in ATTACHMENT_TYPES.
+// @Tags Attachments
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Idea ID"
+// @Param file formData file true "The file"
+// @Success 201 {object} model.Attachment
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Idea not found"
+// @Failure 409 {object} map[string]string "Idea has too many attachments or was merged"
+// @Failure 413 {object} map[string]string "File too large"
+// @Failure 415 {object} map[string]string "File type not allowed"
+// @Router /idea/{id}/attachments [post]
+func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ideaID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid idea ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.service.MaxSize()+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("expected a multipart upload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// The file is streamed to the blob store, other fields are skipped
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			http.Error(w, `missing "file" field`, http.StatusBadRequest)
+			return
+		}
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			sendAttachmentError(w, err)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read upload: %v", err), http.StatusBadRequest)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		result := h.service.Upload(ideaID, actor, part.FileName(), part)
+		part.Close()
+		if result.Err != nil {
+			sendAttachmentError(w, result.Err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(result.Data)
+		return
+	}
+}
+
+// DownloadAttachment godoc
+// @Summary Download an attachment
+// @Description Supports range requests and conditional GET
+// @Tags Attachments
+// @Produce octet-stream
+// @Param id path string true "Idea ID"
+// @Param attachmentId path string true "Attachment ID"
+// @Success 200 {file} file "The file"
+// @Success 206 {file} file "The requested range"
+// @Failure 400 {object} map[string]string "Invalid ID format"
+// @Failure 404 {object} map[string]string "Attachment not found"
+// @Router /idea/{id}/attachments/{attachmentId} [get]
+func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	ideaID, attachmentID, err := attachmentPathIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewer, _ := utils.ExtractActorFromToken(r)
+
+	attachment, content, err := h.service.Open(ideaID, attachmentID, viewer)
+	if err != nil {
+		sendAttachmentError(w, err)
+		return
+	}
+	defer content.Close()
+
+	// Served as a download with the sniffed type, so a browser never renders
+	// an upload as part of the site
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("ETag", `"`+attachment.SHA256+`"`)
+	http.ServeContent(w, r, attachment.Filename, attachment.CreatedAt, content)
+}
+
+// DeleteAttachment godoc
+// @Summary Delete an attachment
+// @Description Only the uploader or a moderator can delete an attachment
+// @Tags Attachments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Idea ID"
+// @Param attachmentId path string true "Attachment ID"
+// @Success 200 {object} map[string]string "Attachment deleted"
+// @Failure 400 {object} map[string]string "Invalid ID format"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Not the uploader or a moderator"
+// @Failure 404 {object} map[string]string "Attachment not found"
+// @Router /idea/{id}/attachments/{attachmentId} [delete]
+func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ideaID, attachmentID, err := attachmentPathIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Delete(ideaID, attachmentID, actor); err != nil {
+		sendAttachmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Attachment deleted"})
+}
+
+func attachmentPathIDs(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	ideaID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid idea ID format: %v", err)
+	}
+	attachmentID, err := uuid.Parse(r.PathValue("attachmentId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid attachment ID format: %v", err)
+	}
+	return ideaID, attachmentID, nil
+}
+
+func sendAttachmentError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, service.ErrIdeaNotFound), errors.Is(err, service.ErrAttachmentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrAttachmentForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrIdeaMerged), errors.Is(err, service.ErrTooManyAttachments):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrAttachmentTooLarge), errors.As(err, &tooLarge):
+		http.Error(w, service.ErrAttachmentTooLarge.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, service.ErrAttachmentType):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, service.ErrEmptyAttachment):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
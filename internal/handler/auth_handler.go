@@ -2,14 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"test_project/test/internal/model"
 	"test_project/test/internal/service"
 	utils "test_project/test/pkg"
-	"time"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/golang-jwt/jwt/v4"
 )
 
 type AuthHandler struct {
@@ -38,7 +37,15 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.userService.CreateUser(req); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, service.ErrRegistrationClosed), errors.Is(err, service.ErrEmailDomain),
+			errors.Is(err, service.ErrInvalidInvite):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, service.ErrWeakPassword):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -60,35 +67,25 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	valid, err := h.userService.ValidateCredentials(req.Username, req.Password)
-	if err != nil || !valid {
-		h.sendError(w, "Invalid credentials", http.StatusUnauthorized)
-		return
-	}
-
-	// Get user to get their ID
-	result := h.userService.GetUserByUsername(req.Username)
+	result := h.userService.Login(req.Username, req.Password, clientInfo(r))
 	if result.Err != nil {
-		h.sendError(w, "Error retrieving user", http.StatusInternalServerError)
+		if errors.Is(result.Err, service.ErrInvalidCredentials) {
+			h.sendError(w, "Invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		h.sendError(w, result.Err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": req.Username,
-		"user_id":  result.Data.ID.String(),
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
-	})
-
-	secretKey := utils.GetEnvOrDefault("JWT_SECRET", "default")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
 
-	tokenString, err := token.SignedString([]byte(secretKey))
-	if err != nil {
-		http.Error(w, "Error generating token", http.StatusInternalServerError)
-		return
+func clientInfo(r *http.Request) model.ClientInfo {
+	return model.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IP:        utils.ClientIP(r),
 	}
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(model.LoginResponse{Token: tokenString})
 }
 
 func (h *AuthHandler) sendError(w http.ResponseWriter, message string, status int) {
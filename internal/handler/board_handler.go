@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"test_project/test/internal/model"
+	"test_project/test/internal/service"
+	utils "test_project/test/pkg"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type BoardHandler struct {
+	service   *service.BoardService
+	validator *validator.Validate
+}
+
+func NewBoardHandler(service *service.BoardService) *BoardHandler {
+	return &BoardHandler{
+		service:   service,
+		validator: newValidator(),
+	}
+}
+
+// CreateBoard godoc
+// @Summary Create a board
+// @Description Admin only. The slug is used in URLs, lowercase letters and digits joined with dashes. allowedStatuses empty allows every status, otherwise it must include requested.
+// @Tags Boards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param board body model.CreateBoardPayload true "Slug, name and settings"
+// @Success 201 {object} model.Board
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Failure 409 {object} map[string]string "Slug already taken"
+// @Router /boards [post]
+func (h *BoardHandler) CreateBoard(w http.ResponseWriter, r *http.Request) {
+	var payload model.CreateBoardPayload
+	if err := decodeStrict(r, &payload); err != nil {
+		sendValidationError(w, "failed to decode board", err)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		sendValidationError(w, "invalid board", err)
+		return
+	}
+
+	result := h.service.CreateBoard(payload)
+	if result.Err != nil {
+		h.sendBoardError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// GetBoards godoc
+// @Summary List boards
+// @Tags Boards
+// @Produce json
+// @Success 200 {array} model.Board
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /boards [get]
+func (h *BoardHandler) GetBoards(w http.ResponseWriter, r *http.Request) {
+	result := h.service.GetBoards()
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// GetBoard godoc
+// @Summary Get a board
+// @Tags Boards
+// @Produce json
+// @Param slug path string true "Board slug"
+// @Success 200 {object} model.Board
+// @Failure 404 {object} map[string]string "Board not found"
+// @Router /boards/{slug} [get]
+func (h *BoardHandler) GetBoard(w http.ResponseWriter, r *http.Request) {
+	result := h.service.GetBoard(r.PathValue("slug"))
+	if result.Err != nil {
+		h.sendBoardError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// UpdateBoard godoc
+// @Summary Update a board
+// @Description Admins and owners of the board. Omitted fields are left as they are. Ideas already in a status that is no longer allowed keep it.
+// @Tags Boards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param slug path string true "Board slug"
+// @Param board body model.UpdateBoardPayload true "Fields to change"
+// @Success 200 {object} model.Board
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Not an admin or owner of the board"
+// @Failure 404 {object} map[string]string "Board not found"
+// @Router /boards/{slug} [patch]
+func (h *BoardHandler) UpdateBoard(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload model.UpdateBoardPayload
+	if err := decodeStrict(r, &payload); err != nil {
+		sendValidationError(w, "failed to decode board", err)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		sendValidationError(w, "invalid board", err)
+		return
+	}
+
+	result := h.service.UpdateBoard(r.PathValue("slug"), payload, actor)
+	if result.Err != nil {
+		h.sendBoardError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// DeleteBoard godoc
+// @Summary Delete a board
+// @Description Admin only. Only boards without ideas can be deleted, the default board can't.
+// @Tags Boards
+// @Produce json
+// @Security BearerAuth
+// @Param slug path string true "Board slug"
+// @Success 200 {object} map[string]string "Board deleted"
+// @Failure 400 {object} map[string]string "The default board"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Failure 404 {object} map[string]string "Board not found"
+// @Failure 409 {object} map[string]string "The board still has ideas"
+// @Router /boards/{slug} [delete]
+func (h *BoardHandler) DeleteBoard(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteBoard(r.PathValue("slug")); err != nil {
+		h.sendBoardError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Board deleted successfully"})
+}
+
+// GetMembers godoc
+// @Summary List the members of a board
+// @Tags Boards
+// @Produce json
+// @Security BearerAuth
+// @Param slug path string true "Board slug"
+// @Success 200 {array} model.BoardMembership
+// @Failure 404 {object} map[string]string "Board not found"
+// @Router /boards/{slug}/members [get]
+func (h *BoardHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	result := h.service.GetMembers(r.PathValue("slug"))
+	if result.Err != nil {
+		h.sendBoardError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// SetMember godoc
+// @Summary Add a member to a board
+// @Description Admins and owners of the board. Adds the user or changes their role. Owners manage the board, on a members-only board members can file ideas and vote.
+// @Tags Boards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param slug path string true "Board slug"
+// @Param username path string true "Username"
+// @Param member body model.SetBoardMemberPayload true "Role on the board"
+// @Success 200 {object} model.BoardMembership
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Not an admin or owner of the board"
+// @Failure 404 {object} map[string]string "Board or user not found"
+// @Router /boards/{slug}/members/{username} [put]
+func (h *BoardHandler) SetMember(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload model.SetBoardMemberPayload
+	if err := decodeStrict(r, &payload); err != nil {
+		sendValidationError(w, "failed to decode member", err)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		sendValidationError(w, "invalid member", err)
+		return
+	}
+
+	result := h.service.SetMember(r.PathValue("slug"), r.PathValue("username"), payload.Role, actor)
+	if result.Err != nil {
+		h.sendBoardError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// RemoveMember godoc
+// @Summary Remove a member from a board
+// @Description Admins and owners of the board
+// @Tags Boards
+// @Produce json
+// @Security BearerAuth
+// @Param slug path string true "Board slug"
+// @Param username path string true "Username"
+// @Success 200 {object} map[string]string "Member removed"
+// @Failure 403 {object} map[string]string "Not an admin or owner of the board"
+// @Failure 404 {object} map[string]string "Board or member not found"
+// @Router /boards/{slug}/members/{username} [delete]
+func (h *BoardHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.RemoveMember(r.PathValue("slug"), r.PathValue("username"), actor); err != nil {
+		h.sendBoardError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Member removed successfully"})
+}
+
+func (h *BoardHandler) sendBoardError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrBoardNotFound), errors.Is(err, service.ErrMemberNotFound), errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrBoardForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrBoardExists), errors.Is(err, service.ErrBoardNotEmpty):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidBoard):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
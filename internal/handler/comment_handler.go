@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"test_project/test/internal/model"
+	"test_project/test/internal/service"
+	utils "test_project/test/pkg"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type CommentHandler struct {
+	service   *service.CommentService
+	validator *validator.Validate
+}
+
+func NewCommentHandler(service *service.CommentService) *CommentHandler {
+	return &CommentHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// GetComments godoc
+// @Summary List comments on an idea
+// @Description Returns top-level comments oldest first, each with its replies. Bodies are markdown.
+// @Tags Comments
+// @Produce json
+// @Param id path string true "Idea ID"
+// @Success 200 {array} model.Comment
+// @Failure 400 {object} map[string]string "Bad request - invalid ID format"
+// @Failure 404 {object} map[string]string "Idea not found"
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /idea/{id}/comments [get]
+func (h *CommentHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	ideaID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid idea ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	viewer, _ := utils.ExtractActorFromToken(r)
+
+	result := h.service.GetComments(ideaID, viewer)
+	if result.Err != nil {
+		h.sendCommentError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// CreateComment godoc
+// @Summary Comment on an idea
+// @Description Adds a comment, or a reply when parentId is set. Replies can only answer top-level comments.
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Idea ID"
+// @Param comment body model.CreateCommentPayload true "Markdown body and optional parent comment"
+// @Success 201 {object} model.Comment
+// @Failure 400 {object} map[string]string "Bad request - invalid body or parent"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Idea not found"
+// @Router /idea/{id}/comments [post]
+func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ideaID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid idea ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var payload model.CreateCommentPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode comment: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := h.service.CreateComment(ideaID, actor, payload)
+	if result.Err != nil {
+		h.sendCommentError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// UpdateComment godoc
+// @Summary Edit a comment
+// @Description Only the author or a moderator can edit a comment
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Idea ID"
+// @Param commentId path string true "Comment ID"
+// @Param comment body model.UpdateCommentPayload true "New markdown body"
+// @Success 200 {object} model.Comment
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Not the author or a moderator"
+// @Failure 404 {object} map[string]string "Comment not found"
+// @Router /idea/{id}/comments/{commentId} [patch]
+func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ideaID, commentID, err := commentPathIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload model.UpdateCommentPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode comment: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := h.service.UpdateComment(ideaID, commentID, actor, payload)
+	if result.Err != nil {
+		h.sendCommentError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// DeleteComment godoc
+// @Summary Delete a comment
+// @Description Only the author or a moderator can delete a comment. Deleting a top-level comment also deletes its replies.
+// @Tags Comments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Idea ID"
+// @Param commentId path string true "Comment ID"
+// @Success 200 {object} map[string]string "Comment deleted"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Not the author or a moderator"
+// @Failure 404 {object} map[string]string "Comment not found"
+// @Router /idea/{id}/comments/{commentId} [delete]
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ideaID, commentID, err := commentPathIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteComment(ideaID, commentID, actor); err != nil {
+		h.sendCommentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Comment deleted successfully"})
+}
+
+func commentPathIDs(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	ideaID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid idea ID format: %v", err)
+	}
+
+	commentID, err := uuid.Parse(r.PathValue("commentId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid comment ID format: %v", err)
+	}
+
+	return ideaID, commentID, nil
+}
+
+func (h *CommentHandler) sendCommentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrIdeaNotFound), errors.Is(err, service.ErrCommentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrCommentForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrIdeaMerged):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidParent), errors.Is(err, service.ErrEmptyComment):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
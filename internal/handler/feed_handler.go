@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"test_project/test/internal/model"
+	"test_project/test/internal/service"
+	utils "test_project/test/pkg"
+	"time"
+)
+
+// Feed files every feed is offered as
+const (
+	atomFile = "ideas.atom"
+	rssFile  = "ideas.rss"
+)
+
+type FeedHandler struct {
+	service *service.FeedService
+}
+
+func NewFeedHandler(service *service.FeedService) *FeedHandler {
+	return &FeedHandler{service: service}
+}
+
+// GetIdeasFeed godoc
+// @Summary Feed of the newest ideas
+// @Description Atom (ideas.atom) or RSS 2.0 (ideas.rss). Supports conditional GET with If-None-Match and If-Modified-Since.
+// @Tags Feeds
+// @Produce xml
+// @Param file path string true "ideas.atom or ideas.rss"
+// @Success 200 {string} string "The feed"
+// @Success 304 {string} string "Not modified"
+// @Failure 404 {object} map[string]string "Unknown feed"
+// @Router /feeds/{file} [get]
+func (h *FeedHandler) GetIdeasFeed(w http.ResponseWriter, r *http.Request) {
+	h.serveFeed(w, r, h.service.NewestIdeas())
+}
+
+// GetStatusFeed godoc
+// @Summary Feed of the ideas in a status
+// @Description Ideas that most recently moved into the status come first, e.g. /feeds/status/published/ideas.atom for recently published ideas.
+// @Tags Feeds
+// @Produce xml
+// @Param status path string true "Status, e.g. published"
+// @Param file path string true "ideas.atom or ideas.rss"
+// @Success 200 {string} string "The feed"
+// @Success 304 {string} string "Not modified"
+// @Failure 404 {object} map[string]string "Unknown status or feed"
+// @Router /feeds/status/{status}/{file} [get]
+func (h *FeedHandler) GetStatusFeed(w http.ResponseWriter, r *http.Request) {
+	h.serveFeed(w, r, h.service.IdeasWithStatus(model.RequestStatus(r.PathValue("status"))))
+}
+
+// GetTagFeed godoc
+// @Summary Feed of the newest ideas with a tag
+// @Tags Feeds
+// @Produce xml
+// @Param tag path string true "Tag"
+// @Param file path string true "ideas.atom or ideas.rss"
+// @Success 200 {string} string "The feed"
+// @Success 304 {string} string "Not modified"
+// @Failure 404 {object} map[string]string "Unknown feed"
+// @Router /feeds/tags/{tag}/{file} [get]
+func (h *FeedHandler) GetTagFeed(w http.ResponseWriter, r *http.Request) {
+	h.serveFeed(w, r, h.service.IdeasWithTag(r.PathValue("tag")))
+}
+
+// serveFeed renders the feed in the format named by the file path value.
+// The ETag is a hash of the rendered feed, so readers polling an unchanged
+// feed get a 304.
+func (h *FeedHandler) serveFeed(w http.ResponseWriter, r *http.Request, result utils.Result[model.Feed]) {
+	file := r.PathValue("file")
+	if file != atomFile && file != rssFile {
+		http.Error(w, "feed not found", http.StatusNotFound)
+		return
+	}
+
+	if result.Err != nil {
+		if errors.Is(result.Err, service.ErrUnknownStatus) {
+			http.Error(w, result.Err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var body []byte
+	var err error
+	if file == atomFile {
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		body, err = renderAtom(result.Data)
+	} else {
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		body, err = renderRSS(result.Data)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	http.ServeContent(w, r, file, result.Data.Updated, bytes.NewReader(body))
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href  string `xml:"href,attr"`
+	Rel   string `xml:"rel,attr,omitempty"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     *atomAuthor    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    *atomContent   `xml:"content,omitempty"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+func renderAtom(feed model.Feed) ([]byte, error) {
+	doc := atomFeed{
+		ID:       feed.Link,
+		Title:    feed.Title,
+		Subtitle: feed.Subtitle,
+		Updated:  atomTime(feed.Updated),
+		Links: []atomLink{
+			{Href: feed.Link + ".atom", Rel: "self", Type: "application/atom+xml"},
+			{Href: feed.Link + ".rss", Rel: "alternate", Type: "application/rss+xml"},
+		},
+	}
+
+	for _, entry := range feed.Entries {
+		e := atomEntry{
+			ID:        entry.ID,
+			Title:     entry.Title,
+			Links:     []atomLink{{Href: entry.Link, Rel: "alternate"}},
+			Published: atomTime(entry.Published),
+			Updated:   atomTime(entry.Updated),
+			Summary:   entry.Summary,
+		}
+		for _, link := range entry.Links {
+			e.Links = append(e.Links, atomLink{Href: link.URL, Rel: "related", Title: linkTitle(link)})
+		}
+		if content := entryContent(entry); content != "" {
+			e.Content = &atomContent{Type: "html", Value: content}
+		}
+		if entry.Author != "" {
+			e.Author = &atomAuthor{Name: entry.Author}
+		}
+		for _, category := range entry.Categories {
+			e.Categories = append(e.Categories, atomCategory{Term: category})
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+
+	return marshalFeed(doc)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Self          atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description,omitempty"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func renderRSS(feed model.Feed) ([]byte, error) {
+	channel := rssChannel{
+		Title:       feed.Title,
+		Link:        feed.Link + ".rss",
+		Description: feed.Subtitle,
+		Self:        atomLink{Href: feed.Link + ".rss", Rel: "self", Type: "application/rss+xml"},
+	}
+	if !feed.Updated.IsZero() {
+		channel.LastBuildDate = feed.Updated.UTC().Format(time.RFC1123Z)
+	}
+
+	for _, entry := range feed.Entries {
+		// RSS has no separate content, readers expect HTML in the description
+		description := entryContent(entry)
+		if description == "" {
+			description = entry.Summary
+		}
+		channel.Items = append(channel.Items, rssItem{
+			Title:       entry.Title,
+			Link:        entry.Link,
+			Description: description,
+			GUID:        rssGUID{IsPermaLink: "false", Value: entry.ID},
+			PubDate:     entry.Published.UTC().Format(time.RFC1123Z),
+			Categories:  entry.Categories,
+		})
+	}
+
+	return marshalFeed(rssFeed{Version: "2.0", Atom: "http://www.w3.org/2005/Atom", Channel: channel})
+}
+
+// entryContent is the description HTML followed by the links of the idea,
+// so readers show them in either format
+func entryContent(entry model.FeedEntry) string {
+	if len(entry.Links) == 0 {
+		return entry.Content
+	}
+
+	var b strings.Builder
+	b.WriteString(entry.Content)
+	b.WriteString("<ul>")
+	for _, link := range entry.Links {
+		fmt.Fprintf(&b, `<li><a href="%s">%s</a></li>`, html.EscapeString(link.URL), html.EscapeString(linkTitle(link)))
+	}
+	b.WriteString("</ul>")
+	return b.String()
+}
+
+// linkTitle names a link by its title, or by its type without one
+func linkTitle(link model.IdeaLink) string {
+	if link.Title != "" {
+		return link.Title
+	}
+	return linkTypeNames[link.Type]
+}
+
+var linkTypeNames = map[model.LinkType]string{
+	model.LinkRepository: "Repository",
+	model.LinkBlogPost:   "Blog post",
+	model.LinkVideo:      "Video",
+	model.LinkDemo:       "Demo",
+	model.LinkDocs:       "Docs",
+}
+
+func marshalFeed(doc any) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// atomTime formats a time for Atom, an empty feed counts as updated at the
+// Unix epoch so it renders the same on every request
+func atomTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Unix(0, 0)
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"test_project/test/internal/service"
+	utils "test_project/test/pkg"
+
+	"github.com/google/uuid"
+)
+
+type FollowHandler struct {
+	service *service.FollowService
+}
+
+func NewFollowHandler(service *service.FollowService) *FollowHandler {
+	return &FollowHandler{service: service}
+}
+
+// FollowIdea godoc
+// @Summary Follow an idea
+// @Description Get notified when the idea is edited, changes status or gets a comment. Following twice is fine.
+// @Tags Follows
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Idea ID"
+// @Success 200 {object} map[string]string "Following"
+// @Failure 400 {object} map[string]string "Invalid ID format"
+// @Failure 404 {object} map[string]string "Idea not found"
+// @Failure 409 {object} map[string]string "Idea was merged"
+// @Router /idea/{id}/follow [put]
+func (h *FollowHandler) FollowIdea(w http.ResponseWriter, r *http.Request) {
+	h.setFollow(w, r, true)
+}
+
+// UnfollowIdea godoc
+// @Summary Stop following an idea
+// @Tags Follows
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Idea ID"
+// @Success 200 {object} map[string]string "Not following"
+// @Failure 400 {object} map[string]string "Invalid ID format"
+// @Failure 404 {object} map[string]string "Idea not found"
+// @Router /idea/{id}/follow [delete]
+func (h *FollowHandler) UnfollowIdea(w http.ResponseWriter, r *http.Request) {
+	h.setFollow(w, r, false)
+}
+
+// GetFollowing godoc
+// @Summary Ideas the current user follows
+// @Description Most recently followed first
+// @Tags Follows
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.Idea
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /me/following [get]
+func (h *FollowHandler) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result := h.service.GetFollowing(actor)
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+func (h *FollowHandler) setFollow(w http.ResponseWriter, r *http.Request, follow bool) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ideaID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	message := "Following idea"
+	if follow {
+		err = h.service.Follow(ideaID, actor)
+	} else {
+		message = "No longer following idea"
+		err = h.service.Unfollow(ideaID, actor)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrIdeaNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrIdeaMerged):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
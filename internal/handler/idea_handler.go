@@ -2,22 +2,30 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 	"test_project/test/internal/model"
 	"test_project/test/internal/service"
 	utils "test_project/test/pkg"
 	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 )
 
 type IdeaHandler struct {
-	service *service.IdeaService
+	service   *service.IdeaService
+	validator *validator.Validate
 }
 
 func NewIdeaHandler(service *service.IdeaService) *IdeaHandler {
-	return &IdeaHandler{service}
+	return &IdeaHandler{
+		service:   service,
+		validator: newValidator(),
+	}
 }
 
 // CreateIdea godoc
@@ -27,15 +35,51 @@ func NewIdeaHandler(service *service.IdeaService) *IdeaHandler {
 // @Accept json
 // @Produce json
 // @Param idea body model.CreateIdeaPayload true "Idea object with title, description, tech stack, and tags"
-// @Success 201 {object} map[string]string "Returns a success message with the created idea ID"
-// @Failure 400 {object} map[string]string "Bad request - invalid payload format or missing required fields"
+// @Success 201 {object} model.CreateIdeaResponse "The created idea ID and existing ideas that look like duplicates"
+// @Failure 400 {object} map[string]string "Bad request - invalid payload, fields lists the problem per field"
+// @Failure 403 {object} map[string]string "The default board is members only"
 // @Failure 500 {object} map[string]string "Server error - database or internal processing error"
 // @Router /idea [post]
 func (h *IdeaHandler) CreateIdea(w http.ResponseWriter, r *http.Request) {
+	h.createIdea(w, r, model.DefaultBoardSlug)
+}
+
+// CreateBoardIdea godoc
+// @Summary Create an idea on a board
+// @Description Files a new idea on the board. On a members-only board only members can file ideas.
+// @Tags Ideas
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param slug path string true "Board slug"
+// @Param idea body model.CreateIdeaPayload true "Idea object with title, description, tech stack, and tags"
+// @Success 201 {object} model.CreateIdeaResponse "The created idea ID and existing ideas on the board that look like duplicates"
+// @Failure 400 {object} map[string]string "Bad request - invalid payload, fields lists the problem per field"
+// @Failure 403 {object} map[string]string "Not a member of the board"
+// @Failure 404 {object} map[string]string "Board not found"
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /boards/{slug}/ideas [post]
+func (h *IdeaHandler) CreateBoardIdea(w http.ResponseWriter, r *http.Request) {
+	h.createIdea(w, r, r.PathValue("slug"))
+}
+
+func (h *IdeaHandler) createIdea(w http.ResponseWriter, r *http.Request, slug string) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var createPayload model.CreateIdeaPayload
 
-	if err := json.NewDecoder(r.Body).Decode(&createPayload); err != nil {
-		http.Error(w, fmt.Sprintf("failed to decode idea: %v", err), http.StatusBadRequest)
+	if err := decodeStrict(r, &createPayload); err != nil {
+		sendValidationError(w, "failed to decode idea", err)
+		return
+	}
+
+	createPayload.Title = strings.TrimSpace(createPayload.Title)
+	if err := h.validator.Struct(createPayload); err != nil {
+		sendValidationError(w, "invalid idea", err)
 		return
 	}
 
@@ -46,8 +90,11 @@ func (h *IdeaHandler) CreateIdea(w http.ResponseWriter, r *http.Request) {
 		TechStack:   createPayload.TechStack,
 		Tags:        createPayload.Tags,
 		Status:      createPayload.Status,
+		Visibility:  createPayload.Visibility,
+		Links:       ideaLinks(createPayload.Links),
 		Votes:       []model.Vote{},
-		RequestedBy: "anonymous",
+		RequestedBy: actor.Username,
+		AuthorID:    &actor.ID,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -56,29 +103,79 @@ func (h *IdeaHandler) CreateIdea(w http.ResponseWriter, r *http.Request) {
 		idea.Status = model.Requested
 	}
 
-	result := h.service.CreateIdea(idea)
+	result := h.service.CreateIdea(slug, idea, actor)
 	if result.Err != nil {
-		http.Error(w, fmt.Sprintf("failed to create idea: %v", result.Err), http.StatusInternalServerError)
+		switch {
+		case errors.Is(result.Err, service.ErrInvalidTransition), errors.Is(result.Err, service.ErrInvalidTechStack),
+			errors.Is(result.Err, service.ErrInvalidTag), errors.Is(result.Err, service.ErrInvalidLink):
+			http.Error(w, result.Err.Error(), http.StatusBadRequest)
+		case errors.Is(result.Err, service.ErrBoardNotFound):
+			http.Error(w, result.Err.Error(), http.StatusNotFound)
+		case errors.Is(result.Err, service.ErrNotBoardMember):
+			http.Error(w, result.Err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, fmt.Sprintf("failed to create idea: %v", result.Err), http.StatusInternalServerError)
+		}
 		return
 	}
 
+	// The idea is already saved, failing to look for duplicates is not worth
+	// an error response
+	duplicates := h.service.FindDuplicates(slug, idea, actor)
+	if duplicates.Err != nil {
+		log.Printf("failed to look for duplicates of idea %s: %v", idea.ID, duplicates.Err)
+		duplicates.Data = []model.DuplicateCandidate{}
+	}
+
 	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(map[string]string{"result": result.Data}); err != nil {
+	response := model.CreateIdeaResponse{Result: result.Data, ID: idea.ID, PossibleDuplicates: duplicates.Data}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
 	}
 }
 
 // GetAllIdeas godoc
 // @Summary Get all ideas
-// @Description Retrieves all ideas from the system
+// @Description Retrieves the public ideas of the default board. Signed in users also get their own drafts, private and unlisted ideas, maintainers every private idea.
 // @Tags Ideas
 // @Produce json
+// @Param assignee query string false "Only ideas assigned to this username"
 // @Success 200 {array} model.Idea
+// @Failure 401 {object} error "Invalid token"
 // @Failure 500 {object} error "Server error"
 // @Router /idea [get]
 func (h *IdeaHandler) GetAllIdeas(w http.ResponseWriter, r *http.Request) {
-	result := h.service.GetAllIdeas()
+	h.listIdeas(w, r, model.DefaultBoardSlug)
+}
+
+// GetBoardIdeas godoc
+// @Summary Get the ideas of a board
+// @Description Retrieves the public ideas of the board. Signed in users also get their own drafts, private and unlisted ideas, maintainers every private idea.
+// @Tags Ideas
+// @Produce json
+// @Param slug path string true "Board slug"
+// @Param assignee query string false "Only ideas assigned to this username"
+// @Success 200 {array} model.Idea
+// @Failure 401 {object} error "Invalid token"
+// @Failure 404 {object} error "Board not found"
+// @Failure 500 {object} error "Server error"
+// @Router /boards/{slug}/ideas [get]
+func (h *IdeaHandler) GetBoardIdeas(w http.ResponseWriter, r *http.Request) {
+	h.listIdeas(w, r, r.PathValue("slug"))
+}
+
+func (h *IdeaHandler) listIdeas(w http.ResponseWriter, r *http.Request, slug string) {
+	// Anonymous viewers are the zero actor
+	viewer, _ := utils.ExtractActorFromToken(r)
+
+	filter := model.IdeaFilter{Assignee: r.URL.Query().Get("assignee")}
+
+	result := h.service.GetAllIdeas(slug, viewer, filter)
 	if result.Err != nil {
+		if errors.Is(result.Err, service.ErrBoardNotFound) {
+			http.Error(w, result.Err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -89,7 +186,7 @@ func (h *IdeaHandler) GetAllIdeas(w http.ResponseWriter, r *http.Request) {
 
 // GetIdea godoc
 // @Summary Get a specific idea by ID
-// @Description Retrieves a single idea by its ID
+// @Description Retrieves a single idea by its ID. For an idea that was merged the idea it was merged into is returned, with its path in Content-Location. Drafts are only found by their author, private ideas by their author and maintainers.
 // @Tags Ideas
 // @Produce json
 // @Param id path string true "Idea ID"
@@ -110,30 +207,70 @@ func (h *IdeaHandler) GetIdea(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := h.service.GetIdea(id)
+	viewer, _ := utils.ExtractActorFromToken(r)
+	h.sendIdea(w, id, h.service.GetIdea(id, viewer))
+}
+
+// GetBoardIdea godoc
+// @Summary Get an idea of a board
+// @Description Retrieves a single idea on the board, like GET /idea/{id}
+// @Tags Ideas
+// @Produce json
+// @Param slug path string true "Board slug"
+// @Param id path string true "Idea ID"
+// @Success 200 {object} model.Idea
+// @Failure 400 {object} error "Invalid ID format"
+// @Failure 404 {object} error "Board or idea not found"
+// @Router /boards/{slug}/ideas/{id} [get]
+func (h *IdeaHandler) GetBoardIdea(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	viewer, _ := utils.ExtractActorFromToken(r)
+	h.sendIdea(w, id, h.service.GetBoardIdea(r.PathValue("slug"), id, viewer))
+}
+
+func (h *IdeaHandler) sendIdea(w http.ResponseWriter, id uuid.UUID, result utils.Result[model.Idea]) {
 	if result.Err != nil {
 		http.Error(w, result.Err.Error(), http.StatusNotFound)
 		return
 	}
 
+	// The idea was merged, point clients at the canonical idea
+	if result.Data.ID != id {
+		w.Header().Set("Content-Location", "/v1/idea/"+result.Data.ID.String())
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result.Data)
 }
 
 // UpdateIdea godoc
 // @Summary Update an existing idea
-// @Description Updates an idea's information in the system
+// @Description Updates an idea's information in the system. Only the author and maintainers can update an idea, only maintainers can change its status. Status changes must follow the configured workflow, some need a statusReason. links replaces the links, publishing needs at least one and a published idea can't lose its last one.
 // @Tags Ideas
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path string true "Idea ID"
 // @Param idea body model.UpdateIdeaPayload true "Updated idea object"
 // @Success 200 {object} map[string]string "Success message"
-// @Failure 400 {object} error "Invalid request or ID format"
+// @Failure 400 {object} error "Invalid request or ID format, missing status reason or no link to publish with"
+// @Failure 403 {object} error "Not the author or a maintainer, or status changed by someone else than a maintainer"
 // @Failure 404 {object} error "Idea not found"
+// @Failure 409 {object} error "Status change not allowed"
 // @Failure 500 {object} error "Server error"
 // @Router /idea/{id} [post]
 func (h *IdeaHandler) UpdateIdea(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	idStr := r.PathValue("id")
 	if idStr == "" {
 		http.Error(w, "missing id parameter", http.StatusBadRequest)
@@ -147,12 +284,21 @@ func (h *IdeaHandler) UpdateIdea(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var updatePayload model.UpdateIdeaPayload
-	if err := json.NewDecoder(r.Body).Decode(&updatePayload); err != nil {
-		http.Error(w, fmt.Sprintf("failed to decode idea: %v", err), http.StatusBadRequest)
+	if err := decodeStrict(r, &updatePayload); err != nil {
+		sendValidationError(w, "failed to decode idea", err)
+		return
+	}
+
+	if updatePayload.Title != nil {
+		title := strings.TrimSpace(*updatePayload.Title)
+		updatePayload.Title = &title
+	}
+	if err := h.validator.Struct(updatePayload); err != nil {
+		sendValidationError(w, "invalid idea", err)
 		return
 	}
 
-	result := h.service.GetIdea(id)
+	result := h.service.GetIdea(id, actor)
 	if result.Err != nil {
 		http.Error(w, result.Err.Error(), http.StatusNotFound)
 		return
@@ -170,12 +316,6 @@ func (h *IdeaHandler) UpdateIdea(w http.ResponseWriter, r *http.Request) {
 		updatedIdea.TechStack = *updatePayload.TechStack
 	}
 	if updatePayload.Tags != nil {
-		// tagsJSON, err := json.Marshal(updatePayload.Tags)
-		// if err != nil {
-		// 	http.Error(w, fmt.Sprintf("failed to marshal tags: %v", err), http.StatusBadRequest)
-		// 	return
-		// }
-		// updatedIdea.Tags = tagsJSON
 		updatedIdea.Tags = *updatePayload.Tags
 	}
 
@@ -187,11 +327,37 @@ func (h *IdeaHandler) UpdateIdea(w http.ResponseWriter, r *http.Request) {
 		updatedIdea.RequestedBy = *updatePayload.RequestedBy
 	}
 
+	if updatePayload.Visibility != nil {
+		updatedIdea.Visibility = *updatePayload.Visibility
+	}
+
+	if updatePayload.Links != nil {
+		updatedIdea.Links = ideaLinks(*updatePayload.Links)
+	}
+
 	updatedIdea.UpdatedAt = time.Now()
 
-	updateResult := h.service.UpdateIdea(id, updatedIdea)
+	var reason string
+	if updatePayload.StatusReason != nil {
+		reason = *updatePayload.StatusReason
+	}
+
+	updateResult := h.service.UpdateIdea(id, updatedIdea, actor, reason)
 	if updateResult.Err != nil {
-		http.Error(w, updateResult.Err.Error(), http.StatusInternalServerError)
+		switch {
+		case errors.Is(updateResult.Err, service.ErrIdeaNotFound):
+			http.Error(w, updateResult.Err.Error(), http.StatusNotFound)
+		case errors.Is(updateResult.Err, service.ErrIdeaForbidden), errors.Is(updateResult.Err, service.ErrStatusForbidden):
+			http.Error(w, updateResult.Err.Error(), http.StatusForbidden)
+		case errors.Is(updateResult.Err, service.ErrInvalidTransition), errors.Is(updateResult.Err, service.ErrIdeaMerged):
+			http.Error(w, updateResult.Err.Error(), http.StatusConflict)
+		case errors.Is(updateResult.Err, service.ErrReasonRequired), errors.Is(updateResult.Err, service.ErrInvalidTechStack),
+			errors.Is(updateResult.Err, service.ErrInvalidTag), errors.Is(updateResult.Err, service.ErrInvalidLink),
+			errors.Is(updateResult.Err, service.ErrLinkRequired):
+			http.Error(w, updateResult.Err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, updateResult.Err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -201,16 +367,25 @@ func (h *IdeaHandler) UpdateIdea(w http.ResponseWriter, r *http.Request) {
 
 // DeleteIdea godoc
 // @Summary Delete an idea
-// @Description Removes an idea from the system
+// @Description Removes an idea from the system. Only the author and maintainers can delete an idea.
 // @Tags Ideas
 // @Produce json
+// @Security BearerAuth
 // @Param id path string true "Idea ID"
 // @Success 200 {object} map[string]string "Success message"
 // @Failure 400 {object} error "Invalid ID format"
+// @Failure 401 {object} error "Unauthorized"
+// @Failure 403 {object} error "Not the author or a maintainer"
 // @Failure 404 {object} error "Idea not found"
 // @Failure 500 {object} error "Server error"
 // @Router /idea/{id} [delete]
 func (h *IdeaHandler) DeleteIdea(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	idStr := r.PathValue("id")
 	if idStr == "" {
 		http.Error(w, "missing id query parameter", http.StatusBadRequest)
@@ -223,12 +398,159 @@ func (h *IdeaHandler) DeleteIdea(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := h.service.DeleteIdea(id)
+	result := h.service.DeleteIdea(id, actor)
 	if result.Err != nil {
-		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		switch {
+		case errors.Is(result.Err, service.ErrIdeaNotFound):
+			http.Error(w, result.Err.Error(), http.StatusNotFound)
+		case errors.Is(result.Err, service.ErrIdeaForbidden):
+			http.Error(w, result.Err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"result": result.Data})
 }
+
+// PublishIdea godoc
+// @Summary Publish an idea
+// @Description Makes a draft, private or unlisted idea public. Only the author and maintainers can publish an idea, publishing a public idea does nothing.
+// @Tags Ideas
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Idea ID"
+// @Success 200 {object} map[string]string "Idea published"
+// @Failure 400 {object} error "Invalid ID format"
+// @Failure 401 {object} error "Unauthorized"
+// @Failure 403 {object} error "Not the author or a maintainer"
+// @Failure 404 {object} error "Idea not found"
+// @Failure 409 {object} error "Idea was merged"
+// @Failure 500 {object} error "Server error"
+// @Router /idea/{id}/publish [post]
+func (h *IdeaHandler) PublishIdea(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.PublishIdea(id, actor); err != nil {
+		switch {
+		case errors.Is(err, service.ErrIdeaNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrIdeaForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, service.ErrIdeaMerged):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Idea published successfully"})
+}
+
+// GetStatusHistory godoc
+// @Summary Get the history of an idea
+// @Description Lists every status change with who made it, when and why, and every change of the assignees, oldest first. kind tells them apart.
+// @Tags Ideas
+// @Produce json
+// @Param id path string true "Idea ID"
+// @Success 200 {array} model.StatusTransition
+// @Failure 400 {object} error "Invalid ID format"
+// @Failure 404 {object} error "Idea not found"
+// @Failure 500 {object} error "Server error"
+// @Router /idea/{id}/status-history [get]
+func (h *IdeaHandler) GetStatusHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	viewer, _ := utils.ExtractActorFromToken(r)
+
+	result := h.service.GetStatusHistory(id, viewer)
+	if result.Err != nil {
+		if errors.Is(result.Err, service.ErrIdeaNotFound) {
+			http.Error(w, result.Err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// MergeIdea godoc
+// @Summary Merge a duplicate idea
+// @Description Moderators only. Moves votes (one per user) and comments of the idea into the canonical idea. The idea becomes a redirect to the canonical one.
+// @Tags Ideas
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID of the duplicate"
+// @Param merge body model.MergeIdeaPayload true "ID of the canonical idea"
+// @Success 200 {object} map[string]string "Ideas merged"
+// @Failure 400 {object} error "Invalid request"
+// @Failure 403 {object} error "Not a moderator"
+// @Failure 404 {object} error "Idea not found"
+// @Failure 409 {object} error "Idea was already merged"
+// @Router /idea/{id}/merge [post]
+func (h *IdeaHandler) MergeIdea(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var payload model.MergeIdeaPayload
+	if err := decodeStrict(r, &payload); err != nil {
+		sendValidationError(w, "failed to decode merge", err)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		sendValidationError(w, "invalid merge", err)
+		return
+	}
+
+	if err := h.service.MergeIdea(id, payload.Into); err != nil {
+		switch {
+		case errors.Is(err, service.ErrIdeaNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrIdeaMerged):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, service.ErrInvalidMerge):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Ideas merged successfully"})
+}
+
+// ideaLinks turns the links of a payload into links of the idea, in order
+func ideaLinks(payloads []model.IdeaLinkPayload) []model.IdeaLink {
+	links := make([]model.IdeaLink, 0, len(payloads))
+	for _, p := range payloads {
+		links = append(links, model.IdeaLink{Type: p.Type, URL: p.URL, Title: p.Title})
+	}
+	return links
+}
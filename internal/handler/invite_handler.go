@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"test_project/test/internal/model"
+	"test_project/test/internal/service"
+	utils "test_project/test/pkg"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type InviteHandler struct {
+	service   *service.InviteService
+	validator *validator.Validate
+}
+
+func NewInviteHandler(service *service.InviteService) *InviteHandler {
+	return &InviteHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// CreateInvite godoc
+// @Summary Create an invite code
+// @Description Admin only. The plain code is only returned in this response.
+// @Tags Invites
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param invite body model.CreateInviteRequest true "Max uses (default 1), optional expiry and note"
+// @Success 201 {object} model.CreateInviteResponse
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Router /auth/invites [post]
+func (h *InviteHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ExtractUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode invite: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := h.service.CreateInvite(userID, req)
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// GetInvites godoc
+// @Summary List invite codes
+// @Tags Invites
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.Invite
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Router /auth/invites [get]
+func (h *InviteHandler) GetInvites(w http.ResponseWriter, r *http.Request) {
+	result := h.service.GetInvites()
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// RevokeInvite godoc
+// @Summary Revoke an invite code
+// @Tags Invites
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Invite ID"
+// @Success 200 {object} map[string]string "Invite revoked"
+// @Failure 400 {object} map[string]string "Invalid ID format"
+// @Failure 404 {object} map[string]string "Invite not found"
+// @Router /auth/invites/{id} [delete]
+func (h *InviteHandler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RevokeInvite(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Invite revoked"})
+}
+
+// GetRedemptions godoc
+// @Summary List who registered with an invite
+// @Tags Invites
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Invite ID"
+// @Success 200 {array} model.InviteRedemption
+// @Failure 400 {object} map[string]string "Invalid ID format"
+// @Router /auth/invites/{id}/redemptions [get]
+func (h *InviteHandler) GetRedemptions(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	result := h.service.GetRedemptions(id)
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
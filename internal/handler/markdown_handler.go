@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"test_project/test/internal/model"
+	"test_project/test/pkg/markdown"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type MarkdownHandler struct {
+	validator *validator.Validate
+}
+
+func NewMarkdownHandler() *MarkdownHandler {
+	return &MarkdownHandler{
+		validator: validator.New(),
+	}
+}
+
+// PreviewMarkdown godoc
+// @Summary Preview markdown
+// @Description Renders markdown the same way idea descriptions are rendered into descriptionHtml, for editors to show a preview. Raw HTML is shown as text.
+// @Tags Markdown
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param markdown body model.MarkdownPreviewPayload true "Markdown to render"
+// @Success 200 {object} model.MarkdownPreview
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /markdown/preview [post]
+func (h *MarkdownHandler) PreviewMarkdown(w http.ResponseWriter, r *http.Request) {
+	var payload model.MarkdownPreviewPayload
+	if err := decodeStrict(r, &payload); err != nil {
+		sendValidationError(w, "failed to decode markdown", err)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		sendValidationError(w, "invalid markdown", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.MarkdownPreview{HTML: markdown.Render(payload.Markdown)})
+}
@@ -0,0 +1,394 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"test_project/test/internal/model"
+	"test_project/test/internal/service"
+	utils "test_project/test/pkg"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type MilestoneHandler struct {
+	service   *service.MilestoneService
+	validator *validator.Validate
+}
+
+func NewMilestoneHandler(service *service.MilestoneService) *MilestoneHandler {
+	return &MilestoneHandler{
+		service:   service,
+		validator: newValidator(),
+	}
+}
+
+// CreateMilestone godoc
+// @Summary Create a milestone
+// @Description Maintainers only. New milestones are open.
+// @Tags Milestones
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param milestone body model.CreateMilestonePayload true "Name and target date (YYYY-MM-DD)"
+// @Success 201 {object} model.Milestone
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Not a maintainer"
+// @Router /milestones [post]
+func (h *MilestoneHandler) CreateMilestone(w http.ResponseWriter, r *http.Request) {
+	var payload model.CreateMilestonePayload
+	if err := decodeStrict(r, &payload); err != nil {
+		sendValidationError(w, "failed to decode milestone", err)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		sendValidationError(w, "invalid milestone", err)
+		return
+	}
+
+	result := h.service.CreateMilestone(payload)
+	if result.Err != nil {
+		h.sendMilestoneError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// GetMilestones godoc
+// @Summary List milestones
+// @Description By target date. Progress is the share of assigned ideas that are published, counting the ideas the caller can list.
+// @Tags Milestones
+// @Produce json
+// @Param state query string false "open or closed, all milestones if omitted"
+// @Success 200 {array} model.Milestone
+// @Failure 400 {object} map[string]string "Unknown state"
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /milestones [get]
+func (h *MilestoneHandler) GetMilestones(w http.ResponseWriter, r *http.Request) {
+	// Anonymous viewers are the zero actor
+	viewer, _ := utils.ExtractActorFromToken(r)
+
+	result := h.service.GetMilestones(model.MilestoneState(r.URL.Query().Get("state")), viewer)
+	if result.Err != nil {
+		h.sendMilestoneError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// GetMilestone godoc
+// @Summary Get a milestone with its progress
+// @Tags Milestones
+// @Produce json
+// @Param id path string true "Milestone ID"
+// @Success 200 {object} model.Milestone
+// @Failure 400 {object} map[string]string "Invalid ID format"
+// @Failure 404 {object} map[string]string "Milestone not found"
+// @Router /milestones/{id} [get]
+func (h *MilestoneHandler) GetMilestone(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	viewer, _ := utils.ExtractActorFromToken(r)
+
+	result := h.service.GetMilestone(id, viewer)
+	if result.Err != nil {
+		h.sendMilestoneError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// GetMilestoneIdeas godoc
+// @Summary Ideas assigned to a milestone
+// @Tags Milestones
+// @Produce json
+// @Param id path string true "Milestone ID"
+// @Success 200 {array} model.Idea
+// @Failure 400 {object} map[string]string "Invalid ID format"
+// @Failure 404 {object} map[string]string "Milestone not found"
+// @Router /milestones/{id}/ideas [get]
+func (h *MilestoneHandler) GetMilestoneIdeas(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	viewer, _ := utils.ExtractActorFromToken(r)
+
+	result := h.service.GetMilestoneIdeas(id, viewer)
+	if result.Err != nil {
+		h.sendMilestoneError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// UpdateMilestone godoc
+// @Summary Update a milestone
+// @Description Maintainers only. Omitted fields are left as they are. Set state to closed when the milestone is done, or to open to reopen it.
+// @Tags Milestones
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Milestone ID"
+// @Param milestone body model.UpdateMilestonePayload true "Fields to change"
+// @Success 200 {object} model.Milestone
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Not a maintainer"
+// @Failure 404 {object} map[string]string "Milestone not found"
+// @Router /milestones/{id} [patch]
+func (h *MilestoneHandler) UpdateMilestone(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload model.UpdateMilestonePayload
+	if err := decodeStrict(r, &payload); err != nil {
+		sendValidationError(w, "failed to decode milestone", err)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		sendValidationError(w, "invalid milestone", err)
+		return
+	}
+
+	result := h.service.UpdateMilestone(id, payload, actor)
+	if result.Err != nil {
+		h.sendMilestoneError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// DeleteMilestone godoc
+// @Summary Delete a milestone
+// @Description Maintainers only. Assigned ideas are kept and no longer planned for a milestone.
+// @Tags Milestones
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Milestone ID"
+// @Success 200 {object} map[string]string "Milestone deleted"
+// @Failure 403 {object} map[string]string "Not a maintainer"
+// @Failure 404 {object} map[string]string "Milestone not found"
+// @Router /milestones/{id} [delete]
+func (h *MilestoneHandler) DeleteMilestone(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteMilestone(id); err != nil {
+		h.sendMilestoneError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Milestone deleted successfully"})
+}
+
+// AssignIdea godoc
+// @Summary Assign an idea to a milestone
+// @Description Maintainers only. An idea belongs to one milestone at most, assigning it to another one moves it.
+// @Tags Milestones
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Milestone ID"
+// @Param ideaId path string true "Idea ID"
+// @Success 200 {object} map[string]string "Idea assigned"
+// @Failure 403 {object} map[string]string "Not a maintainer"
+// @Failure 404 {object} map[string]string "Milestone or idea not found"
+// @Failure 409 {object} map[string]string "Idea was merged"
+// @Router /milestones/{id}/ideas/{ideaId} [put]
+func (h *MilestoneHandler) AssignIdea(w http.ResponseWriter, r *http.Request) {
+	id, ideaID, ok := parseMilestoneIdeaIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.AssignIdea(id, ideaID); err != nil {
+		h.sendMilestoneError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Idea assigned to the milestone"})
+}
+
+// UnassignIdea godoc
+// @Summary Remove an idea from a milestone
+// @Description Maintainers only.
+// @Tags Milestones
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Milestone ID"
+// @Param ideaId path string true "Idea ID"
+// @Success 200 {object} map[string]string "Idea unassigned"
+// @Failure 403 {object} map[string]string "Not a maintainer"
+// @Failure 404 {object} map[string]string "Milestone or idea not found, or the idea is not assigned to it"
+// @Router /milestones/{id}/ideas/{ideaId} [delete]
+func (h *MilestoneHandler) UnassignIdea(w http.ResponseWriter, r *http.Request) {
+	id, ideaID, ok := parseMilestoneIdeaIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.UnassignIdea(id, ideaID); err != nil {
+		h.sendMilestoneError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Idea removed from the milestone"})
+}
+
+// GetCalendar godoc
+// @Summary iCalendar export of milestone dates
+// @Description An all-day event on the target date of every milestone, to subscribe to from calendar apps. Progress counts public ideas only.
+// @Tags Milestones
+// @Produce text/calendar
+// @Param state query string false "open or closed, all milestones if omitted"
+// @Success 200 {string} string "The calendar"
+// @Success 304 {string} string "Not modified"
+// @Failure 400 {object} map[string]string "Unknown state"
+// @Router /milestones.ics [get]
+func (h *MilestoneHandler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	result := h.service.Calendar(model.MilestoneState(r.URL.Query().Get("state")))
+	if result.Err != nil {
+		h.sendMilestoneError(w, result.Err)
+		return
+	}
+
+	body := renderICalendar(result.Data, time.Now())
+
+	// DTSTAMP changes on every request, so the ETag hashes the events only
+	events, _ := json.Marshal(result.Data)
+	sum := sha256.Sum256(events)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	http.ServeContent(w, r, "milestones.ics", time.Time{}, bytes.NewReader(body))
+}
+
+func parseMilestoneIdeaIDs(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	ideaID, err := uuid.Parse(r.PathValue("ideaId"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid idea ID format: %v", err), http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return id, ideaID, true
+}
+
+func (h *MilestoneHandler) sendMilestoneError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrMilestoneNotFound), errors.Is(err, service.ErrIdeaNotFound), errors.Is(err, service.ErrIdeaNotInMilestone):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrIdeaMerged):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidMilestone), errors.Is(err, service.ErrUnknownMilestoneState):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// renderICalendar writes the calendar as RFC 5545 with all-day events. The
+// end date is exclusive, so an event on a single day ends the day after.
+func renderICalendar(calendar model.Calendar, now time.Time) []byte {
+	var b bytes.Buffer
+	line := func(name, value string) {
+		writeICalLine(&b, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Go Ideas//Milestones//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", icalText(calendar.Name))
+	for _, event := range calendar.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", event.UID)
+		line("DTSTAMP", now.UTC().Format("20060102T150405Z"))
+		line("LAST-MODIFIED", event.Updated.UTC().Format("20060102T150405Z"))
+		line("DTSTART;VALUE=DATE", event.Date.Format("20060102"))
+		line("DTEND;VALUE=DATE", event.Date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY", icalText(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION", icalText(event.Description))
+		}
+		line("URL", event.URL)
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+
+	return b.Bytes()
+}
+
+// icalText escapes a TEXT value
+var icalText = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace
+
+// writeICalLine folds the content line after 75 octets without splitting a
+// character, continuation lines start with a space
+func writeICalLine(b *bytes.Buffer, content string) {
+	limit := 75
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		b.WriteString(content[:cut])
+		b.WriteString("\r\n ")
+		content = content[cut:]
+		// The leading space counts towards the next line
+		limit = 74
+	}
+	b.WriteString(content)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"test_project/test/internal/service"
+	utils "test_project/test/pkg"
+)
+
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	service *service.OIDCService
+}
+
+func NewOIDCHandler(service *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{service}
+}
+
+// Login godoc
+// @Summary Start single sign-on
+// @Description Redirects the browser to the configured OpenID Connect provider
+// @Tags Auth
+// @Success 302 "Redirect to the identity provider"
+// @Failure 404 {object} map[string]string "Single sign-on is not configured"
+// @Failure 502 {object} map[string]string "Identity provider unavailable"
+// @Router /auth/oidc/login [get]
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	h.redirect(w, r, h.service.BeginLogin())
+}
+
+// Link godoc
+// @Summary Link single sign-on to your account
+// @Description Redirects to the OpenID Connect provider like login, the callback then links the provider identity to the signed in account
+// @Tags Auth
+// @Security BearerAuth
+// @Success 302 "Redirect to the identity provider"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 404 {object} map[string]string "Single sign-on is not configured"
+// @Failure 502 {object} map[string]string "Identity provider unavailable"
+// @Router /auth/oidc/link [get]
+func (h *OIDCHandler) Link(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.ExtractActorFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	h.redirect(w, r, h.service.BeginLink(actor))
+}
+
+// redirect stores the state in a cookie and sends the browser to the provider
+func (h *OIDCHandler) redirect(w http.ResponseWriter, r *http.Request, result utils.Result[service.OIDCAuthRequest]) {
+	if result.Err != nil {
+		if errors.Is(result.Err, service.ErrOIDCDisabled) {
+			h.sendError(w, result.Err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"error": result.Err.Error()})
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    result.Data.StateToken,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, result.Data.RedirectURL, http.StatusFound)
+}
+
+// Callback godoc
+// @Summary Finish single sign-on
+// @Description Handles the provider redirect, verifies the ID token and returns a session token
+// @Tags Auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login request"
+// @Success 200 {object} model.LoginResponse
+// @Failure 400 {object} map[string]string "Invalid state or provider error"
+// @Failure 401 {object} map[string]string "ID token rejected"
+// @Failure 403 {object} map[string]string "Registration mode doesn't allow a new account"
+// @Failure 409 {object} map[string]string "An account with this email exists, or the identity is linked to another account"
+// @Router /auth/oidc/callback [get]
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// The state cookie is single use
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: "", Path: "/", MaxAge: -1})
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": providerErr, "description": query.Get("error_description")})
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		h.sendError(w, service.ErrOIDCInvalidState)
+		return
+	}
+
+	result := h.service.CompleteLogin(cookie.Value, query.Get("state"), query.Get("code"), clientInfo(r))
+	if result.Err != nil {
+		h.sendError(w, result.Err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+func (h *OIDCHandler) sendError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	status := http.StatusUnauthorized
+	switch {
+	case errors.Is(err, service.ErrOIDCDisabled):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrOIDCInvalidState):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrOIDCAccountExists), errors.Is(err, service.ErrOIDCIdentityTaken):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrRegistrationClosed), errors.Is(err, service.ErrEmailDomain):
+		status = http.StatusForbidden
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"test_project/test/internal/model"
+	"test_project/test/internal/service"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type RoadmapHandler struct {
+	service   *service.RoadmapService
+	validator *validator.Validate
+}
+
+func NewRoadmapHandler(service *service.RoadmapService) *RoadmapHandler {
+	return &RoadmapHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// GetRoadmap godoc
+// @Summary Get the public roadmap
+// @Description Ideas grouped into the columns configured in ROADMAP_COLUMNS, with a count per column. Ideas ranked by maintainers come first in each column.
+// @Tags Roadmap
+// @Produce json
+// @Success 200 {object} model.Roadmap
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /roadmap [get]
+func (h *RoadmapHandler) GetRoadmap(w http.ResponseWriter, r *http.Request) {
+	result := h.service.GetRoadmap()
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// RankColumn godoc
+// @Summary Rank ideas within a roadmap column
+// @Description Maintainers only. Replaces the manual order of the column, ideas that are not listed follow in the default order.
+// @Tags Roadmap
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param column path string true "Column key, e.g. in-progress"
+// @Param ranking body model.RankRoadmapPayload true "Idea IDs in display order"
+// @Success 200 {object} map[string]string "Ranking saved"
+// @Failure 400 {object} map[string]string "Bad request or idea not in the column"
+// @Failure 403 {object} map[string]string "Not a maintainer"
+// @Failure 404 {object} map[string]string "Unknown column"
+// @Router /roadmap/{column}/order [put]
+func (h *RoadmapHandler) RankColumn(w http.ResponseWriter, r *http.Request) {
+	var payload model.RankRoadmapPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode ranking: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RankColumn(r.PathValue("column"), payload.IdeaIDs); err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownColumn):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidRanking), errors.Is(err, service.ErrIdeaNotInColumn):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Roadmap ranking saved"})
+}
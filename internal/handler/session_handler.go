@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"test_project/test/internal/service"
+	utils "test_project/test/pkg"
+
+	"github.com/google/uuid"
+)
+
+type SessionHandler struct {
+	service     *service.SessionService
+	userService *service.UserService
+}
+
+func NewSessionHandler(service *service.SessionService, userService *service.UserService) *SessionHandler {
+	return &SessionHandler{service, userService}
+}
+
+// GetSessions godoc
+// @Summary List active sessions
+// @Description Lists where the current user is logged in. The session making the request has current set.
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.Session
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /auth/sessions [get]
+func (h *SessionHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ExtractUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, _ := utils.ExtractSessionIDFromToken(r)
+
+	result := h.service.GetSessions(userID, sessionID)
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} map[string]string "Session revoked"
+// @Failure 400 {object} map[string]string "Invalid ID format"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Session not found"
+// @Router /auth/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ExtractUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Revoke(userID, sessionID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Session revoked"})
+}
+
+// RevokeOtherSessions godoc
+// @Summary Log out all other sessions
+// @Description Revokes every session of the current user except the one making the request
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]int "Number of revoked sessions"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /auth/sessions [delete]
+func (h *SessionHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ExtractUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := utils.ExtractSessionIDFromToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result := h.service.RevokeOthers(userID, sessionID)
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int64{"revoked": result.Data})
+}
+
+// ForceLogout godoc
+// @Summary Force-logout a user
+// @Description Admin only. Revokes every session of the given user.
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Param username path string true "Username"
+// @Success 200 {object} map[string]int "Number of revoked sessions"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /auth/users/{username}/sessions [delete]
+func (h *SessionHandler) ForceLogout(w http.ResponseWriter, r *http.Request) {
+	user := h.userService.GetUserByUsername(r.PathValue("username"))
+	if user.Err != nil {
+		http.Error(w, user.Err.Error(), http.StatusNotFound)
+		return
+	}
+
+	result := h.service.RevokeAll(user.Data.ID)
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int64{"revoked": result.Data})
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"test_project/test/internal/model"
+	"test_project/test/internal/service"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type TagHandler struct {
+	service   *service.TagService
+	validator *validator.Validate
+}
+
+func NewTagHandler(service *service.TagService) *TagHandler {
+	return &TagHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// AutocompleteTags godoc
+// @Summary Autocomplete tags
+// @Description Tags in use starting with q, most used first, with the number of ideas using each
+// @Tags Tags
+// @Produce json
+// @Param q query string false "Prefix to search for"
+// @Param limit query int false "Maximum number of suggestions (default 10, max 50)"
+// @Success 200 {array} model.TagUsage
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /tags [get]
+func (h *TagHandler) AutocompleteTags(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	result := h.service.Autocomplete(r.URL.Query().Get("q"), limit)
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// RenameTag godoc
+// @Summary Rename a tag
+// @Description Admin only. Renames the tag on every idea. To combine two existing tags use merge.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Current tag name"
+// @Param tag body model.RenameTagPayload true "New name"
+// @Success 200 {object} map[string]string "Tag renamed"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Failure 404 {object} map[string]string "Tag not found"
+// @Failure 409 {object} map[string]string "New name already exists"
+// @Router /tags/{name} [patch]
+func (h *TagHandler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	var payload model.RenameTagPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode tag: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RenameTag(r.PathValue("name"), payload.Name); err != nil {
+		h.sendTagError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Tag renamed successfully"})
+}
+
+// MergeTags godoc
+// @Summary Merge tags
+// @Description Admin only. Every idea tagged with one of the sources gets the target tag instead, the sources are deleted.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param merge body model.MergeTagsPayload true "Tags to merge and the tag to keep"
+// @Success 200 {object} map[string]string "Tags merged"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Failure 404 {object} map[string]string "No source tag found"
+// @Router /tags/merge [post]
+func (h *TagHandler) MergeTags(w http.ResponseWriter, r *http.Request) {
+	var payload model.MergeTagsPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode merge: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.MergeTags(payload); err != nil {
+		h.sendTagError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Tags merged successfully"})
+}
+
+func (h *TagHandler) sendTagError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrTagNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrTagExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidTag):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
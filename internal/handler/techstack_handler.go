@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"test_project/test/internal/model"
+	"test_project/test/internal/service"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type TechStackHandler struct {
+	service   *service.TechStackService
+	validator *validator.Validate
+}
+
+func NewTechStackHandler(service *service.TechStackService) *TechStackHandler {
+	return &TechStackHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// GetTechnologies godoc
+// @Summary List the tech-stack catalog
+// @Description Technologies ideas can use, with aliases, icon and colour. Deprecated entries are only listed with includeDeprecated=true.
+// @Tags TechStacks
+// @Produce json
+// @Param includeDeprecated query bool false "Also list deprecated entries"
+// @Success 200 {array} model.Technology
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /tech-stacks [get]
+func (h *TechStackHandler) GetTechnologies(w http.ResponseWriter, r *http.Request) {
+	result := h.service.GetTechnologies(r.URL.Query().Get("includeDeprecated") == "true")
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// CreateTechnology godoc
+// @Summary Add a technology to the catalog
+// @Description Admin only. Names and aliases must be unique across the catalog, ignoring case.
+// @Tags TechStacks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param technology body model.CreateTechnologyPayload true "Name, aliases, icon and colour"
+// @Success 201 {object} model.Technology
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Failure 409 {object} map[string]string "Name or alias already used"
+// @Router /tech-stacks [post]
+func (h *TechStackHandler) CreateTechnology(w http.ResponseWriter, r *http.Request) {
+	var payload model.CreateTechnologyPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode technology: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := h.service.CreateTechnology(payload)
+	if result.Err != nil {
+		h.sendTechError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// UpdateTechnology godoc
+// @Summary Update a catalog entry
+// @Description Admin only. Omitted fields are left as they are. A renamed entry keeps its old name as an alias. Deprecated entries cannot be added to ideas anymore.
+// @Tags TechStacks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Technology ID"
+// @Param technology body model.UpdateTechnologyPayload true "Fields to change"
+// @Success 200 {object} model.Technology
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Failure 404 {object} map[string]string "Technology not found"
+// @Failure 409 {object} map[string]string "Name or alias already used"
+// @Router /tech-stacks/{id} [patch]
+func (h *TechStackHandler) UpdateTechnology(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid technology ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var payload model.UpdateTechnologyPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode technology: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := h.service.UpdateTechnology(id, payload)
+	if result.Err != nil {
+		h.sendTechError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// DeleteTechnology godoc
+// @Summary Delete a catalog entry
+// @Description Admin only. Entries used by ideas cannot be deleted, deprecate them instead.
+// @Tags TechStacks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Technology ID"
+// @Success 200 {object} map[string]string "Technology deleted"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Failure 404 {object} map[string]string "Technology not found"
+// @Failure 409 {object} map[string]string "Technology is in use"
+// @Router /tech-stacks/{id} [delete]
+func (h *TechStackHandler) DeleteTechnology(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid technology ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteTechnology(id); err != nil {
+		h.sendTechError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Technology deleted successfully"})
+}
+
+func (h *TechStackHandler) sendTechError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrTechNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrTechConflict), errors.Is(err, service.ErrTechInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidTech):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"test_project/test/internal/model"
+	"test_project/test/internal/service"
+	utils "test_project/test/pkg"
+)
+
+// LoginTOTP godoc
+// @Summary Complete a two-factor login
+// @Description Exchanges the challenge token from /auth/login and a TOTP or recovery code for a session token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body model.TOTPLoginRequest true "Challenge token and code"
+// @Success 200 {object} model.LoginResponse
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Invalid challenge or code"
+// @Failure 429 {object} map[string]string "Too many failed codes, 2FA is locked for a while"
+// @Router /auth/login/totp [post]
+func (h *AuthHandler) LoginTOTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req model.TOTPLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := h.userService.CompleteTOTPLogin(req, clientInfo(r))
+	if result.Err != nil {
+		h.sendTOTPError(w, result.Err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// EnrollTOTP godoc
+// @Summary Start two-factor enrollment
+// @Description Generates a new TOTP secret and otpauth URI. 2FA is enabled once the secret is confirmed.
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.TOTPEnrollResponse
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 409 {object} map[string]string "2FA already enabled"
+// @Router /auth/totp/enroll [post]
+func (h *AuthHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, err := utils.ExtractClaimsFromToken(r)
+	if err != nil {
+		h.sendError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := utils.ClaimUserID(claims)
+	if err != nil {
+		h.sendError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result := h.userService.BeginTOTPEnrollment(userID)
+	if result.Err != nil {
+		h.sendTOTPError(w, result.Err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm two-factor enrollment
+// @Description Verifies a code for the pending secret, enables 2FA and returns one-time recovery codes
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.TOTPConfirmRequest true "Current TOTP code"
+// @Success 200 {object} model.TOTPConfirmResponse
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Invalid code"
+// @Failure 429 {object} map[string]string "Too many failed codes, 2FA is locked for a while"
+// @Router /auth/totp/confirm [post]
+func (h *AuthHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, err := utils.ExtractClaimsFromToken(r)
+	if err != nil {
+		h.sendError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := utils.ClaimUserID(claims)
+	if err != nil {
+		h.sendError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.TOTPConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := h.userService.ConfirmTOTPEnrollment(userID, req.Code)
+	if result.Err != nil {
+		h.sendTOTPError(w, result.Err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.TOTPConfirmResponse{RecoveryCodes: result.Data})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replaces all recovery codes, previous codes stop working
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.TOTPConfirmRequest true "Current TOTP code"
+// @Success 200 {object} model.TOTPConfirmResponse
+// @Failure 401 {object} map[string]string "Invalid code"
+// @Failure 429 {object} map[string]string "Too many failed codes, 2FA is locked for a while"
+// @Router /auth/totp/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := utils.ExtractUserIDFromToken(r)
+	if err != nil {
+		h.sendError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.TOTPConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := h.userService.RegenerateRecoveryCodes(userID, req.Code)
+	if result.Err != nil {
+		h.sendTOTPError(w, result.Err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.TOTPConfirmResponse{RecoveryCodes: result.Data})
+}
+
+// DisableTOTP godoc
+// @Summary Disable two-factor authentication
+// @Description Turns off 2FA after checking the password and a current code. Not allowed for roles that require 2FA.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.TOTPDisableRequest true "Password and current TOTP code"
+// @Success 200 {object} map[string]string "2FA disabled"
+// @Failure 401 {object} map[string]string "Invalid password or code"
+// @Failure 403 {object} map[string]string "Role requires 2FA"
+// @Failure 429 {object} map[string]string "Too many failed codes, 2FA is locked for a while"
+// @Router /auth/totp [delete]
+func (h *AuthHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := utils.ExtractUserIDFromToken(r)
+	if err != nil {
+		h.sendError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.TOTPDisableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userService.DisableTOTP(userID, req.Password, req.Code); err != nil {
+		h.sendTOTPError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+func (h *AuthHandler) sendTOTPError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials), errors.Is(err, service.ErrInvalidTOTPCode):
+		h.sendError(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, service.ErrTOTPRequired):
+		h.sendError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrTOTPAlreadyEnabled), errors.Is(err, service.ErrTOTPNotEnabled):
+		h.sendError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrTOTPLocked):
+		h.sendError(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+	}
+}
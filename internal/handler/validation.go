@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type validationErrorResponse struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// newValidator returns a validator that names fields by their JSON name, so
+// errors point at the field the client actually sent
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return f.Name
+		}
+		return name
+	})
+	return v
+}
+
+// decodeStrict decodes the request body and rejects fields the payload does
+// not have
+func decodeStrict(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// sendValidationError answers 400 with a message per invalid field. err comes
+// from decodeStrict or from the validator.
+func sendValidationError(w http.ResponseWriter, message string, err error) {
+	response := validationErrorResponse{Error: message, Fields: map[string]string{}}
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		for _, fe := range validationErrs {
+			response.Fields[fieldPath(fe)] = fieldMessage(fe)
+		}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		response.Fields[jsonFieldPath(typeErr.Field)] = "must be " + jsonTypeName(typeErr.Type)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		response.Fields[field] = "is not a known field"
+	default:
+		response.Error = fmt.Sprintf("%s: %v", message, err)
+		response.Fields = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(response)
+}
+
+// fieldPath drops the struct name from the namespace, "techStack[2]" rather
+// than "CreateIdeaPayload.techStack[2]"
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+// jsonFieldPath writes the "tags.0" paths of encoding/json as "tags[0]",
+// like the validator does
+func jsonFieldPath(field string) string {
+	var path strings.Builder
+	for i, part := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(part); err == nil {
+			path.WriteString("[" + part + "]")
+			continue
+		}
+		if i > 0 {
+			path.WriteString(".")
+		}
+		path.WriteString(part)
+	}
+	return path.String()
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	unit := "characters"
+	if kind := fe.Kind(); kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map {
+		unit = "items"
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "max":
+		return fmt.Sprintf("must have at most %s %s", fe.Param(), unit)
+	case "min":
+		return fmt.Sprintf("must have at least %s %s", fe.Param(), unit)
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "http_url":
+		return "must be an http or https URL"
+	default:
+		return fmt.Sprintf("failed the %s check", fe.Tag())
+	}
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	default:
+		return "a " + t.String()
+	}
+}
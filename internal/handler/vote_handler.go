@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"test_project/test/internal/service"
@@ -29,11 +30,13 @@ func NewVoteHandler(service *service.VoteService) *VoteHandler {
 // @Success 200 {object} map[string]string "Vote added successfully"
 // @Failure 400 {object} map[string]string "Bad request - invalid ID format"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
-// @Failure 409 {object} map[string]string "Conflict - user has already voted"
+// @Failure 403 {object} map[string]string "Forbidden - the board is members only"
+// @Failure 404 {object} map[string]string "Idea not found"
+// @Failure 409 {object} map[string]string "Conflict - user has already voted, voting is closed on the board, or the idea was merged"
 // @Failure 500 {object} map[string]string "Server error"
 // @Router /idea/{id}/vote [post]
 func (h *VoteHandler) AddVote(w http.ResponseWriter, r *http.Request) {
-	userID, err := utils.ExtractUserIDFromToken(r)
+	actor, err := utils.ExtractActorFromToken(r)
 	if err != nil {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
@@ -51,8 +54,11 @@ func (h *VoteHandler) AddVote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := h.service.AddVote(userID, ideaID)
+	result := h.service.AddVote(actor, ideaID)
 	if result.Err != nil {
+		if h.sendBoardError(w, result.Err) {
+			return
+		}
 		// Check if it's a "already voted" error
 		if result.Err.Error() == "User has already voted" {
 			http.Error(w, result.Err.Error(), http.StatusConflict)
@@ -80,10 +86,11 @@ func (h *VoteHandler) AddVote(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {object} map[string]string "Bad request - invalid ID format"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
 // @Failure 404 {object} map[string]string "Not found - user has not voted for this idea"
+// @Failure 409 {object} map[string]string "Conflict - voting is closed on the board, or the idea was merged"
 // @Failure 500 {object} map[string]string "Server error"
 // @Router /idea/{id}/vote [delete]
 func (h *VoteHandler) RemoveVote(w http.ResponseWriter, r *http.Request) {
-	userID, err := utils.ExtractUserIDFromToken(r)
+	actor, err := utils.ExtractActorFromToken(r)
 	if err != nil {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
@@ -101,8 +108,11 @@ func (h *VoteHandler) RemoveVote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := h.service.RemoveVote(userID, ideaID)
+	result := h.service.RemoveVote(actor, ideaID)
 	if result.Err != nil {
+		if h.sendBoardError(w, result.Err) {
+			return
+		}
 		// Check if it's a "not voted" error
 		if result.Err.Error() == "User has not voted for this idea" {
 			http.Error(w, result.Err.Error(), http.StatusNotFound)
@@ -128,10 +138,12 @@ func (h *VoteHandler) RemoveVote(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} map[string]bool "Returns voting status"
 // @Failure 400 {object} map[string]string "Bad request - invalid ID format"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 404 {object} map[string]string "Idea not found"
+// @Failure 409 {object} map[string]string "Conflict - the idea was merged into another idea"
 // @Failure 500 {object} map[string]string "Server error"
 // @Router /idea/{id}/vote/status [get]
 func (h *VoteHandler) HasUserVoted(w http.ResponseWriter, r *http.Request) {
-	userID, err := utils.ExtractUserIDFromToken(r)
+	actor, err := utils.ExtractActorFromToken(r)
 	if err != nil {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
@@ -149,8 +161,11 @@ func (h *VoteHandler) HasUserVoted(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := h.service.HasUserVoted(userID, ideaID)
+	result := h.service.HasUserVoted(actor, ideaID)
 	if result.Err != nil {
+		if h.sendBoardError(w, result.Err) {
+			return
+		}
 		http.Error(w, fmt.Sprintf("failed to check vote status: %v", result.Err), http.StatusInternalServerError)
 		return
 	}
@@ -169,6 +184,8 @@ func (h *VoteHandler) HasUserVoted(w http.ResponseWriter, r *http.Request) {
 // @Param id path string true "Idea ID"
 // @Success 200 {object} map[string]int "Returns vote count"
 // @Failure 400 {object} map[string]string "Bad request - invalid ID format"
+// @Failure 404 {object} map[string]string "Idea not found"
+// @Failure 409 {object} map[string]string "Conflict - the idea was merged into another idea"
 // @Failure 500 {object} map[string]string "Server error"
 // @Router /idea/{id}/votes [get]
 func (h *VoteHandler) GetVoteCount(w http.ResponseWriter, r *http.Request) {
@@ -184,8 +201,13 @@ func (h *VoteHandler) GetVoteCount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := h.service.GetVoteCount(ideaID)
+	viewer, _ := utils.ExtractActorFromToken(r)
+
+	result := h.service.GetVoteCount(ideaID, viewer)
 	if result.Err != nil {
+		if h.sendBoardError(w, result.Err) {
+			return
+		}
 		http.Error(w, fmt.Sprintf("failed to get vote count: %v", result.Err), http.StatusInternalServerError)
 		return
 	}
@@ -195,3 +217,19 @@ func (h *VoteHandler) GetVoteCount(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
 	}
 }
+
+// sendBoardError answers errors about the idea and its board, it reports
+// whether err was one of them
+func (h *VoteHandler) sendBoardError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrIdeaNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrVotingClosed), errors.Is(err, service.ErrIdeaMerged):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrNotBoardMember):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		return false
+	}
+	return true
+}
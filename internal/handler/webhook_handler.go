@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"test_project/test/internal/model"
+	"test_project/test/internal/service"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type WebhookHandler struct {
+	service   *service.WebhookService
+	validator *validator.Validate
+}
+
+func NewWebhookHandler(service *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		service:   service,
+		validator: newValidator(),
+	}
+}
+
+// CreateWebhook godoc
+// @Summary Create a webhook
+// @Description Admin only. Events: idea.created, idea.status_changed, idea.deleted, vote.added, vote.removed. The signing secret is only returned in this response.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook body model.CreateWebhookPayload true "URL, events and an optional description"
+// @Success 201 {object} model.CreateWebhookResponse
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var payload model.CreateWebhookPayload
+	if err := decodeStrict(r, &payload); err != nil {
+		sendValidationError(w, "failed to decode webhook", err)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		sendValidationError(w, "invalid webhook", err)
+		return
+	}
+
+	result := h.service.CreateWebhook(payload)
+	if result.Err != nil {
+		h.sendWebhookError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// GetWebhooks godoc
+// @Summary List webhooks
+// @Tags Webhooks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.Webhook
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Router /webhooks [get]
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	result := h.service.GetWebhooks()
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook
+// @Tags Webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} model.Webhook
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	result := h.service.GetWebhook(id)
+	if result.Err != nil {
+		h.sendWebhookError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook
+// @Description Admin only. Omitted fields are left as they are. Set active to true to enable a disabled webhook again.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Param webhook body model.UpdateWebhookPayload true "Fields to change"
+// @Success 200 {object} model.Webhook
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Router /webhooks/{id} [patch]
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var payload model.UpdateWebhookPayload
+	if err := decodeStrict(r, &payload); err != nil {
+		sendValidationError(w, "failed to decode webhook", err)
+		return
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		sendValidationError(w, "invalid webhook", err)
+		return
+	}
+
+	result := h.service.UpdateWebhook(id, payload)
+	if result.Err != nil {
+		h.sendWebhookError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Admin only. The delivery log is deleted as well.
+// @Tags Webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} map[string]string "Webhook deleted"
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteWebhook(id); err != nil {
+		h.sendWebhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Webhook deleted successfully"})
+}
+
+// GetDeliveries godoc
+// @Summary Delivery log of a webhook
+// @Description Newest first, with the response code and error of the last attempt
+// @Tags Webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Param limit query int false "Maximum number of deliveries (default 50, max 500)"
+// @Success 200 {array} model.WebhookDelivery
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	result := h.service.GetDeliveries(id, limit)
+	if result.Err != nil {
+		h.sendWebhookError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+// Redeliver godoc
+// @Summary Redeliver a delivery
+// @Description Admin only. Queues the payload again as a new delivery.
+// @Tags Webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Success 202 {object} model.WebhookDelivery
+// @Failure 404 {object} map[string]string "Webhook or delivery not found"
+// @Failure 409 {object} map[string]string "Webhook is disabled"
+// @Router /webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryId"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid delivery ID format: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	result := h.service.Redeliver(id, deliveryID)
+	if result.Err != nil {
+		h.sendWebhookError(w, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(result.Data)
+}
+
+func (h *WebhookHandler) sendWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrWebhookNotFound), errors.Is(err, service.ErrDeliveryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrWebhookInactive):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidWebhook):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdeaAssignee is a maintainer working on an idea
+type IdeaAssignee struct {
+	IdeaID    uuid.UUID `json:"ideaId" gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `json:"userId" gorm:"type:uuid;primaryKey;index"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// IdeaFilter narrows idea lists, empty fields match every idea
+type IdeaFilter struct {
+	// Username of an assignee
+	Assignee string
+}
+
+// Matches reports whether the idea passes the filter
+func (f IdeaFilter) Matches(idea Idea) bool {
+	return f.Assignee == "" || slices.Contains(idea.Assignees, f.Assignee)
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attachment is a file uploaded to an idea. The content lives in the blob
+// store under BlobKey, ContentType is sniffed from the content rather than
+// taken from the upload.
+type Attachment struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	IdeaID       uuid.UUID  `json:"ideaId" gorm:"type:uuid;not null;index"`
+	UploaderID   *uuid.UUID `json:"uploaderId,omitempty" gorm:"type:uuid;index"`
+	UploaderName string     `json:"uploaderName" gorm:"type:varchar(100)"`
+	Filename     string     `json:"filename" gorm:"type:varchar(255);not null"`
+	ContentType  string     `json:"contentType" gorm:"type:varchar(100);not null"`
+	Size         int64      `json:"size" gorm:"not null"`
+	SHA256       string     `json:"sha256" gorm:"type:varchar(64);not null"`
+	BlobKey      string     `json:"-" gorm:"type:varchar(100);not null;uniqueIndex"`
+	CreatedAt    time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultBoardSlug is the board behind the routes that predate boards, like
+// POST /idea and GET /ideas
+const DefaultBoardSlug = "default"
+
+// BoardRole is what a member may do on a board
+type BoardRole string
+
+const (
+	// BoardMember may file ideas and vote on a members-only board
+	BoardMember BoardRole = "member"
+	// BoardOwner may also change the board's settings and members
+	BoardOwner BoardRole = "owner"
+)
+
+// Board is a separate space for ideas, e.g. per product or team
+type Board struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	Slug        string    `json:"slug" gorm:"type:varchar(50);uniqueIndex;not null"`
+	Name        string    `json:"name" gorm:"type:varchar(100);not null"`
+	Description string    `json:"description" gorm:"type:text"`
+	// Statuses ideas on the board can have, empty allows every status
+	AllowedStatuses []RequestStatus `json:"allowedStatuses" gorm:"type:jsonb;serializer:json"`
+	VotingOpen      bool            `json:"votingOpen" gorm:"not null;default:true"`
+	// Only members can file ideas and vote, everyone can read
+	MembersOnly bool      `json:"membersOnly" gorm:"not null;default:false"`
+	CreatedAt   time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// AllowsStatus reports whether ideas on the board can have the status
+func (b Board) AllowsStatus(status RequestStatus) bool {
+	return len(b.AllowedStatuses) == 0 || slices.Contains(b.AllowedStatuses, status)
+}
+
+type BoardMembership struct {
+	BoardID   uuid.UUID `json:"boardId" gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `json:"userId" gorm:"type:uuid;primaryKey;index"`
+	Username  string    `json:"username" gorm:"-"`
+	Role      BoardRole `json:"role" gorm:"type:varchar(20);not null"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+type CreateBoardPayload struct {
+	Slug            string          `json:"slug" validate:"required,max=50"`
+	Name            string          `json:"name" validate:"required,max=100"`
+	Description     string          `json:"description" validate:"max=2000"`
+	AllowedStatuses []RequestStatus `json:"allowedStatuses" validate:"max=6,dive,oneof=requested reviewing planned in-progress published rejected"`
+	// Defaults to true
+	VotingOpen  *bool `json:"votingOpen,omitempty"`
+	MembersOnly bool  `json:"membersOnly"`
+}
+
+type UpdateBoardPayload struct {
+	Name            *string          `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Description     *string          `json:"description,omitempty" validate:"omitempty,max=2000"`
+	AllowedStatuses *[]RequestStatus `json:"allowedStatuses,omitempty" validate:"omitempty,max=6,dive,oneof=requested reviewing planned in-progress published rejected"`
+	VotingOpen      *bool            `json:"votingOpen,omitempty"`
+	MembersOnly     *bool            `json:"membersOnly,omitempty"`
+}
+
+type SetBoardMemberPayload struct {
+	Role BoardRole `json:"role" validate:"required,oneof=member owner"`
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Comment on an idea. Comments with a ParentID are replies, only one level of
+// nesting is allowed. Body is markdown, BodyHTML its sanitized rendering.
+type Comment struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	IdeaID     uuid.UUID  `json:"ideaId" gorm:"type:uuid;not null;index"`
+	ParentID   *uuid.UUID `json:"parentId,omitempty" gorm:"type:uuid;index"`
+	AuthorID   *uuid.UUID `json:"authorId,omitempty" gorm:"type:uuid;index"`
+	AuthorName string     `json:"authorName" gorm:"type:varchar(100)"`
+	Body       string     `json:"body" gorm:"type:text;not null"`
+	BodyHTML   string     `json:"bodyHtml" gorm:"type:text"`
+	Edited     bool       `json:"edited" gorm:"not null;default:false"`
+	Replies    []Comment  `json:"replies,omitempty" gorm:"-"`
+	CreatedAt  time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// IsAuthor reports whether the viewer wrote the comment
+func (c Comment) IsAuthor(viewer Actor) bool {
+	return viewer.ID != uuid.Nil && c.AuthorID != nil && *c.AuthorID == viewer.ID
+}
+
+type CreateCommentPayload struct {
+	Body     string     `json:"body" validate:"required,max=10000"`
+	ParentID *uuid.UUID `json:"parentId,omitempty"`
+}
+
+type UpdateCommentPayload struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+// Actor is the authenticated user performing an action, as read from the token
+type Actor struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	Role     Role      `json:"role"`
+}
+
+// CanModerate reports whether the actor may change content owned by ownerID
+func (a Actor) CanModerate(ownerID uuid.UUID) bool {
+	return a.ID == ownerID || a.Role.AtLeast(RoleModerator)
+}
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType names a domain event
+type EventType string
+
+const (
+	EventIdeaCreated       EventType = "idea.created"
+	EventIdeaUpdated       EventType = "idea.updated"
+	EventIdeaStatusChanged EventType = "idea.status_changed"
+	EventIdeaDeleted       EventType = "idea.deleted"
+	EventIdeaMerged        EventType = "idea.merged"
+	EventIdeaPublished     EventType = "idea.published"
+	EventVoteAdded         EventType = "vote.added"
+	EventVoteRemoved       EventType = "vote.removed"
+	EventCommentCreated    EventType = "comment.created"
+	EventUserRegistered    EventType = "user.registered"
+	EventUserDeleted       EventType = "user.deleted"
+)
+
+// EventPayload is the typed content of a domain event
+type EventPayload interface {
+	EventType() EventType
+}
+
+type IdeaCreated struct {
+	Idea Idea `json:"idea"`
+}
+
+// IdeaUpdated is an edit that left the status as it was
+type IdeaUpdated struct {
+	Idea  Idea  `json:"idea"`
+	Actor Actor `json:"actor"`
+}
+
+type IdeaStatusChanged struct {
+	Idea   Idea          `json:"idea"`
+	From   RequestStatus `json:"from"`
+	To     RequestStatus `json:"to"`
+	Reason string        `json:"reason,omitempty"`
+	Actor  Actor         `json:"actor"`
+}
+
+// IdeaDeleted, VoteAdded and VoteRemoved carry the visibility the idea had,
+// so subscribers can leave out ideas not everyone may see
+type IdeaDeleted struct {
+	IdeaID     uuid.UUID  `json:"ideaId"`
+	Visibility Visibility `json:"visibility"`
+}
+
+type IdeaMerged struct {
+	SourceID uuid.UUID `json:"sourceId"`
+	TargetID uuid.UUID `json:"targetId"`
+}
+
+// IdeaPublished is an idea becoming public, whether through the publish
+// action or an edit of its visibility
+type IdeaPublished struct {
+	Idea  Idea       `json:"idea"`
+	From  Visibility `json:"from"`
+	Actor Actor      `json:"actor"`
+}
+
+type VoteAdded struct {
+	IdeaID     uuid.UUID  `json:"ideaId"`
+	UserID     uuid.UUID  `json:"userId"`
+	Visibility Visibility `json:"visibility"`
+}
+
+type VoteRemoved struct {
+	IdeaID     uuid.UUID  `json:"ideaId"`
+	UserID     uuid.UUID  `json:"userId"`
+	Visibility Visibility `json:"visibility"`
+}
+
+// CommentCreated carries the parent comment of a reply, so subscribers know
+// whom it answers
+type CommentCreated struct {
+	Comment   Comment  `json:"comment"`
+	IdeaTitle string   `json:"ideaTitle"`
+	Parent    *Comment `json:"parent,omitempty"`
+}
+
+type UserRegistered struct {
+	UserID   uuid.UUID `json:"userId"`
+	Username string    `json:"username"`
+}
+
+type UserDeleted struct {
+	UserID uuid.UUID      `json:"userId"`
+	Policy DeletionPolicy `json:"policy"`
+}
+
+func (IdeaCreated) EventType() EventType       { return EventIdeaCreated }
+func (IdeaUpdated) EventType() EventType       { return EventIdeaUpdated }
+func (IdeaStatusChanged) EventType() EventType { return EventIdeaStatusChanged }
+func (IdeaDeleted) EventType() EventType       { return EventIdeaDeleted }
+func (IdeaMerged) EventType() EventType        { return EventIdeaMerged }
+func (IdeaPublished) EventType() EventType     { return EventIdeaPublished }
+func (VoteAdded) EventType() EventType         { return EventVoteAdded }
+func (VoteRemoved) EventType() EventType       { return EventVoteRemoved }
+func (CommentCreated) EventType() EventType    { return EventCommentCreated }
+func (UserRegistered) EventType() EventType    { return EventUserRegistered }
+func (UserDeleted) EventType() EventType       { return EventUserDeleted }
+
+// OutboxEvent is a domain event waiting in the outbox. It is written in the
+// same transaction as the change it describes and dispatched afterwards.
+// Delivered lists the subscribers that already handled it, a retry only goes
+// to the others.
+type OutboxEvent struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	Type          EventType  `json:"type" gorm:"type:varchar(50);not null"`
+	Payload       string     `json:"payload" gorm:"type:jsonb;not null"`
+	OccurredAt    time.Time  `json:"occurredAt" gorm:"not null"`
+	Attempts      int        `json:"attempts" gorm:"not null;default:0"`
+	Delivered     []string   `json:"delivered" gorm:"type:jsonb;serializer:json"`
+	LastError     string     `json:"lastError,omitempty"`
+	NextAttemptAt time.Time  `json:"nextAttemptAt" gorm:"index"`
+	DispatchedAt  *time.Time `json:"dispatchedAt,omitempty" gorm:"index"`
+}
+
+func NewOutboxEvent(payload EventPayload) (OutboxEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return OutboxEvent{}, err
+	}
+
+	now := time.Now()
+	return OutboxEvent{
+		ID:            uuid.New(),
+		Type:          payload.EventType(),
+		Payload:       string(data),
+		OccurredAt:    now,
+		NextAttemptAt: now,
+	}, nil
+}
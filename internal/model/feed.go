@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Feed is a list of ideas for feed readers, rendered as Atom or RSS. Link is
+// the absolute URL of the feed without the format extension.
+type Feed struct {
+	Title    string
+	Subtitle string
+	Link     string
+	Updated  time.Time
+	Entries  []FeedEntry
+}
+
+// FeedEntry is one idea in a feed. ID stays the same for the idea across
+// feeds and edits, readers use it to recognise entries they have seen.
+type FeedEntry struct {
+	ID      string
+	Title   string
+	Link    string
+	Summary string
+	// Sanitized HTML of the description
+	Content    string
+	Author     string
+	Categories []string
+	// Where to find the result of the idea
+	Links     []IdeaLink
+	Published time.Time
+	Updated   time.Time
+}
@@ -0,0 +1,14 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Follow subscribes a user to the changes of an idea
+type Follow struct {
+	IdeaID    uuid.UUID `json:"ideaId" gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `json:"userId" gorm:"type:uuid;primaryKey;index"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
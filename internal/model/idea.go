@@ -1,7 +1,6 @@
 package model
 
 import (
-	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,10 +10,6 @@ import (
 // @Description Status of the idea request
 type RequestStatus string
 
-// TechStack represents the technology stack
-// @Description Technology stack for the idea
-type TechStack string
-
 const (
 	Requested  RequestStatus = "requested"
 	Reviewing  RequestStatus = "reviewing"
@@ -24,50 +19,131 @@ const (
 	Rejected   RequestStatus = "rejected"
 )
 
+// Visibility decides who can see an idea
+// @Description Visibility of the idea
+type Visibility string
+
 const (
-	Rust      TechStack = "Rust"
-	Go        TechStack = "Go"
-	Next      TechStack = "Next"
-	React     TechStack = "React"
-	Axum      TechStack = "Axum"
-	Postgres  TechStack = "Postgres"
-	MySQL     TechStack = "MySQL"
-	Docker    TechStack = "Docker"
-	ActixWeb  TechStack = "ActixWeb"
-	ChiRouter TechStack = "ChiRouter"
-	Node      TechStack = "Node"
+	// VisibilityDraft is only visible to the author
+	VisibilityDraft Visibility = "draft"
+	// VisibilityPrivate is visible to the author and maintainers
+	VisibilityPrivate Visibility = "private"
+	// VisibilityUnlisted is visible to anyone with the link but not listed
+	VisibilityUnlisted Visibility = "unlisted"
+	VisibilityPublic   Visibility = "public"
 )
 
 type Idea struct {
-	ID          uuid.UUID       `json:"id" gorm:"type:uuid;primaryKey"`
-	Title       string          `json:"title" gorm:"not null"`
-	Description string          `json:"description" gorm:"type:text"`
-	TechStack   json.RawMessage `json:"techStack" gorm:"type:jsonb"`
-	Tags        json.RawMessage `json:"tags" gorm:"type:jsonb"`
-	Status      RequestStatus   `json:"status" gorm:"type:varchar(20);default:'requested'"`
-	Votes       []Vote          `json:"votes,omitempty" gorm:"not null;foreignKey:IdeaID;default:0"`
-	VoteCount   int             `json:"voteCount" gorm:"-"`
-	RequestedBy string          `json:"requestedBy" gorm:"type:varchar(100)"`
-	CreatedAt   time.Time       `json:"createdAt" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time       `json:"updatedAt" gorm:"autoUpdateTime"`
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	BoardID     uuid.UUID `json:"boardId" gorm:"type:uuid;index"`
+	Title       string    `json:"title" gorm:"not null"`
+	Description string    `json:"description" gorm:"type:text"`
+	// Description rendered from markdown and sanitized, set by the server
+	DescriptionHTML string        `json:"descriptionHtml" gorm:"type:text"`
+	TechStack       []string      `json:"techStack" gorm:"type:jsonb;serializer:json"`
+	Tags            []string      `json:"tags" gorm:"-"`
+	Status          RequestStatus `json:"status" gorm:"type:varchar(20);default:'requested'"`
+	Visibility      Visibility    `json:"visibility" gorm:"type:varchar(20);not null;default:'public';index"`
+	Votes           []Vote        `json:"votes,omitempty" gorm:"not null;foreignKey:IdeaID;default:0"`
+	VoteCount       int           `json:"voteCount" gorm:"-"`
+	CommentCount    int           `json:"commentCount" gorm:"-"`
+	RequestedBy     string        `json:"requestedBy" gorm:"type:varchar(100)"`
+	AuthorID        *uuid.UUID    `json:"authorId,omitempty" gorm:"type:uuid;index"`
+	// Usernames of the maintainers working on the idea
+	Assignees []string `json:"assignees" gorm:"-"`
+	// Where to find the result, required to publish the idea
+	Links []IdeaLink `json:"links" gorm:"-"`
+	// Set when the idea was merged into another one, the idea is then only a
+	// redirect to that idea
+	MergedInto *uuid.UUID `json:"mergedInto,omitempty" gorm:"type:uuid;index"`
+	// Position within its roadmap column, 0 means not ranked by hand
+	RoadmapRank int `json:"roadmapRank,omitempty" gorm:"not null;default:0"`
+	// Milestone the idea is planned for
+	MilestoneID *uuid.UUID `json:"milestoneId,omitempty" gorm:"type:uuid;index"`
+	CreatedAt   time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
 }
 
 type CreateIdeaPayload struct {
-	Title       string          `json:"title"`
-	Description string          `json:"description"`
-	TechStack   json.RawMessage `json:"techStack"`
-	Tags        json.RawMessage `json:"tags"`
-	Status      RequestStatus   `json:"status,omitempty"`
-	RequestedBy string          `json:"requestedBy,omitempty"`
+	Title       string        `json:"title" validate:"required,max=200"`
+	Description string        `json:"description" validate:"max=10000"`
+	TechStack   []string      `json:"techStack" validate:"max=20,dive,required,max=50"`
+	Tags        []string      `json:"tags" validate:"max=20,dive,required,max=50"`
+	Status      RequestStatus `json:"status,omitempty" validate:"omitempty,oneof=requested reviewing planned in-progress published rejected"`
+	// Defaults to public
+	Visibility Visibility        `json:"visibility,omitempty" validate:"omitempty,oneof=draft private unlisted public"`
+	Links      []IdeaLinkPayload `json:"links" validate:"max=10,dive"`
+	// Ignored, the author is taken from the token
+	RequestedBy string `json:"requestedBy,omitempty" validate:"max=100"`
 }
 
 type UpdateIdeaPayload struct {
-	Title       *string          `json:"title,omitempty"`
-	Description *string          `json:"description,omitempty"`
-	TechStack   *json.RawMessage `json:"techStack,omitempty"`
-	Tags        *json.RawMessage `json:"tags,omitempty"`
-	Status      *RequestStatus   `json:"status,omitempty"`
-	RequestedBy *string          `json:"requestedBy,omitempty"`
+	Title       *string        `json:"title,omitempty" validate:"omitempty,min=1,max=200"`
+	Description *string        `json:"description,omitempty" validate:"omitempty,max=10000"`
+	TechStack   *[]string      `json:"techStack,omitempty" validate:"omitempty,max=20,dive,required,max=50"`
+	Tags        *[]string      `json:"tags,omitempty" validate:"omitempty,max=20,dive,required,max=50"`
+	Status      *RequestStatus `json:"status,omitempty" validate:"omitempty,oneof=requested reviewing planned in-progress published rejected"`
+	RequestedBy *string        `json:"requestedBy,omitempty" validate:"omitempty,min=1,max=100"`
+	// Only the author and maintainers can change it
+	Visibility *Visibility `json:"visibility,omitempty" validate:"omitempty,oneof=draft private unlisted public"`
+	// Why the status changed, required for statuses in STATUS_REASON_REQUIRED
+	StatusReason *string `json:"statusReason,omitempty" validate:"omitempty,max=1000"`
+	// Replaces the links, publishing needs at least one and a published idea
+	// can't lose its last one
+	Links *[]IdeaLinkPayload `json:"links,omitempty" validate:"omitempty,max=10,dive"`
+}
+
+// EffectiveVisibility treats ideas saved before visibilities existed as public
+func (i Idea) EffectiveVisibility() Visibility {
+	if i.Visibility == "" {
+		return VisibilityPublic
+	}
+	return i.Visibility
+}
+
+// IsAuthor reports whether the viewer filed the idea
+func (i Idea) IsAuthor(viewer Actor) bool {
+	return viewer.ID != uuid.Nil && i.AuthorID != nil && *i.AuthorID == viewer.ID
+}
+
+// VisibleTo reports whether the viewer may open the idea. Anonymous viewers
+// are the zero Actor.
+func (i Idea) VisibleTo(viewer Actor) bool {
+	switch i.EffectiveVisibility() {
+	case VisibilityPublic, VisibilityUnlisted:
+		return true
+	case VisibilityPrivate:
+		return i.IsAuthor(viewer) || viewer.Role.AtLeast(RoleMaintainer)
+	}
+	return i.IsAuthor(viewer)
+}
+
+// ListedFor reports whether the idea shows up in lists for the viewer.
+// Unlisted ideas are only listed for their author.
+func (i Idea) ListedFor(viewer Actor) bool {
+	if i.EffectiveVisibility() == VisibilityUnlisted {
+		return i.IsAuthor(viewer)
+	}
+	return i.VisibleTo(viewer)
+}
+
+// DuplicateCandidate is an existing idea that looks like the one being filed.
+// Score is between 0 and 1.
+type DuplicateCandidate struct {
+	ID     uuid.UUID     `json:"id"`
+	Title  string        `json:"title"`
+	Status RequestStatus `json:"status"`
+	Score  float64       `json:"score"`
+}
+
+type CreateIdeaResponse struct {
+	Result             string               `json:"result"`
+	ID                 uuid.UUID            `json:"id"`
+	PossibleDuplicates []DuplicateCandidate `json:"possibleDuplicates"`
+}
+
+type MergeIdeaPayload struct {
+	Into uuid.UUID `json:"into" validate:"required"`
 }
 
 // *********************
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RegistrationMode controls who may use POST /auth/register
+type RegistrationMode string
+
+const (
+	RegistrationOpen       RegistrationMode = "open"
+	RegistrationInviteOnly RegistrationMode = "invite-only"
+	RegistrationDomain     RegistrationMode = "domain-restricted"
+)
+
+// Invite is an admin-issued registration code. Only the hash of the code is
+// stored, CodeHint keeps the last characters so admins can tell codes apart.
+type Invite struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	CodeHash  string     `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`
+	CodeHint  string     `json:"codeHint" gorm:"type:varchar(8)"`
+	Note      string     `json:"note" gorm:"type:varchar(200)"`
+	MaxUses   int        `json:"maxUses" gorm:"not null;default:1"`
+	Uses      int        `json:"uses" gorm:"not null;default:0"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	CreatedBy uuid.UUID  `json:"createdBy" gorm:"type:uuid"`
+	CreatedAt time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// InviteRedemption records which account was created with which invite
+type InviteRedemption struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	InviteID   uuid.UUID `json:"inviteId" gorm:"type:uuid;not null;index"`
+	UserID     uuid.UUID `json:"userId" gorm:"type:uuid;not null"`
+	Username   string    `json:"username" gorm:"->;-:migration"`
+	RedeemedAt time.Time `json:"redeemedAt"`
+}
+
+type CreateInviteRequest struct {
+	MaxUses   int        `json:"maxUses" validate:"omitempty,min=1,max=10000"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Note      string     `json:"note" validate:"max=200"`
+}
+
+// CreateInviteResponse is the only place the plain invite code is returned
+type CreateInviteResponse struct {
+	Invite Invite `json:"invite"`
+	Code   string `json:"code"`
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LinkType tells what a link on an idea points at
+// @Description Type of the link
+type LinkType string
+
+const (
+	LinkRepository LinkType = "repository"
+	LinkBlogPost   LinkType = "blog-post"
+	LinkVideo      LinkType = "video"
+	LinkDemo       LinkType = "demo"
+	LinkDocs       LinkType = "docs"
+)
+
+// IdeaLink points at the result of an idea, like the repository or the
+// announcement. Links keep the order they were given in.
+type IdeaLink struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	IdeaID    uuid.UUID `json:"-" gorm:"type:uuid;not null;index"`
+	Type      LinkType  `json:"type" gorm:"type:varchar(20);not null"`
+	URL       string    `json:"url" gorm:"type:text;not null"`
+	Title     string    `json:"title,omitempty" gorm:"type:varchar(200)"`
+	Position  int       `json:"-" gorm:"not null;default:0"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+type IdeaLinkPayload struct {
+	Type  LinkType `json:"type" validate:"required,oneof=repository blog-post video demo docs"`
+	URL   string   `json:"url" validate:"required,max=2000,http_url"`
+	Title string   `json:"title" validate:"max=200"`
+}
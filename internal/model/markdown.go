@@ -0,0 +1,10 @@
+package model
+
+type MarkdownPreviewPayload struct {
+	Markdown string `json:"markdown" validate:"max=10000"`
+}
+
+// MarkdownPreview is markdown rendered the way idea descriptions are
+type MarkdownPreview struct {
+	HTML string `json:"html"`
+}
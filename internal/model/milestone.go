@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MilestoneState tells whether work towards a milestone is still going on
+type MilestoneState string
+
+const (
+	MilestoneOpen   MilestoneState = "open"
+	MilestoneClosed MilestoneState = "closed"
+)
+
+// MilestoneDateLayout is the format of target dates in payloads
+const MilestoneDateLayout = "2006-01-02"
+
+// Milestone is a target date on the roadmap that ideas are assigned to
+type Milestone struct {
+	ID          uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey"`
+	Name        string            `json:"name" gorm:"type:varchar(100);not null"`
+	Description string            `json:"description" gorm:"type:text"`
+	TargetDate  time.Time         `json:"targetDate" gorm:"type:date;not null;index"`
+	State       MilestoneState    `json:"state" gorm:"type:varchar(20);not null;default:'open';index"`
+	ClosedAt    *time.Time        `json:"closedAt,omitempty"`
+	Progress    MilestoneProgress `json:"progress" gorm:"-"`
+	CreatedAt   time.Time         `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time         `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// MilestoneProgress counts the ideas assigned to a milestone. Share is the
+// part of them that is published, between 0 and 1.
+type MilestoneProgress struct {
+	Ideas     int     `json:"ideas"`
+	Published int     `json:"published"`
+	Share     float64 `json:"share"`
+}
+
+type CreateMilestonePayload struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=2000"`
+	// YYYY-MM-DD
+	TargetDate string `json:"targetDate" validate:"required,datetime=2006-01-02"`
+}
+
+type UpdateMilestonePayload struct {
+	Name        *string         `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Description *string         `json:"description,omitempty" validate:"omitempty,max=2000"`
+	TargetDate  *string         `json:"targetDate,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	State       *MilestoneState `json:"state,omitempty" validate:"omitempty,oneof=open closed"`
+}
+
+// Calendar lists milestone dates for the iCalendar export
+type Calendar struct {
+	Name   string
+	Events []CalendarEvent
+}
+
+// CalendarEvent is an all-day event on the target date of a milestone. UID
+// stays the same for the milestone, calendars use it to update the event.
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Date        time.Time
+	Updated     time.Time
+}
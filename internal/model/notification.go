@@ -0,0 +1,85 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationType says why a user was notified
+type NotificationType string
+
+const (
+	NotificationStatusChanged NotificationType = "status_changed"
+	NotificationIdeaUpdated   NotificationType = "idea_updated"
+	NotificationComment       NotificationType = "comment"
+	NotificationReply         NotificationType = "reply"
+	NotificationMention       NotificationType = "mention"
+)
+
+// Notification is an entry in a user's inbox. From and To are only set for
+// status changes, CommentID for comments, replies and mentions.
+type Notification struct {
+	ID        uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID        `json:"-" gorm:"type:uuid;not null;index"`
+	Type      NotificationType `json:"type" gorm:"type:varchar(30);not null"`
+	IdeaID    uuid.UUID        `json:"ideaId" gorm:"type:uuid;not null;index"`
+	IdeaTitle string           `json:"ideaTitle"`
+	CommentID *uuid.UUID       `json:"commentId,omitempty" gorm:"type:uuid"`
+	ActorName string           `json:"actorName" gorm:"type:varchar(100)"`
+	From      RequestStatus    `json:"from,omitempty" gorm:"type:varchar(20)"`
+	To        RequestStatus    `json:"to,omitempty" gorm:"type:varchar(20)"`
+	ReadAt    *time.Time       `json:"readAt"`
+	CreatedAt time.Time        `json:"createdAt" gorm:"index"`
+}
+
+// NotificationPreferences decide which events end up in the inbox. Users
+// without saved preferences get everything.
+type NotificationPreferences struct {
+	UserID        uuid.UUID `json:"-" gorm:"type:uuid;primaryKey"`
+	StatusChanged bool      `json:"statusChanged" gorm:"not null"`
+	IdeaUpdated   bool      `json:"ideaUpdated" gorm:"not null"`
+	Comments      bool      `json:"comments" gorm:"not null"`
+	Replies       bool      `json:"replies" gorm:"not null"`
+	Mentions      bool      `json:"mentions" gorm:"not null"`
+}
+
+func DefaultNotificationPreferences(userID uuid.UUID) NotificationPreferences {
+	return NotificationPreferences{
+		UserID:        userID,
+		StatusChanged: true,
+		IdeaUpdated:   true,
+		Comments:      true,
+		Replies:       true,
+		Mentions:      true,
+	}
+}
+
+// Wants reports whether notifications of this type are enabled
+func (p NotificationPreferences) Wants(t NotificationType) bool {
+	switch t {
+	case NotificationStatusChanged:
+		return p.StatusChanged
+	case NotificationIdeaUpdated:
+		return p.IdeaUpdated
+	case NotificationComment:
+		return p.Comments
+	case NotificationReply:
+		return p.Replies
+	case NotificationMention:
+		return p.Mentions
+	}
+	return false
+}
+
+type UpdateNotificationPreferencesPayload struct {
+	StatusChanged *bool `json:"statusChanged,omitempty"`
+	IdeaUpdated   *bool `json:"ideaUpdated,omitempty"`
+	Comments      *bool `json:"comments,omitempty"`
+	Replies       *bool `json:"replies,omitempty"`
+	Mentions      *bool `json:"mentions,omitempty"`
+}
+
+type UnreadCount struct {
+	Unread int64 `json:"unread"`
+}
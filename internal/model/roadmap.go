@@ -0,0 +1,21 @@
+package model
+
+import "github.com/google/uuid"
+
+type RoadmapColumn struct {
+	Key      string          `json:"key"`
+	Name     string          `json:"name"`
+	Statuses []RequestStatus `json:"statuses"`
+	Count    int             `json:"count"`
+	Ideas    []Idea          `json:"ideas"`
+}
+
+type Roadmap struct {
+	Columns []RoadmapColumn `json:"columns"`
+}
+
+// RankRoadmapPayload lists ideas of one column in the order they should be
+// shown. Ideas of the column that are not listed follow after them.
+type RankRoadmapPayload struct {
+	IdeaIDs []uuid.UUID `json:"ideaIds" validate:"required,min=1"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is one issued login token. The ID is the token's jti claim, so
+// revoking the row makes the token unusable before it expires.
+type Session struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID     uuid.UUID  `json:"-" gorm:"type:uuid;not null;index"`
+	UserAgent  string     `json:"userAgent" gorm:"type:varchar(512)"`
+	IP         string     `json:"ip" gorm:"type:varchar(64)"`
+	CreatedAt  time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	Current    bool       `json:"current" gorm:"-"`
+}
+
+// ClientInfo describes where a login came from
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
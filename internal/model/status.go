@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HistoryKind tells what changed in an entry of an idea's history
+type HistoryKind string
+
+const (
+	HistoryStatus     HistoryKind = "status"
+	HistoryAssigned   HistoryKind = "assigned"
+	HistoryUnassigned HistoryKind = "unassigned"
+)
+
+// StatusTransition records one change in the history of an idea, a status
+// change or a change of its assignees. ActorName and Assignee are kept so the
+// history still reads well after the accounts are deleted.
+type StatusTransition struct {
+	ID     uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey"`
+	IdeaID uuid.UUID   `json:"ideaId" gorm:"type:uuid;not null;index"`
+	Kind   HistoryKind `json:"kind" gorm:"type:varchar(20);not null;default:'status'"`
+	// Set for status changes
+	From   RequestStatus `json:"from,omitempty" gorm:"type:varchar(20);not null"`
+	To     RequestStatus `json:"to,omitempty" gorm:"type:varchar(20);not null"`
+	Reason string        `json:"reason,omitempty" gorm:"type:text"`
+	// Set for assignment changes
+	AssigneeID *uuid.UUID `json:"assigneeId,omitempty" gorm:"type:uuid"`
+	Assignee   string     `json:"assignee,omitempty" gorm:"type:varchar(100)"`
+	ActorID    *uuid.UUID `json:"actorId,omitempty" gorm:"type:uuid"`
+	ActorName  string     `json:"actorName" gorm:"type:varchar(100)"`
+	CreatedAt  time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
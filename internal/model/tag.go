@@ -0,0 +1,60 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tag is a normalized tag, see NormalizeTag. Ideas are linked to tags through
+// IdeaTag.
+type Tag struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	Name      string    `json:"name" gorm:"type:varchar(50);not null;uniqueIndex"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+type IdeaTag struct {
+	IdeaID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TagID  uuid.UUID `gorm:"type:uuid;primaryKey;index"`
+}
+
+// TagUsage is a tag with the number of ideas using it
+type TagUsage struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type RenameTagPayload struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+type MergeTagsPayload struct {
+	Sources []string `json:"sources" validate:"required,min=1,dive,required"`
+	Target  string   `json:"target" validate:"required,max=50"`
+}
+
+// NormalizeTag lowercases a tag and joins words with dashes, so "CLI",
+// " cli " and "Command Line" become "cli" and "command-line"
+func NormalizeTag(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == ' ' || r == '_' || r == '-' || r == '\t'
+	})
+	return strings.Join(fields, "-")
+}
+
+// NormalizeTags normalizes every tag and drops empty and repeated ones
+func NormalizeTags(names []string) []string {
+	tags := []string{}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		tag := NormalizeTag(name)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Technology is an entry of the tech-stack catalog. Ideas reference it by
+// name, aliases such as "golang" resolve to the name. Deprecated entries stay
+// on existing ideas but cannot be added to ideas anymore.
+type Technology struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	Name       string    `json:"name" gorm:"type:varchar(50);not null;uniqueIndex"`
+	Aliases    []string  `json:"aliases" gorm:"type:jsonb;serializer:json"`
+	Icon       string    `json:"icon,omitempty" gorm:"type:varchar(255)"`
+	Color      string    `json:"color,omitempty" gorm:"type:varchar(7)"`
+	Deprecated bool      `json:"deprecated" gorm:"not null;default:false"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+type CreateTechnologyPayload struct {
+	Name    string   `json:"name" validate:"required,max=50"`
+	Aliases []string `json:"aliases,omitempty" validate:"omitempty,dive,required,max=50"`
+	Icon    string   `json:"icon,omitempty" validate:"omitempty,url,max=255"`
+	Color   string   `json:"color,omitempty" validate:"omitempty,hexcolor,max=7"`
+}
+
+type UpdateTechnologyPayload struct {
+	Name       *string   `json:"name,omitempty" validate:"omitempty,required,max=50"`
+	Aliases    *[]string `json:"aliases,omitempty" validate:"omitempty,dive,required,max=50"`
+	Icon       *string   `json:"icon,omitempty" validate:"omitempty,max=255"`
+	Color      *string   `json:"color,omitempty" validate:"omitempty,max=7"`
+	Deprecated *bool     `json:"deprecated,omitempty"`
+}
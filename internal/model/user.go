@@ -6,20 +6,102 @@ import (
 	"github.com/google/uuid"
 )
 
+// Role represents what a user is allowed to do
+// @Description Role of the user
+type Role string
+
+const (
+	RoleUser       Role = "user"
+	RoleModerator  Role = "moderator"
+	RoleMaintainer Role = "maintainer"
+	RoleAdmin      Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleUser:       0,
+	RoleModerator:  1,
+	RoleMaintainer: 2,
+	RoleAdmin:      3,
+}
+
+// AtLeast reports whether r grants everything min does. Unknown roles grant nothing.
+func (r Role) AtLeast(min Role) bool {
+	rank, ok := roleRank[r]
+	return ok && rank >= roleRank[min]
+}
+
 type User struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	Username     string    `json:"username" gorm:"unique;not null"`
+	Password     string    `json:"-" gorm:"not null"`
+	Email        string    `json:"email" gorm:"unique;not null"`
+	DisplayName  string    `json:"displayName" gorm:"type:varchar(100)"`
+	IsAdmin      bool      `json:"isAdmin" gorm:"not null;default:false"`
+	Role         Role      `json:"role" gorm:"type:varchar(20);not null;default:'user'"`
+	TOTPSecret   string    `json:"-" gorm:"type:varchar(64)"`
+	TOTPEnabled  bool      `json:"totpEnabled" gorm:"not null;default:false"`
+	TOTPLastStep int64     `json:"-" gorm:"not null;default:0"`
+	// Wrong 2FA codes in a row. Reaching the limit locks the account's 2FA until
+	// TOTPLockedUntil and starts the count over.
+	TOTPFailures    int        `json:"-" gorm:"not null;default:0"`
+	TOTPLockedUntil *time.Time `json:"-"`
+	// Follow ideas automatically when creating or voting for them
+	AutoFollowCreated bool      `json:"autoFollowCreated" gorm:"not null;default:true"`
+	AutoFollowVoted   bool      `json:"autoFollowVoted" gorm:"not null;default:true"`
+	CreatedAt         time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// DeletionPolicy decides what happens to a user's ideas, votes and comments
+// when the account is deleted
+type DeletionPolicy string
+
+const (
+	// DeletionAnonymize keeps ideas and comments but detaches them from the
+	// user, votes are removed
+	DeletionAnonymize DeletionPolicy = "anonymize"
+	// DeletionReassign hands ideas, votes and comments over to another account
+	DeletionReassign DeletionPolicy = "reassign"
+	// DeletionCascade removes the user's ideas, votes and comments as well
+	DeletionCascade DeletionPolicy = "cascade"
+)
+
+// EffectiveRole folds the legacy IsAdmin flag into the role
+func (u User) EffectiveRole() Role {
+	if u.IsAdmin {
+		return RoleAdmin
+	}
+	if u.Role == "" {
+		return RoleUser
+	}
+	return u.Role
+}
+
+// RecoveryCode is a one-time code that can stand in for a TOTP code.
+// Only the sha256 of the code is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID  `json:"-" gorm:"type:uuid;not null;index"`
+	CodeHash  string     `json:"-" gorm:"type:varchar(64);not null;index"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// UserIdentity links a user to an account at an external OpenID Connect provider
+type UserIdentity struct {
 	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
-	Username  string    `json:"username" gorm:"unique;not null"`
-	Password  string    `json:"-" gorm:"not null"`
-	Email     string    `json:"email" gorm:"unique;not null"`
-	IsAdmin   bool      `json:"isAdmin" gorm:"not null;default:false"`
+	UserID    uuid.UUID `json:"-" gorm:"type:uuid;not null;index"`
+	Issuer    string    `json:"issuer" gorm:"not null;uniqueIndex:idx_identity_issuer_subject"`
+	Subject   string    `json:"subject" gorm:"not null;uniqueIndex:idx_identity_issuer_subject"`
+	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
 }
 
 type RegisterRequest struct {
-	Username string `json:"username" validate:"required,min=3,max=50"`
-	Password string `json:"password" validate:"required,min=6"`
-	Email    string `json:"email" validate:"required,email"`
+	Username   string `json:"username" validate:"required,min=3,max=50"`
+	Password   string `json:"password" validate:"required"`
+	Email      string `json:"email" validate:"required,email"`
+	InviteCode string `json:"inviteCode,omitempty"`
 }
 
 type LoginRequest struct {
@@ -27,11 +109,66 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// LoginResponse carries either a session token or, when the account has 2FA
+// enabled, a short-lived challenge token to exchange at /auth/login/totp.
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token             string `json:"token,omitempty"`
+	ChallengeToken    string `json:"challengeToken,omitempty"`
+	TOTPRequired      bool   `json:"totpRequired,omitempty"`
+	TOTPSetupRequired bool   `json:"totpSetupRequired,omitempty"`
 }
 
 type DeleteRequest struct {
 	Username string `json:"username" validate:"required"`
 	Password string `json:"password" validate:"required"`
 }
+
+// *********************
+//  account management
+// *********************
+
+type UpdateProfileRequest struct {
+	Email             *string `json:"email,omitempty" validate:"omitempty,email"`
+	DisplayName       *string `json:"displayName,omitempty" validate:"omitempty,max=100"`
+	AutoFollowCreated *bool   `json:"autoFollowCreated,omitempty"`
+	AutoFollowVoted   *bool   `json:"autoFollowVoted,omitempty"`
+	// Required to change the email
+	CurrentPassword string `json:"currentPassword,omitempty"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// *********************
+//    2FA related
+// *********************
+
+type TOTPLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recoveryCode" validate:"required_without=Code"`
+}
+
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TOTPConfirmRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type TOTPConfirmResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type TOTPDisableRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
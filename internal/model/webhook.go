@@ -0,0 +1,110 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEvent is something webhooks can subscribe to
+type WebhookEvent string
+
+const (
+	WebhookIdeaCreated       WebhookEvent = "idea.created"
+	WebhookIdeaStatusChanged WebhookEvent = "idea.status_changed"
+	WebhookIdeaDeleted       WebhookEvent = "idea.deleted"
+	WebhookVoteAdded         WebhookEvent = "vote.added"
+	WebhookVoteRemoved       WebhookEvent = "vote.removed"
+)
+
+// Webhook is an admin-managed subscription. Payloads are signed with Secret,
+// which is only returned when the webhook is created. A webhook that keeps
+// failing is disabled, DisabledAt tells when.
+type Webhook struct {
+	ID                  uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey"`
+	URL                 string         `json:"url" gorm:"not null"`
+	Secret              string         `json:"-" gorm:"not null"`
+	Events              []WebhookEvent `json:"events" gorm:"type:jsonb;serializer:json;not null"`
+	Description         string         `json:"description" gorm:"type:varchar(200)"`
+	Active              bool           `json:"active" gorm:"not null;index"`
+	ConsecutiveFailures int            `json:"consecutiveFailures" gorm:"not null;default:0"`
+	DisabledAt          *time.Time     `json:"disabledAt,omitempty"`
+	CreatedAt           time.Time      `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt           time.Time      `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+type CreateWebhookPayload struct {
+	URL         string         `json:"url" validate:"required,url"`
+	Events      []WebhookEvent `json:"events" validate:"required,min=1,dive,oneof=idea.created idea.status_changed idea.deleted vote.added vote.removed"`
+	Description string         `json:"description" validate:"max=200"`
+}
+
+// UpdateWebhookPayload changes the given fields. Setting active to true
+// enables a disabled webhook again.
+type UpdateWebhookPayload struct {
+	URL         *string         `json:"url,omitempty" validate:"omitempty,url"`
+	Events      *[]WebhookEvent `json:"events,omitempty" validate:"omitempty,min=1,dive,oneof=idea.created idea.status_changed idea.deleted vote.added vote.removed"`
+	Description *string         `json:"description,omitempty" validate:"omitempty,max=200"`
+	Active      *bool           `json:"active,omitempty"`
+}
+
+// CreateWebhookResponse is the only place the signing secret is returned
+type CreateWebhookResponse struct {
+	Webhook Webhook `json:"webhook"`
+	Secret  string  `json:"secret"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event sent to one webhook. It is retried while
+// pending, ResponseCode and Error belong to the last attempt.
+type WebhookDelivery struct {
+	ID            uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey"`
+	WebhookID     uuid.UUID      `json:"webhookId" gorm:"type:uuid;not null;index"`
+	Event         WebhookEvent   `json:"event" gorm:"type:varchar(50);not null"`
+	Payload       string         `json:"payload" gorm:"type:text;not null"`
+	Status        DeliveryStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	Attempts      int            `json:"attempts" gorm:"not null;default:0"`
+	ResponseCode  int            `json:"responseCode,omitempty"`
+	Error         string         `json:"error,omitempty"`
+	NextAttemptAt *time.Time     `json:"nextAttemptAt,omitempty" gorm:"index"`
+	// Set for manual redeliveries
+	RedeliveryOf *uuid.UUID `json:"redeliveryOf,omitempty" gorm:"type:uuid"`
+	DeliveredAt  *time.Time `json:"deliveredAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt" gorm:"index"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// WebhookPayload is the body posted to webhooks
+type WebhookPayload struct {
+	ID        uuid.UUID       `json:"id"`
+	Event     WebhookEvent    `json:"event"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// StatusChangedData is the data of idea.status_changed
+type StatusChangedData struct {
+	Idea Idea          `json:"idea"`
+	From RequestStatus `json:"from"`
+	To   RequestStatus `json:"to"`
+}
+
+// IdeaDeletedData is the data of idea.deleted
+type IdeaDeletedData struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// VoteData is the data of vote.added and vote.removed
+type VoteData struct {
+	IdeaID    uuid.UUID `json:"ideaId"`
+	UserID    uuid.UUID `json:"userId"`
+	VoteCount int       `json:"voteCount"`
+}
@@ -4,30 +4,150 @@ import (
 	"net/http"
 	"test_project/test/internal/handler"
 	"test_project/test/internal/middleware"
+	"test_project/test/internal/model"
 )
 
-func SetupRoutes(ideaHandler *handler.IdeaHandler, authHandler *handler.AuthHandler, voteHandler *handler.VoteHandler) *http.ServeMux {
+func SetupRoutes(ideaHandler *handler.IdeaHandler, authHandler *handler.AuthHandler, voteHandler *handler.VoteHandler, oidcHandler *handler.OIDCHandler, sessionHandler *handler.SessionHandler, inviteHandler *handler.InviteHandler, commentHandler *handler.CommentHandler, roadmapHandler *handler.RoadmapHandler, techStackHandler *handler.TechStackHandler, tagHandler *handler.TagHandler, followHandler *handler.FollowHandler, notificationHandler *handler.NotificationHandler, webhookHandler *handler.WebhookHandler, feedHandler *handler.FeedHandler, attachmentHandler *handler.AttachmentHandler, markdownHandler *handler.MarkdownHandler, boardHandler *handler.BoardHandler, milestoneHandler *handler.MilestoneHandler, assigneeHandler *handler.AssigneeHandler) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	// Auth
 	mux.HandleFunc("POST /auth/login", authHandler.Login)
+	mux.HandleFunc("POST /auth/login/totp", authHandler.LoginTOTP)
 	mux.HandleFunc("POST /auth/register", authHandler.Register)
 	mux.HandleFunc("POST /auth/user", authHandler.DeleteUser)
 	mux.HandleFunc("GET /auth/users", authHandler.GetAllUsers)
 	mux.HandleFunc("GET /auth/user/{username}", authHandler.GetUserByUsername)
 
+	// Account
+	mux.Handle("GET /auth/me", middleware.Auth(http.HandlerFunc(authHandler.GetMe)))
+	mux.Handle("PATCH /auth/me", middleware.Auth(http.HandlerFunc(authHandler.UpdateMe)))
+	mux.Handle("DELETE /auth/me", middleware.Auth(http.HandlerFunc(authHandler.DeleteMe)))
+	mux.Handle("POST /auth/me/password", middleware.Auth(http.HandlerFunc(authHandler.ChangePassword)))
+
+	// Sessions
+	mux.Handle("GET /auth/sessions", middleware.Auth(http.HandlerFunc(sessionHandler.GetSessions)))
+	mux.Handle("DELETE /auth/sessions", middleware.Auth(http.HandlerFunc(sessionHandler.RevokeOtherSessions)))
+	mux.Handle("DELETE /auth/sessions/{id}", middleware.Auth(http.HandlerFunc(sessionHandler.RevokeSession)))
+	mux.Handle("DELETE /auth/users/{username}/sessions", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(sessionHandler.ForceLogout)))
+
+	// Invites
+	mux.Handle("POST /auth/invites", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(inviteHandler.CreateInvite)))
+	mux.Handle("GET /auth/invites", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(inviteHandler.GetInvites)))
+	mux.Handle("DELETE /auth/invites/{id}", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(inviteHandler.RevokeInvite)))
+	mux.Handle("GET /auth/invites/{id}/redemptions", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(inviteHandler.GetRedemptions)))
+
+	// Single sign-on
+	mux.HandleFunc("GET /auth/oidc/login", oidcHandler.Login)
+	mux.HandleFunc("GET /auth/oidc/callback", oidcHandler.Callback)
+	mux.Handle("GET /auth/oidc/link", middleware.Auth(http.HandlerFunc(oidcHandler.Link)))
+
+	// Two-factor
+	mux.Handle("POST /auth/totp/enroll", middleware.AuthEnrollment(http.HandlerFunc(authHandler.EnrollTOTP)))
+	mux.Handle("POST /auth/totp/confirm", middleware.AuthEnrollment(http.HandlerFunc(authHandler.ConfirmTOTP)))
+	mux.Handle("POST /auth/totp/recovery-codes", middleware.Auth(http.HandlerFunc(authHandler.RegenerateRecoveryCodes)))
+	mux.Handle("DELETE /auth/totp", middleware.Auth(http.HandlerFunc(authHandler.DisableTOTP)))
+
 	// Idea
 	mux.Handle("POST /idea", middleware.Auth(http.HandlerFunc(ideaHandler.CreateIdea)))
-	mux.Handle("GET /idea/{id}", http.HandlerFunc(ideaHandler.GetIdea))
-	mux.Handle("GET /ideas", http.HandlerFunc(ideaHandler.GetAllIdeas))
+	mux.Handle("GET /idea/{id}", middleware.OptionalAuth(http.HandlerFunc(ideaHandler.GetIdea)))
+	mux.Handle("GET /ideas", middleware.OptionalAuth(http.HandlerFunc(ideaHandler.GetAllIdeas)))
 	mux.Handle("POST /idea/{id}", middleware.Auth(http.HandlerFunc(ideaHandler.UpdateIdea)))
 	mux.Handle("DELETE /idea/{id}", middleware.Auth(http.HandlerFunc(ideaHandler.DeleteIdea)))
+	mux.Handle("POST /idea/{id}/publish", middleware.Auth(http.HandlerFunc(ideaHandler.PublishIdea)))
+	mux.Handle("GET /idea/{id}/status-history", middleware.OptionalAuth(http.HandlerFunc(ideaHandler.GetStatusHistory)))
+	mux.Handle("POST /idea/{id}/merge", middleware.RequireRole(model.RoleModerator, http.HandlerFunc(ideaHandler.MergeIdea)))
+
+	// Boards
+	mux.Handle("GET /boards", http.HandlerFunc(boardHandler.GetBoards))
+	mux.Handle("POST /boards", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(boardHandler.CreateBoard)))
+	mux.Handle("GET /boards/{slug}", http.HandlerFunc(boardHandler.GetBoard))
+	mux.Handle("PATCH /boards/{slug}", middleware.Auth(http.HandlerFunc(boardHandler.UpdateBoard)))
+	mux.Handle("DELETE /boards/{slug}", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(boardHandler.DeleteBoard)))
+	mux.Handle("GET /boards/{slug}/members", middleware.Auth(http.HandlerFunc(boardHandler.GetMembers)))
+	mux.Handle("PUT /boards/{slug}/members/{username}", middleware.Auth(http.HandlerFunc(boardHandler.SetMember)))
+	mux.Handle("DELETE /boards/{slug}/members/{username}", middleware.Auth(http.HandlerFunc(boardHandler.RemoveMember)))
+	mux.Handle("GET /boards/{slug}/ideas", middleware.OptionalAuth(http.HandlerFunc(ideaHandler.GetBoardIdeas)))
+	mux.Handle("POST /boards/{slug}/ideas", middleware.Auth(http.HandlerFunc(ideaHandler.CreateBoardIdea)))
+	mux.Handle("GET /boards/{slug}/ideas/{id}", middleware.OptionalAuth(http.HandlerFunc(ideaHandler.GetBoardIdea)))
+
+	// Tech-stack catalog
+	mux.Handle("GET /tech-stacks", http.HandlerFunc(techStackHandler.GetTechnologies))
+	mux.Handle("POST /tech-stacks", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(techStackHandler.CreateTechnology)))
+	mux.Handle("PATCH /tech-stacks/{id}", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(techStackHandler.UpdateTechnology)))
+	mux.Handle("DELETE /tech-stacks/{id}", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(techStackHandler.DeleteTechnology)))
+
+	// Tags
+	mux.Handle("GET /tags", http.HandlerFunc(tagHandler.AutocompleteTags))
+	mux.Handle("POST /tags/merge", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(tagHandler.MergeTags)))
+	mux.Handle("PATCH /tags/{name}", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(tagHandler.RenameTag)))
 
 	// Voting
 	mux.Handle("POST /idea/{id}/vote", middleware.Auth(http.HandlerFunc(voteHandler.AddVote)))
 	mux.Handle("DELETE /idea/{id}/vote", middleware.Auth(http.HandlerFunc(voteHandler.RemoveVote)))
 	mux.Handle("GET /idea/{id}/vote/status", middleware.Auth(http.HandlerFunc(voteHandler.HasUserVoted)))
-	mux.Handle("GET /idea/{id}/votes", http.HandlerFunc(voteHandler.GetVoteCount))
+	mux.Handle("GET /idea/{id}/votes", middleware.OptionalAuth(http.HandlerFunc(voteHandler.GetVoteCount)))
+
+	// Follows
+	mux.Handle("PUT /idea/{id}/follow", middleware.Auth(http.HandlerFunc(followHandler.FollowIdea)))
+	mux.Handle("DELETE /idea/{id}/follow", middleware.Auth(http.HandlerFunc(followHandler.UnfollowIdea)))
+	mux.Handle("GET /me/following", middleware.Auth(http.HandlerFunc(followHandler.GetFollowing)))
+
+	// Assignees
+	mux.Handle("PUT /idea/{id}/assignees/{username}", middleware.RequireRole(model.RoleMaintainer, http.HandlerFunc(assigneeHandler.AssignIdea)))
+	mux.Handle("DELETE /idea/{id}/assignees/{username}", middleware.RequireRole(model.RoleMaintainer, http.HandlerFunc(assigneeHandler.UnassignIdea)))
+	mux.Handle("GET /me/assigned", middleware.Auth(http.HandlerFunc(assigneeHandler.GetAssigned)))
+
+	// Notifications
+	mux.Handle("GET /me/notifications", middleware.Auth(http.HandlerFunc(notificationHandler.GetNotifications)))
+	mux.Handle("GET /me/notifications/unread-count", middleware.Auth(http.HandlerFunc(notificationHandler.GetUnreadCount)))
+	mux.Handle("POST /me/notifications/read", middleware.Auth(http.HandlerFunc(notificationHandler.MarkAllRead)))
+	mux.Handle("POST /me/notifications/{id}/read", middleware.Auth(http.HandlerFunc(notificationHandler.MarkRead)))
+	mux.Handle("GET /me/notifications/preferences", middleware.Auth(http.HandlerFunc(notificationHandler.GetPreferences)))
+	mux.Handle("PATCH /me/notifications/preferences", middleware.Auth(http.HandlerFunc(notificationHandler.UpdatePreferences)))
+
+	// Comments
+	mux.Handle("GET /idea/{id}/comments", middleware.OptionalAuth(http.HandlerFunc(commentHandler.GetComments)))
+	mux.Handle("POST /idea/{id}/comments", middleware.Auth(http.HandlerFunc(commentHandler.CreateComment)))
+	mux.Handle("PATCH /idea/{id}/comments/{commentId}", middleware.Auth(http.HandlerFunc(commentHandler.UpdateComment)))
+	mux.Handle("DELETE /idea/{id}/comments/{commentId}", middleware.Auth(http.HandlerFunc(commentHandler.DeleteComment)))
+
+	// Attachments
+	mux.Handle("GET /idea/{id}/attachments", middleware.OptionalAuth(http.HandlerFunc(attachmentHandler.GetAttachments)))
+	mux.Handle("POST /idea/{id}/attachments", middleware.Auth(http.HandlerFunc(attachmentHandler.UploadAttachment)))
+	mux.Handle("GET /idea/{id}/attachments/{attachmentId}", middleware.OptionalAuth(http.HandlerFunc(attachmentHandler.DownloadAttachment)))
+	mux.Handle("DELETE /idea/{id}/attachments/{attachmentId}", middleware.Auth(http.HandlerFunc(attachmentHandler.DeleteAttachment)))
+
+	// Webhooks
+	mux.Handle("GET /webhooks", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(webhookHandler.GetWebhooks)))
+	mux.Handle("POST /webhooks", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(webhookHandler.CreateWebhook)))
+	mux.Handle("GET /webhooks/{id}", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(webhookHandler.GetWebhook)))
+	mux.Handle("PATCH /webhooks/{id}", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(webhookHandler.UpdateWebhook)))
+	mux.Handle("DELETE /webhooks/{id}", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(webhookHandler.DeleteWebhook)))
+	mux.Handle("GET /webhooks/{id}/deliveries", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(webhookHandler.GetDeliveries)))
+	mux.Handle("POST /webhooks/{id}/deliveries/{deliveryId}/redeliver", middleware.RequireRole(model.RoleAdmin, http.HandlerFunc(webhookHandler.Redeliver)))
+
+	// Roadmap
+	mux.Handle("GET /roadmap", http.HandlerFunc(roadmapHandler.GetRoadmap))
+	mux.Handle("PUT /roadmap/{column}/order", middleware.RequireRole(model.RoleMaintainer, http.HandlerFunc(roadmapHandler.RankColumn)))
+
+	// Milestones
+	mux.Handle("GET /milestones", middleware.OptionalAuth(http.HandlerFunc(milestoneHandler.GetMilestones)))
+	mux.Handle("POST /milestones", middleware.RequireRole(model.RoleMaintainer, http.HandlerFunc(milestoneHandler.CreateMilestone)))
+	mux.Handle("GET /milestones.ics", http.HandlerFunc(milestoneHandler.GetCalendar))
+	mux.Handle("GET /milestones/{id}", middleware.OptionalAuth(http.HandlerFunc(milestoneHandler.GetMilestone)))
+	mux.Handle("PATCH /milestones/{id}", middleware.RequireRole(model.RoleMaintainer, http.HandlerFunc(milestoneHandler.UpdateMilestone)))
+	mux.Handle("DELETE /milestones/{id}", middleware.RequireRole(model.RoleMaintainer, http.HandlerFunc(milestoneHandler.DeleteMilestone)))
+	mux.Handle("GET /milestones/{id}/ideas", middleware.OptionalAuth(http.HandlerFunc(milestoneHandler.GetMilestoneIdeas)))
+	mux.Handle("PUT /milestones/{id}/ideas/{ideaId}", middleware.RequireRole(model.RoleMaintainer, http.HandlerFunc(milestoneHandler.AssignIdea)))
+	mux.Handle("DELETE /milestones/{id}/ideas/{ideaId}", middleware.RequireRole(model.RoleMaintainer, http.HandlerFunc(milestoneHandler.UnassignIdea)))
+
+	// Feeds
+	mux.Handle("GET /feeds/{file}", http.HandlerFunc(feedHandler.GetIdeasFeed))
+	mux.Handle("GET /feeds/status/{status}/{file}", http.HandlerFunc(feedHandler.GetStatusFeed))
+	mux.Handle("GET /feeds/tags/{tag}/{file}", http.HandlerFunc(feedHandler.GetTagFeed))
+
+	// Markdown
+	mux.Handle("POST /markdown/preview", middleware.Auth(http.HandlerFunc(markdownHandler.PreviewMarkdown)))
 
 	return mux
 }
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidAssignee = errors.New("only maintainers can be assigned to ideas")
+
+// AssigneeService keeps track of the maintainers working on an idea. Every
+// change is recorded in the idea's history.
+type AssigneeService struct {
+	store storage.AssigneeStorage
+	ideas storage.IdeaStorage
+	users storage.UserStorage
+}
+
+func NewAssigneeService(store storage.AssigneeStorage, ideas storage.IdeaStorage, users storage.UserStorage) *AssigneeService {
+	return &AssigneeService{store, ideas, users}
+}
+
+// Assign adds the maintainer to the idea's assignees, assigning them twice
+// changes nothing
+func (s *AssigneeService) Assign(ideaID uuid.UUID, username string, actor model.Actor) error {
+	user, err := s.getAssignee(ideaID, username)
+	if err != nil {
+		return err
+	}
+	if !user.Role.AtLeast(model.RoleMaintainer) {
+		return fmt.Errorf("%w: %s is not a maintainer", ErrInvalidAssignee, user.Username)
+	}
+
+	return s.store.AssignIdea(ideaID, user.ID, assignmentEntry(ideaID, model.HistoryAssigned, user, actor))
+}
+
+// Unassign removes the user from the idea's assignees. It also works for
+// users who are no longer maintainers.
+func (s *AssigneeService) Unassign(ideaID uuid.UUID, username string, actor model.Actor) error {
+	user, err := s.getAssignee(ideaID, username)
+	if err != nil {
+		return err
+	}
+
+	return s.store.UnassignIdea(ideaID, user.ID, assignmentEntry(ideaID, model.HistoryUnassigned, user, actor))
+}
+
+// GetAssigned returns the ideas the actor is assigned to that they can see
+func (s *AssigneeService) GetAssigned(actor model.Actor) utils.Result[[]model.Idea] {
+	result := s.store.GetAssignedIdeas(actor.ID)
+	if result.Err != nil {
+		return result
+	}
+
+	ideas := []model.Idea{}
+	for _, idea := range result.Data {
+		if idea.VisibleTo(actor) {
+			ideas = append(ideas, idea)
+		}
+	}
+
+	return utils.Result[[]model.Idea]{Data: ideas}
+}
+
+// getAssignee makes sure the idea and the user exist
+func (s *AssigneeService) getAssignee(ideaID uuid.UUID, username string) (model.User, error) {
+	idea := s.ideas.GetIdea(ideaID)
+	if idea.Err != nil || idea.Data.ID == uuid.Nil {
+		return model.User{}, fmt.Errorf("%w: %s", ErrIdeaNotFound, ideaID)
+	}
+	if idea.Data.MergedInto != nil {
+		return model.User{}, fmt.Errorf("%w: %s", ErrIdeaMerged, *idea.Data.MergedInto)
+	}
+
+	user, err := s.users.GetUserByUsername(username)
+	if err != nil || user.ID == uuid.Nil {
+		return model.User{}, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+	}
+
+	return user, nil
+}
+
+func assignmentEntry(ideaID uuid.UUID, kind model.HistoryKind, assignee model.User, actor model.Actor) model.StatusTransition {
+	return model.StatusTransition{
+		ID:         uuid.New(),
+		IdeaID:     ideaID,
+		Kind:       kind,
+		AssigneeID: &assignee.ID,
+		Assignee:   assignee.Username,
+		ActorID:    &actor.ID,
+		ActorName:  actor.Username,
+		CreatedAt:  time.Now(),
+	}
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"test_project/test/internal/config"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+	"time"
+	"unicode"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/google/uuid"
+)
+
+// Bytes read from the start of an upload to sniff its content type
+const sniffLength = 3072
+
+var (
+	ErrAttachmentNotFound  = storage.ErrAttachmentNotFound
+	ErrAttachmentForbidden = errors.New("only the uploader or a moderator can delete this attachment")
+	ErrAttachmentTooLarge  = errors.New("attachment is too large")
+	ErrAttachmentType      = errors.New("attachment type is not allowed")
+	ErrTooManyAttachments  = errors.New("idea has too many attachments")
+	ErrEmptyAttachment     = errors.New("attachment is empty")
+)
+
+type AttachmentService struct {
+	store storage.AttachmentStorage
+	ideas storage.IdeaStorage
+	blobs storage.BlobStore
+	cfg   config.AttachmentConfig
+}
+
+func NewAttachmentService(store storage.AttachmentStorage, ideas storage.IdeaStorage, blobs storage.BlobStore, cfg config.AttachmentConfig) *AttachmentService {
+	return &AttachmentService{store, ideas, blobs, cfg}
+}
+
+// MaxSize is the largest attachment accepted, in bytes
+func (s *AttachmentService) MaxSize() int64 {
+	return s.cfg.MaxSize
+}
+
+func (s *AttachmentService) GetAttachments(ideaID uuid.UUID, viewer model.Actor) utils.Result[[]model.Attachment] {
+	if err := s.checkIdea(ideaID, viewer); err != nil {
+		return utils.Result[[]model.Attachment]{Err: err}
+	}
+	return s.store.GetAttachments(ideaID)
+}
+
+// Upload stores the content as an attachment of the idea. The content type
+// is sniffed from the first bytes and has to be on the allowlist, whatever
+// the client claims it is.
+func (s *AttachmentService) Upload(ideaID uuid.UUID, actor model.Actor, filename string, content io.Reader) utils.Result[model.Attachment] {
+	if err := s.checkIdea(ideaID, actor); err != nil {
+		return utils.Result[model.Attachment]{Err: err}
+	}
+
+	count, err := s.store.CountAttachments(ideaID)
+	if err != nil {
+		return utils.Result[model.Attachment]{Err: err}
+	}
+	if count >= int64(s.cfg.MaxPerIdea) {
+		return utils.Result[model.Attachment]{Err: fmt.Errorf("%w: at most %d", ErrTooManyAttachments, s.cfg.MaxPerIdea)}
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return utils.Result[model.Attachment]{Err: fmt.Errorf("failed to read upload: %w", err)}
+	}
+	head = head[:n]
+	if n == 0 {
+		return utils.Result[model.Attachment]{Err: ErrEmptyAttachment}
+	}
+
+	mtype := mimetype.Detect(head)
+	if !s.allowed(mtype) {
+		return utils.Result[model.Attachment]{Err: fmt.Errorf("%w: %s", ErrAttachmentType, mtype.String())}
+	}
+
+	// One byte more than allowed is read, so an oversized upload is noticed
+	// without reading all of it
+	hash := sha256.New()
+	body := io.TeeReader(io.LimitReader(io.MultiReader(bytes.NewReader(head), content), s.cfg.MaxSize+1), hash)
+
+	id := uuid.New()
+	key := id.String()
+	size, err := s.blobs.Put(key, body)
+	if err != nil {
+		s.deleteBlob(key)
+		return utils.Result[model.Attachment]{Err: err}
+	}
+	if size > s.cfg.MaxSize {
+		s.deleteBlob(key)
+		return utils.Result[model.Attachment]{Err: fmt.Errorf("%w: at most %d bytes", ErrAttachmentTooLarge, s.cfg.MaxSize)}
+	}
+
+	attachment := model.Attachment{
+		ID:           id,
+		IdeaID:       ideaID,
+		UploaderID:   &actor.ID,
+		UploaderName: actor.Username,
+		Filename:     cleanFilename(filename, mtype.Extension()),
+		ContentType:  mtype.String(),
+		Size:         size,
+		SHA256:       hex.EncodeToString(hash.Sum(nil)),
+		BlobKey:      key,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.store.CreateAttachment(attachment); err != nil {
+		s.deleteBlob(key)
+		return utils.Result[model.Attachment]{Err: err}
+	}
+
+	return utils.Result[model.Attachment]{Data: attachment}
+}
+
+// Open returns the attachment with its content, the caller closes it
+func (s *AttachmentService) Open(ideaID, id uuid.UUID, viewer model.Actor) (model.Attachment, io.ReadSeekCloser, error) {
+	attachment, err := s.getAttachment(ideaID, id, viewer)
+	if err != nil {
+		return model.Attachment{}, nil, err
+	}
+
+	content, err := s.blobs.Open(attachment.BlobKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			return model.Attachment{}, nil, fmt.Errorf("%w: content of %s is missing", ErrAttachmentNotFound, id)
+		}
+		return model.Attachment{}, nil, err
+	}
+
+	return attachment, content, nil
+}
+
+// Delete removes the attachment, the uploader and moderators may do so
+func (s *AttachmentService) Delete(ideaID, id uuid.UUID, actor model.Actor) error {
+	attachment, err := s.getAttachment(ideaID, id, actor)
+	if err != nil {
+		return err
+	}
+
+	var uploaderID uuid.UUID
+	if attachment.UploaderID != nil {
+		uploaderID = *attachment.UploaderID
+	}
+	if !actor.CanModerate(uploaderID) {
+		return ErrAttachmentForbidden
+	}
+
+	if err := s.store.DeleteAttachment(id); err != nil {
+		return err
+	}
+	s.deleteBlob(attachment.BlobKey)
+
+	return nil
+}
+
+// CollectGarbage deletes blobs no attachment refers to anymore, left behind
+// by deleted ideas and accounts or failed uploads. Blobs younger than GCGrace
+// are kept, their attachment may not be recorded yet.
+func (s *AttachmentService) CollectGarbage() (int, error) {
+	blobs, err := s.blobs.List()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-s.cfg.GCGrace)
+	var keys []string
+	for _, blob := range blobs {
+		if blob.ModTime.Before(cutoff) {
+			keys = append(keys, blob.Key)
+		}
+	}
+
+	deleted := 0
+	for len(keys) > 0 {
+		batch := keys[:min(len(keys), 500)]
+		keys = keys[len(batch):]
+
+		attached, err := s.store.GetAttachedBlobKeys(batch)
+		if err != nil {
+			return deleted, err
+		}
+		for _, key := range batch {
+			if attached[key] {
+				continue
+			}
+			if err := s.blobs.Delete(key); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// StartCollecting collects garbage in the background every GCInterval
+func (s *AttachmentService) StartCollecting() {
+	go func() {
+		ticker := time.NewTicker(s.cfg.GCInterval)
+		defer ticker.Stop()
+
+		for {
+			if n, err := s.CollectGarbage(); err != nil {
+				log.Printf("failed to collect attachment garbage: %v", err)
+			} else if n > 0 {
+				log.Printf("deleted %d orphaned attachment blobs", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+func (s *AttachmentService) allowed(mtype *mimetype.MIME) bool {
+	for _, t := range s.cfg.AllowedTypes {
+		if mtype.Is(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// getAttachment loads an attachment of an idea the viewer can see
+func (s *AttachmentService) getAttachment(ideaID, id uuid.UUID, viewer model.Actor) (model.Attachment, error) {
+	idea := s.ideas.GetIdea(ideaID)
+	if idea.Err != nil || idea.Data.ID == uuid.Nil || !idea.Data.VisibleTo(viewer) {
+		return model.Attachment{}, fmt.Errorf("%w: %s", ErrIdeaNotFound, ideaID)
+	}
+
+	result := s.store.GetAttachment(id)
+	if result.Err != nil {
+		return model.Attachment{}, result.Err
+	}
+	if result.Data.IdeaID != ideaID {
+		return model.Attachment{}, fmt.Errorf("%w: %s", ErrAttachmentNotFound, id)
+	}
+	return result.Data, nil
+}
+
+// checkIdea makes sure the idea exists, the viewer can see it and it was not
+// merged away
+func (s *AttachmentService) checkIdea(ideaID uuid.UUID, viewer model.Actor) error {
+	result := s.ideas.GetIdea(ideaID)
+	if result.Err != nil || result.Data.ID == uuid.Nil || !result.Data.VisibleTo(viewer) {
+		return fmt.Errorf("%w: %s", ErrIdeaNotFound, ideaID)
+	}
+	if result.Data.MergedInto != nil {
+		return fmt.Errorf("%w: %s", ErrIdeaMerged, *result.Data.MergedInto)
+	}
+	return nil
+}
+
+func (s *AttachmentService) deleteBlob(key string) {
+	if err := s.blobs.Delete(key); err != nil {
+		log.Printf("failed to delete blob %s, left to garbage collection: %v", key, err)
+	}
+}
+
+// cleanFilename keeps the base name of the upload without control
+// characters, falling back to a name with the sniffed extension
+func cleanFilename(name, extension string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	if name == "" || name == "." || name == "/" {
+		name = "attachment" + extension
+	}
+	if len(name) > 255 {
+		ext := filepath.Ext(name)
+		if len(ext) > 20 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:255-len(ext)], "") + ext
+	}
+	return name
+}
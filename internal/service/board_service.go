@@ -0,0 +1,261 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrBoardNotFound  = storage.ErrBoardNotFound
+	ErrBoardExists    = storage.ErrBoardExists
+	ErrMemberNotFound = storage.ErrMemberNotFound
+	ErrUserNotFound   = errors.New("user not found")
+	ErrInvalidBoard   = errors.New("invalid board")
+	ErrBoardForbidden = errors.New("only admins and owners of the board can manage it")
+	ErrBoardNotEmpty  = errors.New("the board still has ideas, delete or merge them first")
+	ErrNotBoardMember = errors.New("only members of the board can file ideas and vote on it")
+	ErrVotingClosed   = errors.New("voting is closed on this board")
+)
+
+// Slugs are lowercase words joined with dashes, e.g. "mobile-app"
+var boardSlugRe = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// BoardService manages boards and their members. Boards are created and
+// deleted by admins, board owners manage settings and members.
+type BoardService struct {
+	store storage.BoardStorage
+	users storage.UserStorage
+}
+
+func NewBoardService(store storage.BoardStorage, users storage.UserStorage) *BoardService {
+	return &BoardService{store, users}
+}
+
+func (s *BoardService) CreateBoard(payload model.CreateBoardPayload) utils.Result[model.Board] {
+	slug := strings.ToLower(strings.TrimSpace(payload.Slug))
+	if !boardSlugRe.MatchString(slug) {
+		return utils.Result[model.Board]{Err: fmt.Errorf("%w: the slug must be lowercase letters and digits joined with dashes", ErrInvalidBoard)}
+	}
+	statuses, err := boardStatuses(payload.AllowedStatuses)
+	if err != nil {
+		return utils.Result[model.Board]{Err: err}
+	}
+
+	now := time.Now()
+	board := model.Board{
+		ID:              uuid.New(),
+		Slug:            slug,
+		Name:            strings.TrimSpace(payload.Name),
+		Description:     strings.TrimSpace(payload.Description),
+		AllowedStatuses: statuses,
+		VotingOpen:      payload.VotingOpen == nil || *payload.VotingOpen,
+		MembersOnly:     payload.MembersOnly,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if err := s.store.CreateBoard(board); err != nil {
+		return utils.Result[model.Board]{Err: err}
+	}
+
+	return utils.Result[model.Board]{Data: board}
+}
+
+func (s *BoardService) GetBoards() utils.Result[[]model.Board] {
+	result := s.store.GetBoards()
+	if result.Err == nil && result.Data == nil {
+		result.Data = []model.Board{}
+	}
+	return result
+}
+
+// GetBoard looks a board up by slug, the empty slug is the default board
+func (s *BoardService) GetBoard(slug string) utils.Result[model.Board] {
+	if slug == "" {
+		slug = model.DefaultBoardSlug
+	}
+	return s.store.GetBoardBySlug(slug)
+}
+
+// BoardOf returns the board the idea is on
+func (s *BoardService) BoardOf(idea model.Idea) utils.Result[model.Board] {
+	if idea.BoardID == uuid.Nil {
+		return s.GetBoard(model.DefaultBoardSlug)
+	}
+	return s.store.GetBoard(idea.BoardID)
+}
+
+// UpdateBoard changes the given fields. Ideas already in a status the board
+// no longer allows keep it, they just can't move into it again.
+func (s *BoardService) UpdateBoard(slug string, payload model.UpdateBoardPayload, actor model.Actor) utils.Result[model.Board] {
+	result := s.GetBoard(slug)
+	if result.Err != nil {
+		return result
+	}
+	board := result.Data
+	if err := s.checkManage(board, actor); err != nil {
+		return utils.Result[model.Board]{Err: err}
+	}
+
+	if payload.Name != nil {
+		board.Name = strings.TrimSpace(*payload.Name)
+	}
+	if payload.Description != nil {
+		board.Description = strings.TrimSpace(*payload.Description)
+	}
+	if payload.AllowedStatuses != nil {
+		statuses, err := boardStatuses(*payload.AllowedStatuses)
+		if err != nil {
+			return utils.Result[model.Board]{Err: err}
+		}
+		board.AllowedStatuses = statuses
+	}
+	if payload.VotingOpen != nil {
+		board.VotingOpen = *payload.VotingOpen
+	}
+	if payload.MembersOnly != nil {
+		board.MembersOnly = *payload.MembersOnly
+	}
+
+	if err := s.store.UpdateBoard(board); err != nil {
+		return utils.Result[model.Board]{Err: err}
+	}
+
+	return utils.Result[model.Board]{Data: board}
+}
+
+// DeleteBoard deletes a board without ideas. The default board stays.
+func (s *BoardService) DeleteBoard(slug string) error {
+	if slug == model.DefaultBoardSlug {
+		return fmt.Errorf("%w: the default board can't be deleted", ErrInvalidBoard)
+	}
+
+	result := s.GetBoard(slug)
+	if result.Err != nil {
+		return result.Err
+	}
+
+	count, err := s.store.CountBoardIdeas(result.Data.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrBoardNotEmpty
+	}
+
+	return s.store.DeleteBoard(result.Data.ID)
+}
+
+func (s *BoardService) GetMembers(slug string) utils.Result[[]model.BoardMembership] {
+	board := s.GetBoard(slug)
+	if board.Err != nil {
+		return utils.Result[[]model.BoardMembership]{Err: board.Err}
+	}
+
+	result := s.store.GetBoardMembers(board.Data.ID)
+	if result.Err == nil && result.Data == nil {
+		result.Data = []model.BoardMembership{}
+	}
+	return result
+}
+
+// SetMember adds the user to the board with the role, or changes the role of
+// a member
+func (s *BoardService) SetMember(slug, username string, role model.BoardRole, actor model.Actor) utils.Result[model.BoardMembership] {
+	board := s.GetBoard(slug)
+	if board.Err != nil {
+		return utils.Result[model.BoardMembership]{Err: board.Err}
+	}
+	if err := s.checkManage(board.Data, actor); err != nil {
+		return utils.Result[model.BoardMembership]{Err: err}
+	}
+
+	user, err := s.users.GetUserByUsername(username)
+	if err != nil || user.ID == uuid.Nil {
+		return utils.Result[model.BoardMembership]{Err: fmt.Errorf("%w: %s", ErrUserNotFound, username)}
+	}
+
+	member := model.BoardMembership{
+		BoardID:   board.Data.ID,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      role,
+		CreatedAt: time.Now(),
+	}
+	if err := s.store.SetBoardMember(member); err != nil {
+		return utils.Result[model.BoardMembership]{Err: err}
+	}
+
+	return utils.Result[model.BoardMembership]{Data: member}
+}
+
+func (s *BoardService) RemoveMember(slug, username string, actor model.Actor) error {
+	board := s.GetBoard(slug)
+	if board.Err != nil {
+		return board.Err
+	}
+	if err := s.checkManage(board.Data, actor); err != nil {
+		return err
+	}
+
+	user, err := s.users.GetUserByUsername(username)
+	if err != nil || user.ID == uuid.Nil {
+		return fmt.Errorf("%w: %s", ErrMemberNotFound, username)
+	}
+
+	return s.store.RemoveBoardMember(board.Data.ID, user.ID)
+}
+
+// CheckContributor makes sure the actor may file ideas and vote on the
+// board. Admins can always contribute.
+func (s *BoardService) CheckContributor(board model.Board, actor model.Actor) error {
+	if !board.MembersOnly || actor.Role.AtLeast(model.RoleAdmin) {
+		return nil
+	}
+
+	if _, err := s.store.GetBoardMembership(board.ID, actor.ID); err != nil {
+		if errors.Is(err, ErrMemberNotFound) {
+			return fmt.Errorf("%w: %s", ErrNotBoardMember, board.Slug)
+		}
+		return err
+	}
+	return nil
+}
+
+// checkManage makes sure the actor is an admin or an owner of the board
+func (s *BoardService) checkManage(board model.Board, actor model.Actor) error {
+	if actor.Role.AtLeast(model.RoleAdmin) {
+		return nil
+	}
+
+	member, err := s.store.GetBoardMembership(board.ID, actor.ID)
+	if errors.Is(err, ErrMemberNotFound) || (err == nil && member.Role != model.BoardOwner) {
+		return ErrBoardForbidden
+	}
+	return err
+}
+
+// boardStatuses removes repeated statuses. Ideas start as requested, so a
+// board that restricts statuses has to allow it.
+func boardStatuses(statuses []model.RequestStatus) ([]model.RequestStatus, error) {
+	var unique []model.RequestStatus
+	for _, status := range statuses {
+		if !slices.Contains(unique, status) {
+			unique = append(unique, status)
+		}
+	}
+
+	if len(unique) > 0 && !slices.Contains(unique, model.Requested) {
+		return nil, fmt.Errorf("%w: allowedStatuses must include %s, new ideas start there", ErrInvalidBoard, model.Requested)
+	}
+	return unique, nil
+}
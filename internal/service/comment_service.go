@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+	"test_project/test/pkg/markdown"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrIdeaNotFound     = errors.New("idea not found")
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrInvalidParent    = errors.New("replies must answer a top-level comment on the same idea")
+	ErrCommentForbidden = errors.New("only the author or a moderator can change this comment")
+	ErrEmptyComment     = errors.New("comment body must not be empty")
+)
+
+type CommentService struct {
+	store  storage.CommentStorage
+	ideas  storage.IdeaStorage
+	events *EventBus
+}
+
+func NewCommentService(store storage.CommentStorage, ideas storage.IdeaStorage, events *EventBus) *CommentService {
+	return &CommentService{store, ideas, events}
+}
+
+// GetComments returns the top-level comments of an idea, oldest first, each
+// with its replies
+func (s *CommentService) GetComments(ideaID uuid.UUID, viewer model.Actor) utils.Result[[]model.Comment] {
+	if _, err := s.getIdea(ideaID, viewer); err != nil {
+		return utils.Result[[]model.Comment]{Err: err}
+	}
+
+	result := s.store.GetComments(ideaID)
+	if result.Err != nil {
+		return result
+	}
+
+	return utils.Result[[]model.Comment]{Data: buildCommentTree(result.Data)}
+}
+
+func (s *CommentService) CreateComment(ideaID uuid.UUID, actor model.Actor, payload model.CreateCommentPayload) utils.Result[model.Comment] {
+	body := strings.TrimSpace(payload.Body)
+	if body == "" {
+		return utils.Result[model.Comment]{Err: ErrEmptyComment}
+	}
+
+	idea, err := s.getIdea(ideaID, actor)
+	if err != nil {
+		return utils.Result[model.Comment]{Err: err}
+	}
+
+	var parent *model.Comment
+	if payload.ParentID != nil {
+		result := s.store.GetComment(*payload.ParentID)
+		if result.Err != nil || result.Data.IdeaID != ideaID || result.Data.ParentID != nil {
+			return utils.Result[model.Comment]{Err: ErrInvalidParent}
+		}
+		parent = &result.Data
+	}
+
+	now := time.Now()
+	comment := model.Comment{
+		ID:         uuid.New(),
+		IdeaID:     ideaID,
+		ParentID:   payload.ParentID,
+		AuthorID:   &actor.ID,
+		AuthorName: actor.Username,
+		Body:       body,
+		BodyHTML:   markdown.Render(body),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	event, err := Event(model.CommentCreated{Comment: comment, IdeaTitle: idea.Title, Parent: parent})
+	if err != nil {
+		return utils.Result[model.Comment]{Err: err}
+	}
+
+	if err := s.store.CreateComment(comment, event); err != nil {
+		return utils.Result[model.Comment]{Err: err}
+	}
+	s.events.Wake()
+
+	return utils.Result[model.Comment]{Data: comment}
+}
+
+func (s *CommentService) UpdateComment(ideaID, commentID uuid.UUID, actor model.Actor, payload model.UpdateCommentPayload) utils.Result[model.Comment] {
+	body := strings.TrimSpace(payload.Body)
+	if body == "" {
+		return utils.Result[model.Comment]{Err: ErrEmptyComment}
+	}
+
+	result := s.getOwnComment(ideaID, commentID, actor)
+	if result.Err != nil {
+		return result
+	}
+
+	comment := result.Data
+	comment.Body = body
+	comment.BodyHTML = markdown.Render(body)
+	comment.Edited = true
+	comment.UpdatedAt = time.Now()
+
+	if err := s.store.UpdateComment(comment); err != nil {
+		return utils.Result[model.Comment]{Err: err}
+	}
+
+	return utils.Result[model.Comment]{Data: comment}
+}
+
+// DeleteComment deletes a comment, deleting a top-level comment also deletes
+// its replies
+func (s *CommentService) DeleteComment(ideaID, commentID uuid.UUID, actor model.Actor) error {
+	if result := s.getOwnComment(ideaID, commentID, actor); result.Err != nil {
+		return result.Err
+	}
+
+	return s.store.DeleteComment(commentID)
+}
+
+// getIdea makes sure the idea exists and the viewer can see it. The Postgres
+// store returns an empty idea rather than an error for unknown IDs, so both
+// cases are checked.
+func (s *CommentService) getIdea(ideaID uuid.UUID, viewer model.Actor) (model.Idea, error) {
+	result := s.ideas.GetIdea(ideaID)
+	if result.Err != nil || result.Data.ID == uuid.Nil || !result.Data.VisibleTo(viewer) {
+		return model.Idea{}, fmt.Errorf("%w: %s", ErrIdeaNotFound, ideaID)
+	}
+	if result.Data.MergedInto != nil {
+		return model.Idea{}, fmt.Errorf("%w: %s", ErrIdeaMerged, *result.Data.MergedInto)
+	}
+	return result.Data, nil
+}
+
+// getOwnComment loads a comment of the idea that the actor is allowed to change
+func (s *CommentService) getOwnComment(ideaID, commentID uuid.UUID, actor model.Actor) utils.Result[model.Comment] {
+	result := s.store.GetComment(commentID)
+	if result.Err != nil || result.Data.IdeaID != ideaID {
+		return utils.Result[model.Comment]{Err: fmt.Errorf("%w: %s", ErrCommentNotFound, commentID)}
+	}
+
+	if !result.Data.IsAuthor(actor) && !actor.Role.AtLeast(model.RoleModerator) {
+		return utils.Result[model.Comment]{Err: ErrCommentForbidden}
+	}
+
+	return result
+}
+
+// buildCommentTree nests replies under their parent. The input is ordered
+// oldest first, so both levels keep that order.
+func buildCommentTree(comments []model.Comment) []model.Comment {
+	replies := make(map[uuid.UUID][]model.Comment)
+	for _, c := range comments {
+		if c.ParentID != nil {
+			replies[*c.ParentID] = append(replies[*c.ParentID], c)
+		}
+	}
+
+	tree := []model.Comment{}
+	for _, c := range comments {
+		if c.ParentID == nil {
+			c.Replies = replies[c.ID]
+			tree = append(tree, c)
+		}
+	}
+
+	return tree
+}
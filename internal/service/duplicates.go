@@ -0,0 +1,104 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"test_project/test/internal/model"
+	"unicode"
+)
+
+const (
+	duplicateThreshold = 0.45
+	maxDuplicates      = 5
+	titleWeight        = 0.7
+)
+
+// stopWords are left out of description comparisons, they say nothing about
+// what an idea is about
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "be": true, "for": true,
+	"from": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"that": true, "the": true, "this": true, "to": true, "with": true, "would": true,
+	"should": true, "can": true, "we": true, "i": true, "add": true, "support": true,
+}
+
+// findDuplicates scores every candidate against the idea and returns the
+// likely duplicates, best match first. Titles are compared by character
+// trigrams so small spelling differences still match, descriptions by words.
+func findDuplicates(idea model.Idea, candidates []model.Idea) []model.DuplicateCandidate {
+	title := trigrams(idea.Title)
+	words := wordSet(idea.Description)
+
+	duplicates := []model.DuplicateCandidate{}
+	for _, other := range candidates {
+		if other.ID == idea.ID || other.MergedInto != nil {
+			continue
+		}
+
+		score := jaccard(title, trigrams(other.Title))
+		otherWords := wordSet(other.Description)
+		if len(words) > 0 && len(otherWords) > 0 {
+			score = titleWeight*score + (1-titleWeight)*jaccard(words, otherWords)
+		}
+
+		if score >= duplicateThreshold {
+			duplicates = append(duplicates, model.DuplicateCandidate{
+				ID:     other.ID,
+				Title:  other.Title,
+				Status: other.Status,
+				Score:  float64(int(score*100)) / 100,
+			})
+		}
+	}
+
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return duplicates[i].Score > duplicates[j].Score
+	})
+	if len(duplicates) > maxDuplicates {
+		duplicates = duplicates[:maxDuplicates]
+	}
+	return duplicates
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func wordSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range words(text) {
+		if !stopWords[w] {
+			set[w] = true
+		}
+	}
+	return set
+}
+
+// trigrams works like pg_trgm: each word is padded with two spaces in front
+// and one behind, so "go" gives "  g", " go" and "go "
+func trigrams(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range words(text) {
+		padded := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for k := range a {
+		if b[k] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
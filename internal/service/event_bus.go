@@ -0,0 +1,178 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"test_project/test/internal/config"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	"time"
+)
+
+// Events are loaded from the outbox in batches of this size
+const eventBatchSize = 100
+
+// EventHandler handles one domain event. Returning an error makes the bus
+// retry the event for this handler later.
+type EventHandler func(event model.OutboxEvent) error
+
+type subscriber struct {
+	name   string
+	handle EventHandler
+}
+
+// EventBus dispatches domain events from the outbox to subscribers. Services
+// write events together with their changes, so an event is recorded exactly
+// when the change is, and the bus delivers it at least once: after a crash or
+// a failed handler it is dispatched again. Handlers should therefore cope
+// with seeing an event twice.
+type EventBus struct {
+	store       storage.OutboxStorage
+	cfg         config.EventConfig
+	subscribers map[model.EventType][]subscriber
+	wake        chan struct{}
+}
+
+func NewEventBus(store storage.OutboxStorage, cfg config.EventConfig) *EventBus {
+	return &EventBus{
+		store:       store,
+		cfg:         cfg,
+		subscribers: map[model.EventType][]subscriber{},
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Subscribe registers a handler for events of type T. The name identifies
+// the subscriber in the outbox and must stay the same across restarts.
+func Subscribe[T model.EventPayload](bus *EventBus, name string, handle func(event model.OutboxEvent, payload T) error) {
+	var zero T
+	eventType := zero.EventType()
+
+	bus.subscribers[eventType] = append(bus.subscribers[eventType], subscriber{
+		name: name,
+		handle: func(event model.OutboxEvent) error {
+			var payload T
+			if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+				return fmt.Errorf("failed to decode %s: %v", event.Type, err)
+			}
+			return handle(event, payload)
+		},
+	})
+}
+
+// Event builds the outbox entry for a payload. Services pass it to the
+// storage method making the change.
+func Event(payload model.EventPayload) (model.OutboxEvent, error) {
+	event, err := model.NewOutboxEvent(payload)
+	if err != nil {
+		return model.OutboxEvent{}, fmt.Errorf("failed to build %s event: %v", payload.EventType(), err)
+	}
+	return event, nil
+}
+
+// Wake dispatches right away instead of at the next poll, services call it
+// after committing events
+func (b *EventBus) Wake() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// StartDispatching dispatches in the background, every PollInterval and
+// whenever woken. Dispatched events are pruned once an hour.
+func (b *EventBus) StartDispatching() {
+	go func() {
+		ticker := time.NewTicker(b.cfg.PollInterval)
+		defer ticker.Stop()
+		var lastPrune time.Time
+
+		for {
+			if err := b.Dispatch(); err != nil {
+				log.Printf("failed to dispatch events: %v", err)
+			}
+
+			if time.Since(lastPrune) > time.Hour {
+				if _, err := b.store.PruneOutbox(time.Now().Add(-b.cfg.Retention)); err != nil {
+					log.Printf("failed to prune events: %v", err)
+				}
+				lastPrune = time.Now()
+			}
+
+			select {
+			case <-ticker.C:
+			case <-b.wake:
+			}
+		}
+	}()
+}
+
+// Dispatch hands every due event to the subscribers that have not handled it
+// yet. An event that failed for a subscriber is retried with backoff.
+func (b *EventBus) Dispatch() error {
+	for {
+		pending := b.store.GetPendingEvents(time.Now(), eventBatchSize)
+		if pending.Err != nil {
+			return pending.Err
+		}
+
+		for _, event := range pending.Data {
+			if err := b.store.UpdateOutboxEvent(b.dispatch(event)); err != nil {
+				return err
+			}
+		}
+
+		if len(pending.Data) < eventBatchSize {
+			return nil
+		}
+	}
+}
+
+func (b *EventBus) dispatch(event model.OutboxEvent) model.OutboxEvent {
+	var errs []string
+	for _, sub := range b.subscribers[event.Type] {
+		if slices.Contains(event.Delivered, sub.name) {
+			continue
+		}
+		if err := b.handle(sub, event); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", sub.name, err))
+			continue
+		}
+		event.Delivered = append(event.Delivered, sub.name)
+	}
+
+	now := time.Now()
+	event.Attempts++
+	if len(errs) == 0 {
+		event.DispatchedAt = &now
+		event.LastError = ""
+		return event
+	}
+
+	event.LastError = strings.Join(errs, "; ")
+	event.NextAttemptAt = now.Add(b.retryBackoff(event.Attempts))
+	log.Printf("event %s (%s) failed, retrying: %s", event.ID, event.Type, event.LastError)
+	return event
+}
+
+// handle runs a subscriber, a panicking one counts as failed
+func (b *EventBus) handle(sub subscriber, event model.OutboxEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprint("panic: ", r))
+		}
+	}()
+	return sub.handle(event)
+}
+
+func (b *EventBus) retryBackoff(attempts int) time.Duration {
+	backoff := b.cfg.RetryBackoff
+	for i := 1; i < attempts && backoff < b.cfg.MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, b.cfg.MaxRetryBackoff)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"test_project/test/internal/config"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrUnknownStatus = errors.New("unknown status")
+
+type FeedService struct {
+	ideas   storage.IdeaStorage
+	history storage.StatusHistoryStorage
+	cfg     config.FeedConfig
+}
+
+func NewFeedService(ideas storage.IdeaStorage, history storage.StatusHistoryStorage, cfg config.FeedConfig) *FeedService {
+	return &FeedService{ideas, history, cfg}
+}
+
+// NewestIdeas is the feed of the most recently filed ideas
+func (s *FeedService) NewestIdeas() utils.Result[model.Feed] {
+	result := s.publicIdeas()
+	if result.Err != nil {
+		return utils.Result[model.Feed]{Err: result.Err}
+	}
+
+	feed := model.Feed{
+		Title:    s.cfg.Title + ": newest ideas",
+		Subtitle: "Ideas most recently filed",
+		Link:     s.cfg.BaseURL + "/v1/feeds/ideas",
+	}
+	return utils.Result[model.Feed]{Data: s.buildFeed(feed, result.Data, nil)}
+}
+
+// IdeasWithStatus is the feed of ideas in the status, those that most
+// recently moved into it first, e.g. the recently published ideas
+func (s *FeedService) IdeasWithStatus(status model.RequestStatus) utils.Result[model.Feed] {
+	if !utils.IsValidRequestStatus(status) {
+		return utils.Result[model.Feed]{Err: fmt.Errorf("%w: %s", ErrUnknownStatus, status)}
+	}
+
+	result := s.publicIdeas()
+	if result.Err != nil {
+		return utils.Result[model.Feed]{Err: result.Err}
+	}
+	enteredAt, err := s.history.GetStatusEnteredAt(status)
+	if err != nil {
+		return utils.Result[model.Feed]{Err: err}
+	}
+
+	var ideas []model.Idea
+	for _, idea := range result.Data {
+		if idea.Status == status {
+			ideas = append(ideas, idea)
+		}
+	}
+
+	feed := model.Feed{
+		Title:    fmt.Sprintf("%s: %s ideas", s.cfg.Title, status),
+		Subtitle: fmt.Sprintf("Ideas that most recently became %s", status),
+		Link:     s.cfg.BaseURL + "/v1/feeds/status/" + url.PathEscape(string(status)) + "/ideas",
+	}
+	return utils.Result[model.Feed]{Data: s.buildFeed(feed, ideas, enteredAt)}
+}
+
+// IdeasWithTag is the feed of the most recently filed ideas with the tag
+func (s *FeedService) IdeasWithTag(tag string) utils.Result[model.Feed] {
+	tag = model.NormalizeTag(tag)
+
+	result := s.publicIdeas()
+	if result.Err != nil {
+		return utils.Result[model.Feed]{Err: result.Err}
+	}
+
+	var ideas []model.Idea
+	for _, idea := range result.Data {
+		for _, t := range idea.Tags {
+			if t == tag {
+				ideas = append(ideas, idea)
+				break
+			}
+		}
+	}
+
+	feed := model.Feed{
+		Title:    fmt.Sprintf("%s: ideas tagged %s", s.cfg.Title, tag),
+		Subtitle: fmt.Sprintf("Ideas most recently filed under %s", tag),
+		Link:     s.cfg.BaseURL + "/v1/feeds/tags/" + url.PathEscape(tag) + "/ideas",
+	}
+	return utils.Result[model.Feed]{Data: s.buildFeed(feed, ideas, nil)}
+}
+
+// buildFeed adds the newest ideas as entries. Ideas are ordered by the time
+// in since, or by creation when they are missing from it. The feed counts as
+// updated when its newest entry was.
+func (s *FeedService) buildFeed(feed model.Feed, ideas []model.Idea, since map[uuid.UUID]time.Time) model.Feed {
+	sortTime := func(idea model.Idea) time.Time {
+		if t, ok := since[idea.ID]; ok {
+			return t
+		}
+		return idea.CreatedAt
+	}
+	sort.SliceStable(ideas, func(i, j int) bool {
+		return sortTime(ideas[i]).After(sortTime(ideas[j]))
+	})
+	if len(ideas) > s.cfg.Limit {
+		ideas = ideas[:s.cfg.Limit]
+	}
+
+	feed.Entries = make([]model.FeedEntry, 0, len(ideas))
+	for _, idea := range ideas {
+		updated := idea.UpdatedAt
+		if t := sortTime(idea); t.After(updated) {
+			updated = t
+		}
+		if updated.After(feed.Updated) {
+			feed.Updated = updated
+		}
+
+		feed.Entries = append(feed.Entries, model.FeedEntry{
+			ID:         "urn:uuid:" + idea.ID.String(),
+			Title:      idea.Title,
+			Link:       s.cfg.BaseURL + "/v1/idea/" + idea.ID.String(),
+			Summary:    idea.Description,
+			Content:    idea.DescriptionHTML,
+			Author:     idea.RequestedBy,
+			Categories: idea.Tags,
+			Links:      idea.Links,
+			Published:  idea.CreatedAt,
+			Updated:    updated,
+		})
+	}
+
+	return feed
+}
+
+// publicIdeas returns the ideas listed for everyone, feeds are read
+// anonymously
+func (s *FeedService) publicIdeas() utils.Result[[]model.Idea] {
+	result := s.ideas.GetAllIdeas()
+	if result.Err != nil {
+		return result
+	}
+	return utils.Result[[]model.Idea]{Data: listedIdeas(result.Data, model.Actor{})}
+}
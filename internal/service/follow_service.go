@@ -0,0 +1,90 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+
+	"github.com/google/uuid"
+)
+
+type FollowService struct {
+	store storage.FollowStorage
+	ideas storage.IdeaStorage
+	users storage.UserStorage
+}
+
+func NewFollowService(store storage.FollowStorage, ideas storage.IdeaStorage, users storage.UserStorage) *FollowService {
+	return &FollowService{store, ideas, users}
+}
+
+// Subscribe makes authors follow their new ideas and voters the ideas they
+// vote for, as far as their preferences allow
+func (s *FollowService) Subscribe(bus *EventBus) {
+	Subscribe(bus, "follows", func(_ model.OutboxEvent, e model.IdeaCreated) error {
+		if e.Idea.AuthorID == nil {
+			return nil
+		}
+		return s.autoFollow(e.Idea.ID, *e.Idea.AuthorID, func(user model.User) bool { return user.AutoFollowCreated })
+	})
+	Subscribe(bus, "follows", func(_ model.OutboxEvent, e model.VoteAdded) error {
+		return s.autoFollow(e.IdeaID, e.UserID, func(user model.User) bool { return user.AutoFollowVoted })
+	})
+}
+
+func (s *FollowService) Follow(ideaID uuid.UUID, actor model.Actor) error {
+	if err := s.checkIdea(ideaID, actor); err != nil {
+		return err
+	}
+
+	return s.store.FollowIdea(ideaID, actor.ID)
+}
+
+func (s *FollowService) Unfollow(ideaID uuid.UUID, actor model.Actor) error {
+	if err := s.checkIdea(ideaID, actor); err != nil {
+		return err
+	}
+
+	return s.store.UnfollowIdea(ideaID, actor.ID)
+}
+
+// GetFollowing returns the followed ideas that are listed for the actor
+func (s *FollowService) GetFollowing(actor model.Actor) utils.Result[[]model.Idea] {
+	result := s.store.GetFollowedIdeas(actor.ID)
+	if result.Err != nil {
+		return result
+	}
+
+	return utils.Result[[]model.Idea]{Data: listedIdeas(result.Data, actor)}
+}
+
+// autoFollow follows the idea for the user if wanted says so. Users deleted
+// in the meantime are skipped.
+func (s *FollowService) autoFollow(ideaID, userID uuid.UUID, wanted func(model.User) bool) error {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		log.Printf("not auto-following idea %s for %s: %v", ideaID, userID, err)
+		return nil
+	}
+	if !wanted(user) {
+		return nil
+	}
+
+	return s.store.FollowIdea(ideaID, userID)
+}
+
+// checkIdea makes sure the idea exists, the actor can see it and it is not a
+// redirect, follows belong to the canonical idea
+func (s *FollowService) checkIdea(ideaID uuid.UUID, actor model.Actor) error {
+	result := s.ideas.GetIdea(ideaID)
+	if result.Err != nil || result.Data.ID == uuid.Nil || !result.Data.VisibleTo(actor) {
+		return fmt.Errorf("%w: %s", ErrIdeaNotFound, ideaID)
+	}
+	if result.Data.MergedInto != nil {
+		return fmt.Errorf("%w: %s", ErrIdeaMerged, *result.Data.MergedInto)
+	}
+
+	return nil
+}
@@ -1,54 +1,468 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
+	"test_project/test/internal/config"
 	"test_project/test/internal/model"
 	"test_project/test/internal/storage"
 	utils "test_project/test/pkg"
+	"test_project/test/pkg/markdown"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// Merges can be chained, a redirect is followed at most this many times
+const maxMergeHops = 5
+
+var (
+	ErrInvalidTransition = errors.New("status change not allowed")
+	ErrReasonRequired    = errors.New("a reason is required for this status change")
+	ErrIdeaMerged        = errors.New("idea was merged into another idea")
+	ErrInvalidMerge      = errors.New("invalid merge")
+	ErrIdeaForbidden     = errors.New("only the author or a maintainer can change this idea")
+	ErrStatusForbidden   = errors.New("only maintainers can change the status of an idea")
+	ErrInvalidLink       = errors.New("invalid link")
+	ErrLinkRequired      = errors.New("published ideas need at least one link to the result")
+)
+
 type IdeaService struct {
-	store storage.IdeaStorage
+	store    storage.IdeaStorage
+	history  storage.StatusHistoryStorage
+	merges   storage.MergeStorage
+	boards   *BoardService
+	techs    *TechStackService
+	events   *EventBus
+	workflow config.WorkflowConfig
+}
+
+func NewIdeaService(store storage.IdeaStorage, history storage.StatusHistoryStorage, merges storage.MergeStorage, boards *BoardService, techs *TechStackService, events *EventBus, workflow config.WorkflowConfig) *IdeaService {
+	return &IdeaService{store, history, merges, boards, techs, events, workflow}
+}
+
+// CreateIdea files the idea on the board with the slug, the empty slug is
+// the default board
+func (s *IdeaService) CreateIdea(slug string, idea model.Idea, actor model.Actor) utils.Result[string] {
+	board := s.boards.GetBoard(slug)
+	if board.Err != nil {
+		return utils.Result[string]{Err: board.Err}
+	}
+	if err := s.boards.CheckContributor(board.Data, actor); err != nil {
+		return utils.Result[string]{Err: err}
+	}
+	idea.BoardID = board.Data.ID
+
+	techStack, err := s.techs.NormalizeTechStack(idea.TechStack, nil)
+	if err != nil {
+		return utils.Result[string]{Err: err}
+	}
+	idea.TechStack = techStack
+
+	tags, err := normalizeIdeaTags(idea.Tags)
+	if err != nil {
+		return utils.Result[string]{Err: err}
+	}
+	idea.Tags = tags
+	links, err := normalizeIdeaLinks(idea.Links)
+	if err != nil {
+		return utils.Result[string]{Err: err}
+	}
+	idea.Links = links
+	idea.DescriptionHTML = markdown.Render(idea.Description)
+	if idea.Visibility == "" {
+		idea.Visibility = model.VisibilityPublic
+	}
+
+	// Ideas enter the workflow at the start, later statuses are reached
+	// through transitions
+	if idea.Status != model.Requested {
+		return utils.Result[string]{Err: fmt.Errorf("%w: new ideas start as %s", ErrInvalidTransition, model.Requested)}
+	}
+
+	// The event carries the idea as stored, so fill in what the store would
+	if idea.ID == uuid.Nil {
+		idea.ID = uuid.New()
+	}
+	now := time.Now()
+	if idea.CreatedAt.IsZero() {
+		idea.CreatedAt = now
+	}
+	if idea.UpdatedAt.IsZero() {
+		idea.UpdatedAt = now
+	}
+
+	event, err := Event(model.IdeaCreated{Idea: idea})
+	if err != nil {
+		return utils.Result[string]{Err: err}
+	}
+
+	result := s.store.CreateIdea(idea, event)
+	if result.Err == nil {
+		s.events.Wake()
+	}
+
+	return result
+}
+
+// GetAllIdeas returns the ideas on the board listed for the viewer that pass
+// the filter
+func (s *IdeaService) GetAllIdeas(slug string, viewer model.Actor, filter model.IdeaFilter) utils.Result[[]model.Idea] {
+	board := s.boards.GetBoard(slug)
+	if board.Err != nil {
+		return utils.Result[[]model.Idea]{Err: board.Err}
+	}
+
+	result := s.store.GetAllIdeas()
+	if result.Err != nil {
+		return result
+	}
+
+	ideas := []model.Idea{}
+	for _, idea := range listedIdeas(ideasOnBoard(result.Data, board.Data), viewer) {
+		if filter.Matches(idea) {
+			ideas = append(ideas, idea)
+		}
+	}
+
+	return utils.Result[[]model.Idea]{Data: ideas}
 }
 
-func NewIdeaService(store storage.IdeaStorage) *IdeaService {
-	return &IdeaService{store}
+// GetIdea returns the idea, following redirects of merged ideas to the idea
+// they were merged into. Ideas the viewer can't see are not found.
+func (s *IdeaService) GetIdea(id uuid.UUID, viewer model.Actor) utils.Result[model.Idea] {
+	for range maxMergeHops + 1 {
+		result := s.store.GetIdea(id)
+		if result.Err != nil || result.Data.ID == uuid.Nil || !result.Data.VisibleTo(viewer) {
+			return utils.Result[model.Idea]{Err: fmt.Errorf("%w: %s", ErrIdeaNotFound, id)}
+		}
+		if result.Data.MergedInto == nil {
+			return result
+		}
+		id = *result.Data.MergedInto
+	}
+
+	return utils.Result[model.Idea]{Err: fmt.Errorf("%w: too many redirects", ErrIdeaNotFound)}
 }
 
-func (s *IdeaService) CreateIdea(idea model.Idea) utils.Result[string] {
-	// for _, stack := range idea.TechStack {
-	// 	if !utils.IsValidTechStack(stack) {
-	// 		return utils.Result[string]{Err: fmt.Errorf("invalid tech stack: %v", stack)}
-	// 	}
-	// }
+// GetBoardIdea is GetIdea for an idea that has to be on the board
+func (s *IdeaService) GetBoardIdea(slug string, id uuid.UUID, viewer model.Actor) utils.Result[model.Idea] {
+	board := s.boards.GetBoard(slug)
+	if board.Err != nil {
+		return utils.Result[model.Idea]{Err: board.Err}
+	}
 
-	return s.store.CreateIdea(idea)
+	result := s.GetIdea(id, viewer)
+	if result.Err != nil {
+		return result
+	}
+	if !isOnBoard(result.Data, board.Data) {
+		return utils.Result[model.Idea]{Err: fmt.Errorf("%w: %s", ErrIdeaNotFound, id)}
+	}
+	return result
 }
 
-func (s *IdeaService) GetAllIdeas() utils.Result[[]model.Idea] {
-	return s.store.GetAllIdeas()
+// FindDuplicates lists ideas on the board listed for the viewer that look
+// like the given one
+func (s *IdeaService) FindDuplicates(slug string, idea model.Idea, viewer model.Actor) utils.Result[[]model.DuplicateCandidate] {
+	result := s.GetAllIdeas(slug, viewer, model.IdeaFilter{})
+	if result.Err != nil {
+		return utils.Result[[]model.DuplicateCandidate]{Err: result.Err}
+	}
+
+	return utils.Result[[]model.DuplicateCandidate]{Data: findDuplicates(idea, result.Data)}
 }
 
-func (s *IdeaService) GetIdea(id uuid.UUID) utils.Result[model.Idea] {
-	return s.store.GetIdea(id)
+// MergeIdea folds a duplicate into the canonical idea. Votes and comments move
+// over and the duplicate becomes a redirect to the canonical idea.
+func (s *IdeaService) MergeIdea(sourceID, targetID uuid.UUID) error {
+	if sourceID == targetID {
+		return fmt.Errorf("%w: an idea cannot be merged into itself", ErrInvalidMerge)
+	}
+
+	source := s.store.GetIdea(sourceID)
+	if source.Err != nil || source.Data.ID == uuid.Nil {
+		return fmt.Errorf("%w: %s", ErrIdeaNotFound, sourceID)
+	}
+	if source.Data.MergedInto != nil {
+		return fmt.Errorf("%w: %s", ErrIdeaMerged, sourceID)
+	}
+
+	target := s.store.GetIdea(targetID)
+	if target.Err != nil || target.Data.ID == uuid.Nil {
+		return fmt.Errorf("%w: %s", ErrIdeaNotFound, targetID)
+	}
+	if target.Data.MergedInto != nil {
+		return fmt.Errorf("%w: %s was merged into %s, merge into that idea instead", ErrInvalidMerge, targetID, *target.Data.MergedInto)
+	}
+	if source.Data.BoardID != target.Data.BoardID {
+		return fmt.Errorf("%w: the ideas are on different boards", ErrInvalidMerge)
+	}
+
+	event, err := Event(model.IdeaMerged{SourceID: sourceID, TargetID: targetID})
+	if err != nil {
+		return err
+	}
+
+	if err := s.merges.MergeIdea(sourceID, targetID, event); err != nil {
+		return err
+	}
+	s.events.Wake()
+	return nil
 }
 
-func (s *IdeaService) UpdateIdea(id uuid.UUID, idea model.Idea) utils.Result[string] {
+// UpdateIdea saves the idea. A status change has to be allowed by the
+// workflow and is recorded in the status history together with actor and reason.
+func (s *IdeaService) UpdateIdea(id uuid.UUID, idea model.Idea, actor model.Actor, reason string) utils.Result[string] {
 	if !utils.IsValidRequestStatus(idea.Status) {
 		return utils.Result[string]{Err: fmt.Errorf("invalid request status: %v", idea.Status)}
 	}
 
-	// for _, stack := range idea.TechStack {
-	// 	if !utils.IsValidTechStack(stack) {
-	// 		return utils.Result[string]{Err: fmt.Errorf("invalid tech stack: %v", stack)}
-	// 	}
-	// }
+	current := s.store.GetIdea(id)
+	if current.Err != nil || current.Data.ID == uuid.Nil {
+		return utils.Result[string]{Err: fmt.Errorf("%w: %s", ErrIdeaNotFound, id)}
+	}
+	if current.Data.MergedInto != nil {
+		return utils.Result[string]{Err: fmt.Errorf("%w: %s", ErrIdeaMerged, *current.Data.MergedInto)}
+	}
+	if !current.Data.VisibleTo(actor) {
+		return utils.Result[string]{Err: fmt.Errorf("%w: %s", ErrIdeaNotFound, id)}
+	}
+	if !canEditIdea(current.Data, actor) {
+		return utils.Result[string]{Err: ErrIdeaForbidden}
+	}
+	if idea.Status != current.Data.Status && !actor.Role.AtLeast(model.RoleMaintainer) {
+		return utils.Result[string]{Err: ErrStatusForbidden}
+	}
 
-	return s.store.UpdateIdea(id, idea)
+	if idea.Visibility == "" {
+		idea.Visibility = current.Data.EffectiveVisibility()
+	}
+	var events []model.OutboxEvent
+	if idea.Visibility != current.Data.EffectiveVisibility() && idea.Visibility == model.VisibilityPublic {
+		event, err := Event(model.IdeaPublished{Idea: idea, From: current.Data.EffectiveVisibility(), Actor: actor})
+		if err != nil {
+			return utils.Result[string]{Err: err}
+		}
+		events = append(events, event)
+	}
+
+	techStack, err := s.techs.NormalizeTechStack(idea.TechStack, current.Data.TechStack)
+	if err != nil {
+		return utils.Result[string]{Err: err}
+	}
+	idea.TechStack = techStack
+
+	tags, err := normalizeIdeaTags(idea.Tags)
+	if err != nil {
+		return utils.Result[string]{Err: err}
+	}
+	idea.Tags = tags
+	links, err := normalizeIdeaLinks(idea.Links)
+	if err != nil {
+		return utils.Result[string]{Err: err}
+	}
+	idea.Links = links
+	idea.DescriptionHTML = markdown.Render(idea.Description)
+
+	idea.ID = id
+
+	// Voters want to know where to find the result, also when the links of
+	// an idea that is already published are removed. Ideas published before
+	// links existed have none and can still be edited.
+	if idea.Status == model.Published && len(idea.Links) == 0 &&
+		(current.Data.Status != model.Published || len(current.Data.Links) > 0) {
+		return utils.Result[string]{Err: ErrLinkRequired}
+	}
+
+	from := current.Data.Status
+	if from == idea.Status {
+		event, err := Event(model.IdeaUpdated{Idea: idea, Actor: actor})
+		if err != nil {
+			return utils.Result[string]{Err: err}
+		}
+
+		result := s.store.UpdateIdea(id, idea, append(events, event)...)
+		if result.Err == nil {
+			s.events.Wake()
+		}
+		return result
+	}
+
+	if !s.workflow.Allows(from, idea.Status) {
+		return utils.Result[string]{Err: fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, idea.Status)}
+	}
+	board := s.boards.BoardOf(current.Data)
+	if board.Err != nil {
+		return utils.Result[string]{Err: board.Err}
+	}
+	if !board.Data.AllowsStatus(idea.Status) {
+		return utils.Result[string]{Err: fmt.Errorf("%w: board %s doesn't use %s", ErrInvalidTransition, board.Data.Slug, idea.Status)}
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" && s.workflow.RequiresReason(idea.Status) {
+		return utils.Result[string]{Err: fmt.Errorf("%w: %s", ErrReasonRequired, idea.Status)}
+	}
+
+	transition := model.StatusTransition{
+		ID:        uuid.New(),
+		IdeaID:    id,
+		Kind:      model.HistoryStatus,
+		From:      from,
+		To:        idea.Status,
+		Reason:    reason,
+		ActorID:   &actor.ID,
+		ActorName: actor.Username,
+		CreatedAt: time.Now(),
+	}
+
+	event, err := Event(model.IdeaStatusChanged{Idea: idea, From: from, To: idea.Status, Reason: reason, Actor: actor})
+	if err != nil {
+		return utils.Result[string]{Err: err}
+	}
+
+	result := s.history.UpdateIdeaStatus(id, idea, transition, append(events, event)...)
+	if result.Err == nil {
+		s.events.Wake()
+	}
+
+	return result
 }
 
-func (s *IdeaService) DeleteIdea(id uuid.UUID) utils.Result[string] {
-	return s.store.DeleteIdea(id)
+// PublishIdea makes the idea public. Publishing a public idea does nothing.
+func (s *IdeaService) PublishIdea(id uuid.UUID, actor model.Actor) error {
+	current := s.store.GetIdea(id)
+	if current.Err != nil || current.Data.ID == uuid.Nil || !current.Data.VisibleTo(actor) {
+		return fmt.Errorf("%w: %s", ErrIdeaNotFound, id)
+	}
+	if current.Data.MergedInto != nil {
+		return fmt.Errorf("%w: %s", ErrIdeaMerged, *current.Data.MergedInto)
+	}
+	if !canEditIdea(current.Data, actor) {
+		return ErrIdeaForbidden
+	}
+
+	from := current.Data.EffectiveVisibility()
+	if from == model.VisibilityPublic {
+		return nil
+	}
+
+	idea := current.Data
+	idea.Visibility = model.VisibilityPublic
+	event, err := Event(model.IdeaPublished{Idea: idea, From: from, Actor: actor})
+	if err != nil {
+		return err
+	}
+
+	if result := s.store.UpdateIdea(id, idea, event); result.Err != nil {
+		return result.Err
+	}
+	s.events.Wake()
+	return nil
+}
+
+// GetStatusHistory returns every status change of the idea, oldest first
+func (s *IdeaService) GetStatusHistory(id uuid.UUID, viewer model.Actor) utils.Result[[]model.StatusTransition] {
+	current := s.store.GetIdea(id)
+	if current.Err != nil || current.Data.ID == uuid.Nil || !current.Data.VisibleTo(viewer) {
+		return utils.Result[[]model.StatusTransition]{Err: fmt.Errorf("%w: %s", ErrIdeaNotFound, id)}
+	}
+
+	return s.history.GetStatusHistory(id)
+}
+
+// DeleteIdea deletes an idea the actor can see, only the author and
+// maintainers can delete it
+func (s *IdeaService) DeleteIdea(id uuid.UUID, actor model.Actor) utils.Result[string] {
+	current := s.store.GetIdea(id)
+	if current.Err != nil || current.Data.ID == uuid.Nil || !current.Data.VisibleTo(actor) {
+		return utils.Result[string]{Err: fmt.Errorf("%w: %s", ErrIdeaNotFound, id)}
+	}
+	if !canEditIdea(current.Data, actor) {
+		return utils.Result[string]{Err: ErrIdeaForbidden}
+	}
+
+	event, err := Event(model.IdeaDeleted{IdeaID: id, Visibility: current.Data.EffectiveVisibility()})
+	if err != nil {
+		return utils.Result[string]{Err: err}
+	}
+
+	result := s.store.DeleteIdea(id, event)
+	if result.Err == nil {
+		s.events.Wake()
+	}
+
+	return result
+}
+
+// canEditIdea reports whether the actor may change the idea and who sees it
+func canEditIdea(idea model.Idea, actor model.Actor) bool {
+	return idea.IsAuthor(actor) || actor.Role.AtLeast(model.RoleMaintainer)
+}
+
+// listedIdeas keeps the ideas listed for the viewer
+func listedIdeas(ideas []model.Idea, viewer model.Actor) []model.Idea {
+	listed := make([]model.Idea, 0, len(ideas))
+	for _, idea := range ideas {
+		if idea.ListedFor(viewer) {
+			listed = append(listed, idea)
+		}
+	}
+	return listed
+}
+
+// isOnBoard reports whether the idea is on the board. Ideas saved before
+// boards existed are on the default board.
+func isOnBoard(idea model.Idea, board model.Board) bool {
+	if idea.BoardID == uuid.Nil {
+		return board.Slug == model.DefaultBoardSlug
+	}
+	return idea.BoardID == board.ID
+}
+
+func ideasOnBoard(ideas []model.Idea, board model.Board) []model.Idea {
+	var onBoard []model.Idea
+	for _, idea := range ideas {
+		if isOnBoard(idea, board) {
+			onBoard = append(onBoard, idea)
+		}
+	}
+	return onBoard
+}
+
+// normalizeIdeaLinks checks the links of an idea before they are stored. The
+// same URL is kept once per type, the order is kept as given.
+func normalizeIdeaLinks(links []model.IdeaLink) ([]model.IdeaLink, error) {
+	normalized := make([]model.IdeaLink, 0, len(links))
+	seen := make(map[string]bool)
+	for _, link := range links {
+		link.URL = strings.TrimSpace(link.URL)
+		link.Title = strings.TrimSpace(link.Title)
+
+		u, err := url.Parse(link.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%w: %q is not an http or https URL", ErrInvalidLink, link.URL)
+		}
+		switch link.Type {
+		case model.LinkRepository, model.LinkBlogPost, model.LinkVideo, model.LinkDemo, model.LinkDocs:
+		default:
+			return nil, fmt.Errorf("%w: unknown link type %q", ErrInvalidLink, link.Type)
+		}
+
+		key := string(link.Type) + " " + link.URL
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if link.ID == uuid.Nil {
+			link.ID = uuid.New()
+		}
+		link.Position = len(normalized)
+		normalized = append(normalized, link)
+	}
+	return normalized, nil
 }
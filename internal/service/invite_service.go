@@ -0,0 +1,65 @@
+package service
+
+import (
+	"errors"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InviteService struct {
+	store storage.InviteStorage
+}
+
+func NewInviteService(store storage.InviteStorage) *InviteService {
+	return &InviteService{store}
+}
+
+// CreateInvite issues a new code. MaxUses defaults to a single-use invite.
+func (s *InviteService) CreateInvite(createdBy uuid.UUID, req model.CreateInviteRequest) utils.Result[model.CreateInviteResponse] {
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return utils.Result[model.CreateInviteResponse]{Err: errors.New("expiresAt must be in the future")}
+	}
+
+	code, err := randomURLString(12)
+	if err != nil {
+		return utils.Result[model.CreateInviteResponse]{Err: err}
+	}
+
+	maxUses := req.MaxUses
+	if maxUses == 0 {
+		maxUses = 1
+	}
+
+	invite := model.Invite{
+		ID:        uuid.New(),
+		CodeHash:  utils.HashSecret(code),
+		CodeHint:  code[len(code)-4:],
+		Note:      req.Note,
+		MaxUses:   maxUses,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.store.CreateInvite(invite); err != nil {
+		return utils.Result[model.CreateInviteResponse]{Err: err}
+	}
+
+	return utils.Result[model.CreateInviteResponse]{Data: model.CreateInviteResponse{Invite: invite, Code: code}}
+}
+
+func (s *InviteService) GetInvites() utils.Result[[]model.Invite] {
+	return s.store.GetInvites()
+}
+
+func (s *InviteService) RevokeInvite(id uuid.UUID) error {
+	return s.store.RevokeInvite(id)
+}
+
+func (s *InviteService) GetRedemptions(inviteID uuid.UUID) utils.Result[[]model.InviteRedemption] {
+	return s.store.GetInviteRedemptions(inviteID)
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"test_project/test/internal/config"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMilestoneNotFound     = storage.ErrMilestoneNotFound
+	ErrInvalidMilestone      = errors.New("invalid milestone")
+	ErrIdeaNotInMilestone    = errors.New("idea is not assigned to this milestone")
+	ErrUnknownMilestoneState = errors.New("unknown milestone state")
+)
+
+// MilestoneService manages the milestones ideas are planned for. Progress is
+// the share of assigned ideas that are published, counting only ideas the
+// viewer can list.
+type MilestoneService struct {
+	store storage.MilestoneStorage
+	ideas storage.IdeaStorage
+	cfg   config.FeedConfig
+}
+
+func NewMilestoneService(store storage.MilestoneStorage, ideas storage.IdeaStorage, cfg config.FeedConfig) *MilestoneService {
+	return &MilestoneService{store, ideas, cfg}
+}
+
+func (s *MilestoneService) CreateMilestone(payload model.CreateMilestonePayload) utils.Result[model.Milestone] {
+	date, err := parseMilestoneDate(payload.TargetDate)
+	if err != nil {
+		return utils.Result[model.Milestone]{Err: err}
+	}
+
+	now := time.Now()
+	milestone := model.Milestone{
+		ID:          uuid.New(),
+		Name:        strings.TrimSpace(payload.Name),
+		Description: strings.TrimSpace(payload.Description),
+		TargetDate:  date,
+		State:       model.MilestoneOpen,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.store.CreateMilestone(milestone); err != nil {
+		return utils.Result[model.Milestone]{Err: err}
+	}
+
+	return utils.Result[model.Milestone]{Data: milestone}
+}
+
+// GetMilestones lists milestones by target date with their progress. The
+// state is "open", "closed" or empty for all of them.
+func (s *MilestoneService) GetMilestones(state model.MilestoneState, viewer model.Actor) utils.Result[[]model.Milestone] {
+	if state != "" && state != model.MilestoneOpen && state != model.MilestoneClosed {
+		return utils.Result[[]model.Milestone]{Err: fmt.Errorf("%w: %s", ErrUnknownMilestoneState, state)}
+	}
+
+	result := s.store.GetMilestones(state)
+	if result.Err != nil {
+		return result
+	}
+	if result.Data == nil {
+		result.Data = []model.Milestone{}
+	}
+
+	ideas := s.ideas.GetAllIdeas()
+	if ideas.Err != nil {
+		return utils.Result[[]model.Milestone]{Err: ideas.Err}
+	}
+	progress := milestoneProgress(listedIdeas(ideas.Data, viewer))
+	for i := range result.Data {
+		result.Data[i].Progress = progress[result.Data[i].ID]
+	}
+
+	return result
+}
+
+func (s *MilestoneService) GetMilestone(id uuid.UUID, viewer model.Actor) utils.Result[model.Milestone] {
+	result := s.store.GetMilestone(id)
+	if result.Err != nil {
+		return result
+	}
+
+	ideas := s.ideas.GetAllIdeas()
+	if ideas.Err != nil {
+		return utils.Result[model.Milestone]{Err: ideas.Err}
+	}
+	result.Data.Progress = milestoneProgress(listedIdeas(ideas.Data, viewer))[id]
+
+	return result
+}
+
+// GetMilestoneIdeas lists the ideas assigned to the milestone that the viewer
+// can list
+func (s *MilestoneService) GetMilestoneIdeas(id uuid.UUID, viewer model.Actor) utils.Result[[]model.Idea] {
+	if result := s.store.GetMilestone(id); result.Err != nil {
+		return utils.Result[[]model.Idea]{Err: result.Err}
+	}
+
+	ideas := s.ideas.GetAllIdeas()
+	if ideas.Err != nil {
+		return ideas
+	}
+
+	assigned := []model.Idea{}
+	for _, idea := range listedIdeas(ideas.Data, viewer) {
+		if idea.MilestoneID != nil && *idea.MilestoneID == id {
+			assigned = append(assigned, idea)
+		}
+	}
+
+	return utils.Result[[]model.Idea]{Data: assigned}
+}
+
+// UpdateMilestone changes the given fields. Closing a milestone records when
+// it was closed, reopening clears it again.
+func (s *MilestoneService) UpdateMilestone(id uuid.UUID, payload model.UpdateMilestonePayload, viewer model.Actor) utils.Result[model.Milestone] {
+	result := s.store.GetMilestone(id)
+	if result.Err != nil {
+		return result
+	}
+	milestone := result.Data
+
+	if payload.Name != nil {
+		milestone.Name = strings.TrimSpace(*payload.Name)
+	}
+	if payload.Description != nil {
+		milestone.Description = strings.TrimSpace(*payload.Description)
+	}
+	if payload.TargetDate != nil {
+		date, err := parseMilestoneDate(*payload.TargetDate)
+		if err != nil {
+			return utils.Result[model.Milestone]{Err: err}
+		}
+		milestone.TargetDate = date
+	}
+	if payload.State != nil && *payload.State != milestone.State {
+		milestone.State = *payload.State
+		milestone.ClosedAt = nil
+		if milestone.State == model.MilestoneClosed {
+			now := time.Now()
+			milestone.ClosedAt = &now
+		}
+	}
+
+	if err := s.store.UpdateMilestone(milestone); err != nil {
+		return utils.Result[model.Milestone]{Err: err}
+	}
+
+	return s.GetMilestone(id, viewer)
+}
+
+// DeleteMilestone deletes the milestone, its ideas stay but are unassigned
+func (s *MilestoneService) DeleteMilestone(id uuid.UUID) error {
+	return s.store.DeleteMilestone(id)
+}
+
+// AssignIdea plans the idea for the milestone. An idea is planned for one
+// milestone at most, assigning it again moves it.
+func (s *MilestoneService) AssignIdea(id, ideaID uuid.UUID) error {
+	if result := s.store.GetMilestone(id); result.Err != nil {
+		return result.Err
+	}
+
+	idea := s.ideas.GetIdea(ideaID)
+	if idea.Err != nil || idea.Data.ID == uuid.Nil {
+		return fmt.Errorf("%w: %s", ErrIdeaNotFound, ideaID)
+	}
+	if idea.Data.MergedInto != nil {
+		return fmt.Errorf("%w: %s", ErrIdeaMerged, *idea.Data.MergedInto)
+	}
+
+	return s.store.SetIdeaMilestone(ideaID, &id)
+}
+
+func (s *MilestoneService) UnassignIdea(id, ideaID uuid.UUID) error {
+	if result := s.store.GetMilestone(id); result.Err != nil {
+		return result.Err
+	}
+
+	idea := s.ideas.GetIdea(ideaID)
+	if idea.Err != nil || idea.Data.ID == uuid.Nil {
+		return fmt.Errorf("%w: %s", ErrIdeaNotFound, ideaID)
+	}
+	if idea.Data.MilestoneID == nil || *idea.Data.MilestoneID != id {
+		return ErrIdeaNotInMilestone
+	}
+
+	return s.store.SetIdeaMilestone(ideaID, nil)
+}
+
+// Calendar has an all-day event on the target date of every milestone, for
+// the iCalendar export. It is public, so progress counts public ideas only.
+func (s *MilestoneService) Calendar(state model.MilestoneState) utils.Result[model.Calendar] {
+	result := s.GetMilestones(state, model.Actor{})
+	if result.Err != nil {
+		return utils.Result[model.Calendar]{Err: result.Err}
+	}
+
+	calendar := model.Calendar{Name: s.cfg.Title + " milestones", Events: []model.CalendarEvent{}}
+	for _, milestone := range result.Data {
+		summary := milestone.Name
+		if milestone.State == model.MilestoneClosed {
+			summary += " (closed)"
+		}
+
+		description := fmt.Sprintf("%d of %d ideas published", milestone.Progress.Published, milestone.Progress.Ideas)
+		if milestone.Description != "" {
+			description = milestone.Description + "\n\n" + description
+		}
+
+		calendar.Events = append(calendar.Events, model.CalendarEvent{
+			UID:         "milestone-" + milestone.ID.String(),
+			Summary:     summary,
+			Description: description,
+			URL:         s.cfg.BaseURL + "/v1/milestones/" + milestone.ID.String(),
+			Date:        milestone.TargetDate,
+			Updated:     milestone.UpdatedAt,
+		})
+	}
+
+	return utils.Result[model.Calendar]{Data: calendar}
+}
+
+// milestoneProgress counts the given ideas per milestone
+func milestoneProgress(ideas []model.Idea) map[uuid.UUID]model.MilestoneProgress {
+	progress := make(map[uuid.UUID]model.MilestoneProgress)
+	for _, idea := range ideas {
+		if idea.MilestoneID == nil {
+			continue
+		}
+		p := progress[*idea.MilestoneID]
+		p.Ideas++
+		if idea.Status == model.Published {
+			p.Published++
+		}
+		progress[*idea.MilestoneID] = p
+	}
+
+	for id, p := range progress {
+		p.Share = float64(p.Published) / float64(p.Ideas)
+		progress[id] = p
+	}
+	return progress
+}
+
+func parseMilestoneDate(value string) (time.Time, error) {
+	date, err := time.Parse(model.MilestoneDateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: the target date must be YYYY-MM-DD", ErrInvalidMilestone)
+	}
+	return date, nil
+}
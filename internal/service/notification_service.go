@@ -0,0 +1,293 @@
+package service
+
+import (
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"test_project/test/internal/config"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 200
+	// Only this many @mentions of a comment are notified
+	maxMentions = 10
+)
+
+var ErrNotificationNotFound = storage.ErrNotificationNotFound
+
+// mentionPattern matches @username, usernames are limited to these characters
+// by registration and single sign-on
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([a-zA-Z0-9._-]{3,50})`)
+
+// NotificationService fills the users' inboxes from domain events: followers
+// hear about edits, status changes and comments, authors about replies and
+// users about mentions. Nobody hears about an idea they cannot see.
+type NotificationService struct {
+	store   storage.NotificationStorage
+	users   storage.UserStorage
+	follows storage.FollowStorage
+	ideas   storage.IdeaStorage
+	cfg     config.NotificationConfig
+}
+
+func NewNotificationService(store storage.NotificationStorage, users storage.UserStorage, follows storage.FollowStorage, ideas storage.IdeaStorage, cfg config.NotificationConfig) *NotificationService {
+	return &NotificationService{store, users, follows, ideas, cfg}
+}
+
+func (s *NotificationService) Subscribe(bus *EventBus) {
+	Subscribe(bus, "notifications", func(event model.OutboxEvent, e model.IdeaUpdated) error {
+		return s.notifyFollowers(event, model.Notification{
+			Type:      model.NotificationIdeaUpdated,
+			IdeaID:    e.Idea.ID,
+			IdeaTitle: e.Idea.Title,
+			ActorName: e.Actor.Username,
+		}, e.Actor.ID)
+	})
+	Subscribe(bus, "notifications", func(event model.OutboxEvent, e model.IdeaStatusChanged) error {
+		return s.notifyFollowers(event, model.Notification{
+			Type:      model.NotificationStatusChanged,
+			IdeaID:    e.Idea.ID,
+			IdeaTitle: e.Idea.Title,
+			ActorName: e.Actor.Username,
+			From:      e.From,
+			To:        e.To,
+		}, e.Actor.ID)
+	})
+	Subscribe(bus, "notifications", s.notifyComment)
+}
+
+// notifyComment tells the author of the parent comment about a reply, the
+// users mentioned in the body about the mention and the remaining followers
+// about the comment
+func (s *NotificationService) notifyComment(event model.OutboxEvent, e model.CommentCreated) error {
+	template := model.Notification{
+		IdeaID:    e.Comment.IdeaID,
+		IdeaTitle: e.IdeaTitle,
+		CommentID: &e.Comment.ID,
+		ActorName: e.Comment.AuthorName,
+	}
+	// Nobody is notified about their own comment, or twice about one comment
+	var addressed []uuid.UUID
+	if e.Comment.AuthorID != nil {
+		addressed = append(addressed, *e.Comment.AuthorID)
+	}
+
+	if e.Parent != nil && e.Parent.AuthorID != nil && !slices.Contains(addressed, *e.Parent.AuthorID) {
+		template.Type = model.NotificationReply
+		if err := s.deliver(event, template, []uuid.UUID{*e.Parent.AuthorID}); err != nil {
+			return err
+		}
+		addressed = append(addressed, *e.Parent.AuthorID)
+	}
+
+	var mentioned []uuid.UUID
+	for _, username := range parseMentions(e.Comment.Body) {
+		user, err := s.users.GetUserByUsername(username)
+		if err != nil || slices.Contains(addressed, user.ID) {
+			continue
+		}
+		mentioned = append(mentioned, user.ID)
+		addressed = append(addressed, user.ID)
+	}
+	template.Type = model.NotificationMention
+	if err := s.deliver(event, template, mentioned); err != nil {
+		return err
+	}
+
+	template.Type = model.NotificationComment
+	return s.notifyFollowers(event, template, addressed...)
+}
+
+// notifyFollowers notifies the followers of the idea except the given users
+func (s *NotificationService) notifyFollowers(event model.OutboxEvent, template model.Notification, except ...uuid.UUID) error {
+	followers, err := s.follows.GetFollowerIDs(template.IdeaID)
+	if err != nil {
+		return err
+	}
+
+	recipients := make([]uuid.UUID, 0, len(followers))
+	for _, id := range followers {
+		if !slices.Contains(except, id) {
+			recipients = append(recipients, id)
+		}
+	}
+
+	return s.deliver(event, template, recipients)
+}
+
+// GetNotifications returns the newest notifications of the user
+func (s *NotificationService) GetNotifications(userID uuid.UUID, unreadOnly bool, limit int) utils.Result[[]model.Notification] {
+	if limit <= 0 {
+		limit = defaultNotificationLimit
+	}
+	limit = min(limit, maxNotificationLimit)
+
+	result := s.store.GetNotifications(userID, unreadOnly, limit)
+	if result.Err == nil && result.Data == nil {
+		result.Data = []model.Notification{}
+	}
+	return result
+}
+
+func (s *NotificationService) CountUnread(userID uuid.UUID) utils.Result[model.UnreadCount] {
+	count, err := s.store.CountUnreadNotifications(userID)
+	if err != nil {
+		return utils.Result[model.UnreadCount]{Err: err}
+	}
+
+	return utils.Result[model.UnreadCount]{Data: model.UnreadCount{Unread: count}}
+}
+
+func (s *NotificationService) MarkRead(userID, id uuid.UUID) error {
+	return s.store.MarkNotificationRead(userID, id)
+}
+
+// MarkAllRead returns how many notifications were unread
+func (s *NotificationService) MarkAllRead(userID uuid.UUID) (int64, error) {
+	return s.store.MarkAllNotificationsRead(userID)
+}
+
+func (s *NotificationService) GetPreferences(userID uuid.UUID) utils.Result[model.NotificationPreferences] {
+	prefs, err := s.store.GetNotificationPreferences([]uuid.UUID{userID})
+	if err != nil {
+		return utils.Result[model.NotificationPreferences]{Err: err}
+	}
+
+	return utils.Result[model.NotificationPreferences]{Data: prefs[userID]}
+}
+
+// UpdatePreferences changes the given preferences, omitted ones are left as
+// they are
+func (s *NotificationService) UpdatePreferences(userID uuid.UUID, payload model.UpdateNotificationPreferencesPayload) utils.Result[model.NotificationPreferences] {
+	result := s.GetPreferences(userID)
+	if result.Err != nil {
+		return result
+	}
+
+	prefs := result.Data
+	setIfPresent(&prefs.StatusChanged, payload.StatusChanged)
+	setIfPresent(&prefs.IdeaUpdated, payload.IdeaUpdated)
+	setIfPresent(&prefs.Comments, payload.Comments)
+	setIfPresent(&prefs.Replies, payload.Replies)
+	setIfPresent(&prefs.Mentions, payload.Mentions)
+
+	if err := s.store.SaveNotificationPreferences(prefs); err != nil {
+		return utils.Result[model.NotificationPreferences]{Err: err}
+	}
+
+	return utils.Result[model.NotificationPreferences]{Data: prefs}
+}
+
+// Prune applies the retention policy once
+func (s *NotificationService) Prune() (int64, error) {
+	now := time.Now()
+	return s.store.PruneNotifications(now.Add(-s.cfg.Retention), now.Add(-s.cfg.ReadRetention))
+}
+
+// StartPruning prunes now and then every PruneInterval in the background
+func (s *NotificationService) StartPruning() {
+	go func() {
+		ticker := time.NewTicker(s.cfg.PruneInterval)
+		defer ticker.Stop()
+
+		for {
+			if n, err := s.Prune(); err != nil {
+				log.Printf("failed to prune notifications: %v", err)
+			} else if n > 0 {
+				log.Printf("pruned %d notification(s)", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// deliver stores a copy of the template for every recipient who wants this
+// type and can see the idea. The IDs derive from the event, so a redelivered
+// event does not notify anyone twice.
+func (s *NotificationService) deliver(event model.OutboxEvent, template model.Notification, recipients []uuid.UUID) error {
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	recipients = s.canSee(template.IdeaID, recipients)
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	prefs, err := s.store.GetNotificationPreferences(recipients)
+	if err != nil {
+		return err
+	}
+
+	notifications := make([]model.Notification, 0, len(recipients))
+	for _, userID := range recipients {
+		if !prefs[userID].Wants(template.Type) {
+			continue
+		}
+		notification := template
+		notification.ID = uuid.NewSHA1(event.ID, []byte(string(template.Type)+userID.String()))
+		notification.UserID = userID
+		notification.CreatedAt = event.OccurredAt
+		notifications = append(notifications, notification)
+	}
+
+	return s.store.CreateNotifications(notifications)
+}
+
+// canSee keeps the recipients who can see the idea as it is now. Ideas and
+// users deleted in the meantime are skipped.
+func (s *NotificationService) canSee(ideaID uuid.UUID, recipients []uuid.UUID) []uuid.UUID {
+	idea := s.ideas.GetIdea(ideaID)
+	if idea.Err != nil || idea.Data.ID == uuid.Nil {
+		log.Printf("not notifying about idea %s: %v", ideaID, idea.Err)
+		return nil
+	}
+	if idea.Data.VisibleTo(model.Actor{}) {
+		return recipients
+	}
+
+	visible := make([]uuid.UUID, 0, len(recipients))
+	for _, id := range recipients {
+		user, err := s.users.GetUserByID(id)
+		if err != nil {
+			continue
+		}
+		if idea.Data.VisibleTo(model.Actor{ID: user.ID, Username: user.Username, Role: user.Role}) {
+			visible = append(visible, id)
+		}
+	}
+	return visible
+}
+
+// parseMentions returns the distinct usernames mentioned in the text
+func parseMentions(text string) []string {
+	var usernames []string
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		username := strings.TrimRight(match[1], ".-")
+		key := strings.ToLower(username)
+		if len(username) < 3 || seen[key] {
+			continue
+		}
+		seen[key] = true
+		usernames = append(usernames, username)
+		if len(usernames) == maxMentions {
+			break
+		}
+	}
+	return usernames
+}
+
+func setIfPresent(field *bool, value *bool) {
+	if value != nil {
+		*field = *value
+	}
+}
@@ -0,0 +1,616 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"test_project/test/internal/config"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrOIDCDisabled      = errors.New("single sign-on is not configured")
+	ErrOIDCInvalidState  = errors.New("invalid or expired sign-on state")
+	ErrOIDCAccountExists = errors.New("an account with this email already exists, sign in and link single sign-on from it")
+	ErrOIDCIdentityTaken = errors.New("this sign-on identity is linked to another account")
+)
+
+// How often we are willing to refetch the JWKS when a token has an unknown kid
+const jwksRefreshInterval = time.Minute
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// OIDCAuthRequest is what the handler needs to start a login: where to send
+// the browser, and the state to keep in a cookie until the callback
+type OIDCAuthRequest struct {
+	RedirectURL string
+	StateToken  string
+}
+
+// OIDCService implements the authorization code flow with PKCE. Discovery and
+// the JWKS are fetched lazily so the provider does not need to be up at boot.
+type OIDCService struct {
+	store       storage.UserStorage
+	userService *UserService
+	cfg         config.OIDCConfig
+	client      *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]any
+	keysFetched time.Time
+}
+
+func NewOIDCService(store storage.UserStorage, userService *UserService, cfg config.OIDCConfig, client *http.Client) *OIDCService {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OIDCService{
+		store:       store,
+		userService: userService,
+		cfg:         cfg,
+		client:      client,
+	}
+}
+
+func (s *OIDCService) Enabled() bool {
+	return s.cfg.Enabled()
+}
+
+// BeginLogin builds the authorization URL. state, nonce and the PKCE verifier
+// travel in a signed token so the callback can be served by any instance.
+func (s *OIDCService) BeginLogin() utils.Result[OIDCAuthRequest] {
+	return s.beginAuth(uuid.Nil)
+}
+
+// BeginLink starts the same flow for a signed in user, the callback then
+// links the provider identity to their account instead of looking one up
+func (s *OIDCService) BeginLink(actor model.Actor) utils.Result[OIDCAuthRequest] {
+	if actor.ID == uuid.Nil {
+		return utils.Result[OIDCAuthRequest]{Err: ErrInvalidCredentials}
+	}
+	return s.beginAuth(actor.ID)
+}
+
+func (s *OIDCService) beginAuth(linkUser uuid.UUID) utils.Result[OIDCAuthRequest] {
+	if !s.Enabled() {
+		return utils.Result[OIDCAuthRequest]{Err: ErrOIDCDisabled}
+	}
+
+	disc, err := s.getDiscovery()
+	if err != nil {
+		return utils.Result[OIDCAuthRequest]{Err: err}
+	}
+
+	state, err := randomURLString(24)
+	if err != nil {
+		return utils.Result[OIDCAuthRequest]{Err: err}
+	}
+	nonce, err := randomURLString(24)
+	if err != nil {
+		return utils.Result[OIDCAuthRequest]{Err: err}
+	}
+	verifier, err := randomURLString(48)
+	if err != nil {
+		return utils.Result[OIDCAuthRequest]{Err: err}
+	}
+
+	stateClaims := jwt.MapClaims{
+		"scope":    utils.ScopeOIDCState,
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+	}
+	if linkUser != uuid.Nil {
+		stateClaims["user_id"] = linkUser.String()
+	}
+
+	stateToken, err := utils.GenerateToken(stateClaims, s.cfg.StateTTL)
+	if err != nil {
+		return utils.Result[OIDCAuthRequest]{Err: fmt.Errorf("failed to sign state: %v", err)}
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", s.cfg.ClientID)
+	params.Set("redirect_uri", s.cfg.RedirectURL)
+	params.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	authURL := disc.AuthorizationEndpoint
+	if strings.Contains(authURL, "?") {
+		authURL += "&" + params.Encode()
+	} else {
+		authURL += "?" + params.Encode()
+	}
+
+	return utils.Result[OIDCAuthRequest]{Data: OIDCAuthRequest{RedirectURL: authURL, StateToken: stateToken}}
+}
+
+// CompleteLogin validates the callback, exchanges the code, verifies the ID
+// token and returns a session for the matching, linked or newly created user.
+// When the flow was started with BeginLink the identity is linked to that
+// user first.
+func (s *OIDCService) CompleteLogin(stateToken, state, code string, info model.ClientInfo) utils.Result[model.LoginResponse] {
+	if !s.Enabled() {
+		return utils.Result[model.LoginResponse]{Err: ErrOIDCDisabled}
+	}
+
+	claims, err := utils.ParseToken(stateToken)
+	if err != nil || utils.ClaimScope(claims) != utils.ScopeOIDCState {
+		return utils.Result[model.LoginResponse]{Err: ErrOIDCInvalidState}
+	}
+
+	expectedState, _ := claims["state"].(string)
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["verifier"].(string)
+	if expectedState == "" || state != expectedState || code == "" {
+		return utils.Result[model.LoginResponse]{Err: ErrOIDCInvalidState}
+	}
+
+	tokens, err := s.exchangeCode(code, verifier)
+	if err != nil {
+		return utils.Result[model.LoginResponse]{Err: err}
+	}
+
+	idClaims, err := s.verifyIDToken(tokens.IDToken, nonce)
+	if err != nil {
+		return utils.Result[model.LoginResponse]{Err: err}
+	}
+
+	var user model.User
+	if linkUser, claimErr := utils.ClaimUserID(claims); claimErr == nil {
+		user, err = s.linkUser(linkUser, idClaims)
+	} else {
+		user, err = s.resolveUser(idClaims)
+	}
+	if err != nil {
+		return utils.Result[model.LoginResponse]{Err: err}
+	}
+
+	return s.userService.StartSession(user, info)
+}
+
+func (s *OIDCService) getDiscovery() (*oidcDiscovery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.discovery != nil {
+		return s.discovery, nil
+	}
+
+	wellKnown := strings.TrimSuffix(s.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	resp, err := s.client.Get(wellKnown)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch provider configuration: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch provider configuration: status %d", resp.StatusCode)
+	}
+
+	var disc oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&disc); err != nil {
+		return nil, fmt.Errorf("failed to decode provider configuration: %v", err)
+	}
+
+	if strings.TrimSuffix(disc.Issuer, "/") != strings.TrimSuffix(s.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("provider issuer %q does not match configured issuer", disc.Issuer)
+	}
+
+	if disc.AuthorizationEndpoint == "" || disc.TokenEndpoint == "" || disc.JWKSURI == "" {
+		return nil, errors.New("provider configuration is missing required endpoints")
+	}
+
+	s.discovery = &disc
+	return s.discovery, nil
+}
+
+func (s *OIDCService) exchangeCode(code, verifier string) (*oidcTokenResponse, error) {
+	disc, err := s.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", s.cfg.RedirectURL)
+	form.Set("client_id", s.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequest(http.MethodPost, disc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if s.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(s.cfg.ClientSecret))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to exchange code: status %d", resp.StatusCode)
+	}
+
+	var tokens oidcTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %v", err)
+	}
+
+	if tokens.IDToken == "" {
+		return nil, errors.New("token response did not include an id_token")
+	}
+
+	return &tokens, nil
+}
+
+func (s *OIDCService) verifyIDToken(raw, nonce string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(raw, func(token *jwt.Token) (any, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA, *jwt.SigningMethodRSAPSS:
+		default:
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return s.getKey(kid)
+	})
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("invalid id token: %v", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid id token claims")
+	}
+
+	if !claims.VerifyIssuer(strings.TrimSuffix(s.cfg.Issuer, "/"), true) &&
+		!claims.VerifyIssuer(s.cfg.Issuer, true) {
+		return nil, errors.New("id token issuer mismatch")
+	}
+
+	if !claims.VerifyAudience(s.cfg.ClientID, true) {
+		return nil, errors.New("id token audience mismatch")
+	}
+
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, errors.New("id token has no subject")
+	}
+
+	return claims, nil
+}
+
+// getKey returns the verification key for kid, refetching the JWKS when the
+// provider has rotated keys
+func (s *OIDCService) getKey(kid string) (any, error) {
+	s.mu.Lock()
+	key, ok := s.lookupKey(kid)
+	stale := time.Since(s.keysFetched) > jwksRefreshInterval
+	s.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	if !stale {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if err := s.fetchKeys(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey must be called with s.mu held. A token without kid is accepted
+// only when the provider publishes a single key.
+func (s *OIDCService) lookupKey(kid string) (any, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (s *OIDCService) fetchKeys() error {
+	disc, err := s.getDiscovery()
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Get(disc.JWKSURI)
+	if err != nil {
+		return fmt.Errorf("failed to fetch jwks: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch jwks: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode jwks: %v", err)
+	}
+
+	keys := make(map[string]any)
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip keys we don't understand instead of failing the whole set
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.keysFetched = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// resolveUser finds the local account for the identity: an existing link or
+// a new just-in-time account. An account with the same email is never linked
+// here, owning the address at the provider doesn't prove owning the account,
+// its owner links it with BeginLink instead.
+func (s *OIDCService) resolveUser(claims jwt.MapClaims) (model.User, error) {
+	issuer, _ := claims["iss"].(string)
+	subject, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	emailVerified := claimBool(claims["email_verified"])
+
+	if user, err := s.store.GetUserByIdentity(issuer, subject); err == nil {
+		return user, nil
+	}
+
+	if email == "" || !emailVerified {
+		return model.User{}, errors.New("identity provider did not return a verified email")
+	}
+
+	if _, err := s.store.GetUserByEmail(email); err == nil {
+		return model.User{}, ErrOIDCAccountExists
+	}
+
+	user, err := s.createUser(claims, email)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if err := s.linkIdentity(user.ID, issuer, subject, email); err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+// linkUser links the identity to the signed in user who started the flow
+func (s *OIDCService) linkUser(userID uuid.UUID, claims jwt.MapClaims) (model.User, error) {
+	issuer, _ := claims["iss"].(string)
+	subject, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return model.User{}, ErrOIDCInvalidState
+	}
+
+	if linked, err := s.store.GetUserByIdentity(issuer, subject); err == nil {
+		if linked.ID != user.ID {
+			return model.User{}, ErrOIDCIdentityTaken
+		}
+		return user, nil
+	}
+
+	if err := s.linkIdentity(user.ID, issuer, subject, email); err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+func (s *OIDCService) linkIdentity(userID uuid.UUID, issuer, subject, email string) error {
+	return s.store.CreateIdentity(model.UserIdentity{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Issuer:    issuer,
+		Subject:   subject,
+		Email:     email,
+		CreatedAt: time.Now(),
+	})
+}
+
+var usernameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// createUser is a registration like any other and follows REGISTRATION_MODE.
+// There is no way to hand over an invite code here, so invite-only mode only
+// lets in identities that are already linked.
+func (s *OIDCService) createUser(claims jwt.MapClaims, email string) (model.User, error) {
+	if err := s.userService.checkRegistrationPolicy(model.RegisterRequest{Email: email}); err != nil {
+		return model.User{}, err
+	}
+
+	base, _ := claims["preferred_username"].(string)
+	if base == "" {
+		base = strings.SplitN(email, "@", 2)[0]
+	}
+	base = usernameUnsafe.ReplaceAllString(base, "")
+	if len(base) < 3 {
+		base = "user"
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	username := base
+	for i := 1; ; i++ {
+		if _, err := s.store.GetUserByUsername(username); err != nil {
+			break
+		}
+		if i > 50 {
+			return model.User{}, errors.New("could not find a free username")
+		}
+		username = fmt.Sprintf("%s%d", base, i)
+	}
+
+	// SSO users have no usable password, the hash is of a value nobody knows
+	random, err := randomURLString(32)
+	if err != nil {
+		return model.User{}, err
+	}
+	hash, err := s.userService.passwords.Hash(random)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	user := model.User{
+		ID:       uuid.New(),
+		Username: username,
+		Password: hash,
+		Email:    email,
+		Role:     model.RoleUser,
+	}
+
+	event, err := Event(model.UserRegistered{UserID: user.ID, Username: user.Username})
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if err := s.store.CreateUser(user, event); err != nil {
+		return model.User{}, err
+	}
+	s.userService.events.Wake()
+
+	return s.store.GetUserByUsername(username)
+}
+
+func claimBool(v any) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true"
+	default:
+		return false
+	}
+}
+
+func randomURLString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,600 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"test_project/test/internal/config"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+const (
+	testClientID     = "ideas-app"
+	testClientSecret = "s3cret"
+)
+
+// memUserStore implements the user storage the OIDC flow touches
+type memUserStore struct {
+	storage.UserStorage
+	users      map[uuid.UUID]model.User
+	identities []model.UserIdentity
+}
+
+func newMemUserStore() *memUserStore {
+	return &memUserStore{users: map[uuid.UUID]model.User{}}
+}
+
+func (m *memUserStore) CreateUser(user model.User, events ...model.OutboxEvent) error {
+	m.users[user.ID] = user
+	return nil
+}
+
+func (m *memUserStore) GetUserByID(id uuid.UUID) (model.User, error) {
+	user, ok := m.users[id]
+	if !ok {
+		return model.User{}, errors.New("user not found")
+	}
+	return user, nil
+}
+
+func (m *memUserStore) GetUserByUsername(username string) (model.User, error) {
+	for _, user := range m.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return model.User{}, errors.New("user not found")
+}
+
+func (m *memUserStore) GetUserByEmail(email string) (model.User, error) {
+	for _, user := range m.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return model.User{}, errors.New("user not found")
+}
+
+func (m *memUserStore) GetUserByIdentity(issuer, subject string) (model.User, error) {
+	for _, identity := range m.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return m.users[identity.UserID], nil
+		}
+	}
+	return model.User{}, errors.New("user not found")
+}
+
+func (m *memUserStore) CreateIdentity(identity model.UserIdentity) error {
+	m.identities = append(m.identities, identity)
+	return nil
+}
+
+type memSessionStore struct {
+	storage.SessionStorage
+	sessions []model.Session
+}
+
+func (m *memSessionStore) CreateSession(session model.Session) error {
+	m.sessions = append(m.sessions, session)
+	return nil
+}
+
+// testIdP is an OpenID provider serving discovery, a JWKS and a token
+// endpoint that checks the PKCE verifier against the challenge it was sent
+type testIdP struct {
+	*httptest.Server
+	t *testing.T
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PrivateKey
+	kid       string
+	signer    *rsa.PrivateKey
+	subject   string
+	email     string
+	challenge string
+	nonce     string
+	code      string
+	// mutate lets a test tamper with the ID token claims before signing
+	mutate       func(jwt.MapClaims)
+	tokenCalls   int
+	jwksCalls    int
+	lastVerifier string
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	idp := &testIdP{t: t, keys: map[string]*rsa.PrivateKey{}, subject: "idp-user-1", email: "ada@example.com"}
+	idp.rotateKey("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	return key
+}
+
+// rotateKey replaces the published keys with a new one used for signing
+func (idp *testIdP) rotateKey(kid string) {
+	key := newRSAKey(idp.t)
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.keys = map[string]*rsa.PrivateKey{kid: key}
+	idp.kid = kid
+	idp.signer = key
+}
+
+func (idp *testIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 idp.URL,
+		"authorization_endpoint": idp.URL + "/authorize",
+		"token_endpoint":         idp.URL + "/token",
+		"jwks_uri":               idp.URL + "/jwks",
+	})
+}
+
+func (idp *testIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.jwksCalls++
+
+	keys := []map[string]string{}
+	for kid, key := range idp.keys {
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+}
+
+func (idp *testIdP) token(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.tokenCalls++
+
+	if user, pass, _ := r.BasicAuth(); user != testClientID || pass != testClientSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("code") != idp.code {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	idp.lastVerifier = r.PostForm.Get("code_verifier")
+	sum := sha256.Sum256([]byte(idp.lastVerifier))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != idp.challenge {
+		http.Error(w, `{"error":"invalid_grant","error_description":"PKCE verification failed"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":            idp.URL,
+		"aud":            testClientID,
+		"sub":            idp.subject,
+		"email":          idp.email,
+		"email_verified": true,
+		"nonce":          idp.nonce,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	}
+	if idp.mutate != nil {
+		idp.mutate(claims)
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = idp.kid
+	signed, err := idToken.SignedString(idp.signer)
+	if err != nil {
+		idp.t.Errorf("signing id token: %v", err)
+		http.Error(w, "signing failed", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "id_token": signed, "token_type": "Bearer"})
+}
+
+func newTestOIDCService(t *testing.T, idp *testIdP, store *memUserStore) *OIDCService {
+	t.Helper()
+	passwords, err := NewPasswordService(config.PasswordConfig{Algorithm: AlgorithmBcrypt, BcryptCost: 4})
+	if err != nil {
+		t.Fatalf("NewPasswordService: %v", err)
+	}
+	users := NewUserService(store, NewSessionService(&memSessionStore{}), passwords, NewEventBus(nil, config.EventConfig{}), config.AuthConfig{TokenTTL: time.Hour})
+
+	return NewOIDCService(store, users, config.OIDCConfig{
+		Issuer:       idp.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  "http://localhost:8080/v1/auth/oidc/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+		StateTTL:     time.Minute,
+	}, idp.Client())
+}
+
+// authorize starts a login and plays the provider's authorize page: it
+// remembers the PKCE challenge and nonce and hands out a code. It returns the
+// state cookie and the callback's state and code.
+func authorize(t *testing.T, s *OIDCService, idp *testIdP) (string, string, string) {
+	t.Helper()
+	result := s.BeginLogin()
+	if result.Err != nil {
+		t.Fatalf("BeginLogin: %v", result.Err)
+	}
+
+	redirect, err := url.Parse(result.Data.RedirectURL)
+	if err != nil {
+		t.Fatalf("redirect URL: %v", err)
+	}
+	params := redirect.Query()
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.challenge = params.Get("code_challenge")
+	idp.nonce = params.Get("nonce")
+	idp.code = uuid.NewString()
+
+	return result.Data.StateToken, params.Get("state"), idp.code
+}
+
+func TestOIDCBeginLoginRedirectsWithPKCE(t *testing.T) {
+	idp := newTestIdP(t)
+	s := newTestOIDCService(t, idp, newMemUserStore())
+
+	result := s.BeginLogin()
+	if result.Err != nil {
+		t.Fatalf("BeginLogin: %v", result.Err)
+	}
+
+	redirect, err := url.Parse(result.Data.RedirectURL)
+	if err != nil {
+		t.Fatalf("redirect URL: %v", err)
+	}
+	if got := redirect.Scheme + "://" + redirect.Host + redirect.Path; got != idp.URL+"/authorize" {
+		t.Errorf("redirect to %q, want the authorization endpoint", got)
+	}
+
+	params := redirect.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          "http://localhost:8080/v1/auth/oidc/callback",
+		"scope":                 "openid email profile",
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if params.Get(name) != value {
+			t.Errorf("%s = %q, want %q", name, params.Get(name), value)
+		}
+	}
+	for _, name := range []string{"state", "nonce", "code_challenge"} {
+		if params.Get(name) == "" {
+			t.Errorf("%s is missing", name)
+		}
+	}
+
+	// The verifier only travels in the signed state, never in the URL
+	if strings.Contains(result.Data.RedirectURL, "verifier") {
+		t.Error("redirect URL contains the PKCE verifier")
+	}
+	if second := s.BeginLogin(); second.Err != nil || second.Data.RedirectURL == result.Data.RedirectURL {
+		t.Error("two logins share state, nonce and challenge")
+	}
+}
+
+func TestOIDCCompleteLoginCreatesUser(t *testing.T) {
+	idp := newTestIdP(t)
+	store := newMemUserStore()
+	s := newTestOIDCService(t, idp, store)
+
+	stateToken, state, code := authorize(t, s, idp)
+	result := s.CompleteLogin(stateToken, state, code, model.ClientInfo{})
+	if result.Err != nil {
+		t.Fatalf("CompleteLogin: %v", result.Err)
+	}
+	if result.Data.Token == "" {
+		t.Error("no session token issued")
+	}
+
+	// The IdP only answers when the verifier matches the challenge, check it
+	// saw one anyway
+	if idp.lastVerifier == "" {
+		t.Error("token request had no code_verifier")
+	}
+
+	if len(store.users) != 1 || len(store.identities) != 1 {
+		t.Fatalf("got %d users and %d identities, want 1 each", len(store.users), len(store.identities))
+	}
+	identity := store.identities[0]
+	user := store.users[identity.UserID]
+	if identity.Issuer != idp.URL || identity.Subject != idp.subject {
+		t.Errorf("identity = %+v, want linked to %s at %s", identity, idp.subject, idp.URL)
+	}
+	if user.Email != idp.email || user.Username != "ada" || user.Role != model.RoleUser {
+		t.Errorf("user = %+v, want user ada with the provider email", user)
+	}
+
+	// Logging in again finds the same user through the identity
+	stateToken, state, code = authorize(t, s, idp)
+	if result := s.CompleteLogin(stateToken, state, code, model.ClientInfo{}); result.Err != nil {
+		t.Fatalf("second CompleteLogin: %v", result.Err)
+	}
+	if len(store.users) != 1 || len(store.identities) != 1 {
+		t.Errorf("got %d users and %d identities after logging in again, want 1 each", len(store.users), len(store.identities))
+	}
+}
+
+func TestOIDCCompleteLoginRejectsBadState(t *testing.T) {
+	idp := newTestIdP(t)
+	s := newTestOIDCService(t, idp, newMemUserStore())
+
+	stateToken, state, code := authorize(t, s, idp)
+	other, _, _ := authorize(t, s, idp)
+
+	tests := []struct {
+		name                    string
+		stateToken, state, code string
+	}{
+		{"state differs", stateToken, "forged", code},
+		{"state of another login", other, state, code},
+		{"unsigned state", "not-a-token", state, code},
+		{"no code", stateToken, state, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := s.CompleteLogin(tt.stateToken, tt.state, tt.code, model.ClientInfo{}); !errors.Is(result.Err, ErrOIDCInvalidState) {
+				t.Errorf("CompleteLogin: %v, want %v", result.Err, ErrOIDCInvalidState)
+			}
+		})
+	}
+
+	if idp.tokenCalls != 0 {
+		t.Errorf("token endpoint called %d times, want never", idp.tokenCalls)
+	}
+}
+
+func TestOIDCCompleteLoginRejectsWrongVerifier(t *testing.T) {
+	idp := newTestIdP(t)
+	store := newMemUserStore()
+	s := newTestOIDCService(t, idp, store)
+
+	stateToken, state, code := authorize(t, s, idp)
+	// A code intercepted for another login can't be redeemed with this
+	// login's verifier
+	authorize(t, s, idp)
+	idp.code = code
+
+	if result := s.CompleteLogin(stateToken, state, code, model.ClientInfo{}); result.Err == nil || !strings.Contains(result.Err.Error(), "status 400") {
+		t.Fatalf("CompleteLogin: %v, want the provider to refuse the verifier", result.Err)
+	}
+	if len(store.users) != 0 {
+		t.Errorf("got %d users, want none", len(store.users))
+	}
+}
+
+func TestOIDCCompleteLoginRejectsInvalidIDTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(jwt.MapClaims)
+		resign  bool
+		wantErr string
+	}{
+		{"nonce of another login", func(c jwt.MapClaims) { c["nonce"] = "replayed" }, false, "nonce"},
+		{"no nonce", func(c jwt.MapClaims) { delete(c, "nonce") }, false, "nonce"},
+		{"other audience", func(c jwt.MapClaims) { c["aud"] = "another-app" }, false, "audience"},
+		{"other issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, false, "issuer"},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, false, "invalid id token"},
+		{"no subject", func(c jwt.MapClaims) { delete(c, "sub") }, false, "subject"},
+		{"signed with an unpublished key", nil, true, "invalid id token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newTestIdP(t)
+			store := newMemUserStore()
+			s := newTestOIDCService(t, idp, store)
+
+			idp.mutate = tt.mutate
+			if tt.resign {
+				idp.signer = newRSAKey(t)
+			}
+
+			stateToken, state, code := authorize(t, s, idp)
+			result := s.CompleteLogin(stateToken, state, code, model.ClientInfo{})
+			if result.Err == nil || !strings.Contains(result.Err.Error(), tt.wantErr) {
+				t.Fatalf("CompleteLogin: %v, want an error about %q", result.Err, tt.wantErr)
+			}
+			if len(store.users) != 0 {
+				t.Errorf("got %d users, want none", len(store.users))
+			}
+		})
+	}
+}
+
+func TestOIDCRefetchesRotatedKeys(t *testing.T) {
+	idp := newTestIdP(t)
+	s := newTestOIDCService(t, idp, newMemUserStore())
+
+	login := func() error {
+		stateToken, state, code := authorize(t, s, idp)
+		return s.CompleteLogin(stateToken, state, code, model.ClientInfo{}).Err
+	}
+
+	if err := login(); err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if err := login(); err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if idp.jwksCalls != 1 {
+		t.Errorf("jwks fetched %d times, want once", idp.jwksCalls)
+	}
+
+	// Right after a fetch an unknown kid doesn't make us hit the provider again
+	idp.rotateKey("key-2")
+	if err := login(); err == nil || !strings.Contains(err.Error(), "unknown signing key") {
+		t.Fatalf("login with a fresh key set: %v, want unknown signing key", err)
+	}
+	if idp.jwksCalls != 1 {
+		t.Errorf("jwks fetched %d times, want once", idp.jwksCalls)
+	}
+
+	s.mu.Lock()
+	s.keysFetched = time.Now().Add(-2 * jwksRefreshInterval)
+	s.mu.Unlock()
+
+	if err := login(); err != nil {
+		t.Fatalf("login after the rotation: %v", err)
+	}
+	if idp.jwksCalls != 2 {
+		t.Errorf("jwks fetched %d times, want twice", idp.jwksCalls)
+	}
+}
+
+func TestOIDCRefusesExistingEmail(t *testing.T) {
+	idp := newTestIdP(t)
+	store := newMemUserStore()
+	s := newTestOIDCService(t, idp, store)
+
+	existing := model.User{ID: uuid.New(), Username: "ada", Email: "ADA@example.com", Role: model.RoleUser}
+	store.users[existing.ID] = existing
+
+	stateToken, state, code := authorize(t, s, idp)
+	if result := s.CompleteLogin(stateToken, state, code, model.ClientInfo{}); !errors.Is(result.Err, ErrOIDCAccountExists) {
+		t.Fatalf("CompleteLogin: %v, want %v", result.Err, ErrOIDCAccountExists)
+	}
+	if len(store.users) != 1 || len(store.identities) != 0 {
+		t.Errorf("got %d users and %d identities, want the account untouched", len(store.users), len(store.identities))
+	}
+}
+
+func TestOIDCLinksSignedInUser(t *testing.T) {
+	idp := newTestIdP(t)
+	store := newMemUserStore()
+	s := newTestOIDCService(t, idp, store)
+
+	// The provider's email doesn't have to match the account
+	existing := model.User{ID: uuid.New(), Username: "ada", Email: "ada@home.example", Role: model.RoleUser}
+	store.users[existing.ID] = existing
+
+	link := s.BeginLink(model.Actor{ID: existing.ID, Username: existing.Username, Role: existing.Role})
+	if link.Err != nil {
+		t.Fatalf("BeginLink: %v", link.Err)
+	}
+	redirect, err := url.Parse(link.Data.RedirectURL)
+	if err != nil {
+		t.Fatalf("redirect URL: %v", err)
+	}
+	idp.challenge = redirect.Query().Get("code_challenge")
+	idp.nonce = redirect.Query().Get("nonce")
+	idp.code = "link-code"
+
+	result := s.CompleteLogin(link.Data.StateToken, redirect.Query().Get("state"), idp.code, model.ClientInfo{})
+	if result.Err != nil {
+		t.Fatalf("CompleteLogin: %v", result.Err)
+	}
+	if len(store.users) != 1 || len(store.identities) != 1 || store.identities[0].UserID != existing.ID {
+		t.Fatalf("identities = %+v, want one linked to the existing user", store.identities)
+	}
+
+	// Later logins find the account through the identity
+	stateToken, state, code := authorize(t, s, idp)
+	if result := s.CompleteLogin(stateToken, state, code, model.ClientInfo{}); result.Err != nil {
+		t.Fatalf("login after linking: %v", result.Err)
+	}
+	if len(store.users) != 1 || len(store.identities) != 1 {
+		t.Errorf("got %d users and %d identities, want 1 each", len(store.users), len(store.identities))
+	}
+
+	// Nobody else can take the identity over
+	other := model.User{ID: uuid.New(), Username: "bob", Email: "bob@example.com", Role: model.RoleUser}
+	store.users[other.ID] = other
+	link = s.BeginLink(model.Actor{ID: other.ID, Username: other.Username, Role: other.Role})
+	if link.Err != nil {
+		t.Fatalf("BeginLink: %v", link.Err)
+	}
+	redirect, _ = url.Parse(link.Data.RedirectURL)
+	idp.challenge = redirect.Query().Get("code_challenge")
+	idp.nonce = redirect.Query().Get("nonce")
+	if result := s.CompleteLogin(link.Data.StateToken, redirect.Query().Get("state"), idp.code, model.ClientInfo{}); !errors.Is(result.Err, ErrOIDCIdentityTaken) {
+		t.Errorf("linking a taken identity: %v, want %v", result.Err, ErrOIDCIdentityTaken)
+	}
+	if len(store.identities) != 1 || store.identities[0].UserID != existing.ID {
+		t.Errorf("identities = %+v, want still linked to the first user", store.identities)
+	}
+}
+
+func TestOIDCLinkNeedsSignedInUser(t *testing.T) {
+	idp := newTestIdP(t)
+	s := newTestOIDCService(t, idp, newMemUserStore())
+
+	if result := s.BeginLink(model.Actor{}); !errors.Is(result.Err, ErrInvalidCredentials) {
+		t.Errorf("BeginLink without a user: %v, want %v", result.Err, ErrInvalidCredentials)
+	}
+}
+
+func TestOIDCFollowsRegistrationMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    model.RegistrationMode
+		domains []string
+		linked  bool
+		wantErr error
+	}{
+		{"invite-only refuses new accounts", model.RegistrationInviteOnly, nil, false, ErrRegistrationClosed},
+		{"invite-only lets linked identities in", model.RegistrationInviteOnly, nil, true, nil},
+		{"domain allowed", model.RegistrationDomain, []string{"example.com"}, false, nil},
+		{"domain not allowed", model.RegistrationDomain, []string{"corp.example"}, false, ErrEmailDomain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newTestIdP(t)
+			store := newMemUserStore()
+			s := newTestOIDCService(t, idp, store)
+			s.userService.cfg.RegistrationMode = tt.mode
+			s.userService.cfg.AllowedEmailDomains = tt.domains
+
+			if tt.linked {
+				user := model.User{ID: uuid.New(), Username: "ada", Email: "ada@home.example", Role: model.RoleUser}
+				store.users[user.ID] = user
+				store.identities = append(store.identities, model.UserIdentity{ID: uuid.New(), UserID: user.ID, Issuer: idp.URL, Subject: idp.subject})
+			}
+
+			stateToken, state, code := authorize(t, s, idp)
+			result := s.CompleteLogin(stateToken, state, code, model.ClientInfo{})
+			if !errors.Is(result.Err, tt.wantErr) {
+				t.Fatalf("CompleteLogin: %v, want %v", result.Err, tt.wantErr)
+			}
+			if tt.wantErr != nil && len(store.users) != 0 {
+				t.Errorf("got %d users, want none created", len(store.users))
+			}
+		})
+	}
+}
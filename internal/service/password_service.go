@@ -0,0 +1,239 @@
+package service
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"test_project/test/internal/config"
+	"unicode"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// PasswordService owns the password policy and hashing. New hashes use the
+// configured algorithm, Verify still accepts every format we ever stored and
+// tells the caller when a hash should be upgraded.
+type PasswordService struct {
+	cfg      config.PasswordConfig
+	breached map[string]struct{}
+}
+
+func NewPasswordService(cfg config.PasswordConfig) (*PasswordService, error) {
+	if cfg.Algorithm != AlgorithmArgon2id && cfg.Algorithm != AlgorithmBcrypt {
+		return nil, fmt.Errorf("unknown password hash algorithm %q", cfg.Algorithm)
+	}
+
+	s := &PasswordService{cfg: cfg, breached: map[string]struct{}{}}
+	if cfg.BreachedList != "" {
+		if err := s.loadBreachedList(cfg.BreachedList); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// loadBreachedList reads one entry per line. Entries are either plain
+// passwords or SHA-1 hex digests, the format used by the Pwned Passwords
+// downloads (a ":count" suffix is ignored).
+func (s *PasswordService) loadBreachedList(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open breached password list: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if hash, _, _ := strings.Cut(line, ":"); isSHA1Hex(hash) {
+			s.breached[strings.ToLower(hash)] = struct{}{}
+			continue
+		}
+		s.breached[sha1Hex(line)] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read breached password list: %v", err)
+	}
+
+	return nil
+}
+
+// Validate checks the password against the policy. The returned error wraps
+// ErrWeakPassword and lists every rule that failed.
+func (s *PasswordService) Validate(password, username, email string) error {
+	var problems []string
+
+	length := len([]rune(password))
+	if length < s.cfg.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", s.cfg.MinLength))
+	}
+	if s.cfg.MaxLength > 0 && length > s.cfg.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters", s.cfg.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if s.cfg.RequireUpper && !upper {
+		problems = append(problems, "must contain an uppercase letter")
+	}
+	if s.cfg.RequireLower && !lower {
+		problems = append(problems, "must contain a lowercase letter")
+	}
+	if s.cfg.RequireDigit && !digit {
+		problems = append(problems, "must contain a digit")
+	}
+	if s.cfg.RequireSymbol && !symbol {
+		problems = append(problems, "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	if username != "" && len(username) >= 3 && strings.Contains(lowered, strings.ToLower(username)) {
+		problems = append(problems, "must not contain the username")
+	}
+	if local, _, _ := strings.Cut(email, "@"); len(local) >= 3 && strings.Contains(lowered, strings.ToLower(local)) {
+		problems = append(problems, "must not contain the email address")
+	}
+
+	if _, found := s.breached[sha1Hex(password)]; found {
+		problems = append(problems, "appears in a list of breached passwords")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrWeakPassword, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func (s *PasswordService) Hash(password string) (string, error) {
+	if s.cfg.Algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cfg.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, s.cfg.Argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %v", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, s.cfg.Argon2Time, s.cfg.Argon2Memory, s.cfg.Argon2Threads, s.cfg.Argon2KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, s.cfg.Argon2Memory, s.cfg.Argon2Time, s.cfg.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether password matches hash, and whether the hash was made
+// with an older algorithm or weaker parameters than currently configured
+func (s *PasswordService) Verify(hash, password string) (ok bool, needsRehash bool) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, false
+		}
+
+		computed := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(computed, key) != 1 {
+			return false, false
+		}
+
+		return true, s.cfg.Algorithm != AlgorithmArgon2id ||
+			params.memory != s.cfg.Argon2Memory ||
+			params.time != s.cfg.Argon2Time ||
+			params.threads != s.cfg.Argon2Threads ||
+			uint32(len(key)) != s.cfg.Argon2KeyLen
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return false, false
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, s.cfg.Algorithm != AlgorithmBcrypt || err != nil || cost != s.cfg.BcryptCost
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return argon2Params{}, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return argon2Params{}, nil, nil, errors.New("unsupported argon2 version")
+	}
+
+	var params argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return argon2Params{}, nil, nil, errors.New("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2Params{}, nil, nil, errors.New("invalid argon2id salt")
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return argon2Params{}, nil, nil, errors.New("invalid argon2id key")
+	}
+
+	return params, salt, key, nil
+}
+
+func sha1Hex(value string) string {
+	sum := sha1.Sum([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func isSHA1Hex(value string) bool {
+	if len(value) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"test_project/test/internal/config"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownColumn   = errors.New("unknown roadmap column")
+	ErrInvalidRanking  = errors.New("invalid ranking")
+	ErrIdeaNotInColumn = storage.ErrIdeaNotInColumn
+)
+
+type RoadmapService struct {
+	ideas storage.IdeaStorage
+	store storage.RoadmapStorage
+	cfg   config.RoadmapConfig
+}
+
+func NewRoadmapService(ideas storage.IdeaStorage, store storage.RoadmapStorage, cfg config.RoadmapConfig) *RoadmapService {
+	return &RoadmapService{ideas, store, cfg}
+}
+
+// GetRoadmap groups ideas into the configured columns. Within a column ideas
+// ranked by hand come first, the rest follow in the configured order.
+func (s *RoadmapService) GetRoadmap() utils.Result[model.Roadmap] {
+	result := s.ideas.GetAllIdeas()
+	if result.Err != nil {
+		return utils.Result[model.Roadmap]{Err: result.Err}
+	}
+
+	columnOf := make(map[model.RequestStatus]int)
+	roadmap := model.Roadmap{Columns: make([]model.RoadmapColumn, len(s.cfg.Columns))}
+	for i, c := range s.cfg.Columns {
+		roadmap.Columns[i] = model.RoadmapColumn{Key: c.Key, Name: c.Name, Statuses: c.Statuses, Ideas: []model.Idea{}}
+		for _, status := range c.Statuses {
+			if _, taken := columnOf[status]; !taken {
+				columnOf[status] = i
+			}
+		}
+	}
+
+	// The roadmap is public, only ideas listed for everyone are on it
+	for _, idea := range listedIdeas(result.Data, model.Actor{}) {
+		if i, ok := columnOf[idea.Status]; ok {
+			roadmap.Columns[i].Ideas = append(roadmap.Columns[i].Ideas, idea)
+		}
+	}
+
+	for i := range roadmap.Columns {
+		s.sortColumn(roadmap.Columns[i].Ideas)
+		roadmap.Columns[i].Count = len(roadmap.Columns[i].Ideas)
+	}
+
+	return utils.Result[model.Roadmap]{Data: roadmap}
+}
+
+// RankColumn stores the manual order of a column
+func (s *RoadmapService) RankColumn(key string, ideaIDs []uuid.UUID) error {
+	column, ok := s.cfg.Column(key)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownColumn, key)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(ideaIDs))
+	for _, id := range ideaIDs {
+		if seen[id] {
+			return fmt.Errorf("%w: idea %s is listed twice", ErrInvalidRanking, id)
+		}
+		seen[id] = true
+	}
+
+	return s.store.SetRoadmapRanks(column.Statuses, ideaIDs)
+}
+
+func (s *RoadmapService) sortColumn(ideas []model.Idea) {
+	sort.SliceStable(ideas, func(i, j int) bool {
+		a, b := ideas[i], ideas[j]
+		if (a.RoadmapRank > 0) != (b.RoadmapRank > 0) {
+			return a.RoadmapRank > 0
+		}
+		if a.RoadmapRank > 0 {
+			return a.RoadmapRank < b.RoadmapRank
+		}
+
+		switch s.cfg.Sort {
+		case config.RoadmapSortNewest:
+			return a.CreatedAt.After(b.CreatedAt)
+		case config.RoadmapSortUpdated:
+			return a.UpdatedAt.After(b.UpdatedAt)
+		default:
+			if a.VoteCount != b.VoteCount {
+				return a.VoteCount > b.VoteCount
+			}
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+var ErrSessionRevoked = errors.New("session has been revoked")
+
+// Writing last_seen_at on every request is wasteful, once a minute is plenty
+const sessionTouchInterval = time.Minute
+
+type SessionService struct {
+	store storage.SessionStorage
+}
+
+func NewSessionService(store storage.SessionStorage) *SessionService {
+	return &SessionService{store}
+}
+
+// Create records a new session and returns its ID, which goes into the token
+// as the jti claim
+func (s *SessionService) Create(userID uuid.UUID, info model.ClientInfo, ttl time.Duration) (uuid.UUID, error) {
+	now := time.Now()
+	session := model.Session{
+		ID:         uuid.New(),
+		UserID:     userID,
+		UserAgent:  truncate(info.UserAgent, 512),
+		IP:         truncate(info.IP, 64),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+
+	if err := s.store.CreateSession(session); err != nil {
+		return uuid.Nil, err
+	}
+
+	return session.ID, nil
+}
+
+// ValidateSession is called by middleware.Auth for every authenticated request
+func (s *SessionService) ValidateSession(claims jwt.MapClaims) error {
+	sessionID, err := utils.ClaimSessionID(claims)
+	if err != nil {
+		return err
+	}
+
+	userID, err := utils.ClaimUserID(claims)
+	if err != nil {
+		return err
+	}
+
+	session, err := s.store.GetSession(sessionID)
+	if err != nil {
+		return ErrSessionRevoked
+	}
+
+	now := time.Now()
+	if session.UserID != userID || session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return ErrSessionRevoked
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		// Failing to record last-seen is not a reason to reject the request
+		_ = s.store.TouchSession(session.ID, now)
+	}
+
+	return nil
+}
+
+func (s *SessionService) GetSessions(userID, currentID uuid.UUID) utils.Result[[]model.Session] {
+	sessions, err := s.store.GetActiveSessions(userID)
+	if err != nil {
+		return utils.Result[[]model.Session]{Err: err}
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	return utils.Result[[]model.Session]{Data: sessions}
+}
+
+func (s *SessionService) Revoke(userID, sessionID uuid.UUID) error {
+	return s.store.RevokeSession(userID, sessionID)
+}
+
+// RevokeOthers logs the user out everywhere except the current session
+func (s *SessionService) RevokeOthers(userID, currentID uuid.UUID) utils.Result[int64] {
+	count, err := s.store.RevokeUserSessions(userID, currentID)
+	return utils.NewResult(count, err)
+}
+
+// RevokeAll is the admin force-logout
+func (s *SessionService) RevokeAll(userID uuid.UUID) utils.Result[int64] {
+	count, err := s.store.RevokeUserSessions(userID, uuid.Nil)
+	if err != nil {
+		return utils.Result[int64]{Err: fmt.Errorf("failed to revoke sessions: %w", err)}
+	}
+	return utils.NewResult(count, nil)
+}
+
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
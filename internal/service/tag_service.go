@@ -0,0 +1,83 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+)
+
+const maxTagLength = 50
+
+var (
+	ErrInvalidTag  = errors.New("invalid tag")
+	ErrTagNotFound = storage.ErrTagNotFound
+	ErrTagExists   = storage.ErrTagExists
+)
+
+type TagService struct {
+	store storage.TagStorage
+}
+
+func NewTagService(store storage.TagStorage) *TagService {
+	return &TagService{store}
+}
+
+// Autocomplete suggests tags in use that start with the (normalized) query,
+// most used first
+func (s *TagService) Autocomplete(query string, limit int) utils.Result[[]model.TagUsage] {
+	if limit <= 0 || limit > 50 {
+		limit = 10
+	}
+
+	result := s.store.SearchTags(model.NormalizeTag(query), limit)
+	if result.Err == nil && result.Data == nil {
+		result.Data = []model.TagUsage{}
+	}
+	return result
+}
+
+// RenameTag renames a tag on every idea using it
+func (s *TagService) RenameTag(from, to string) error {
+	from, to = model.NormalizeTag(from), model.NormalizeTag(to)
+	if err := checkTag(to); err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+
+	return s.store.RenameTag(from, to)
+}
+
+// MergeTags replaces the source tags with the target on every idea
+func (s *TagService) MergeTags(payload model.MergeTagsPayload) error {
+	target := model.NormalizeTag(payload.Target)
+	if err := checkTag(target); err != nil {
+		return err
+	}
+
+	return s.store.MergeTags(model.NormalizeTags(payload.Sources), target)
+}
+
+// normalizeIdeaTags normalizes the tags of an idea before it is stored
+func normalizeIdeaTags(tags []string) ([]string, error) {
+	normalized := model.NormalizeTags(tags)
+	for _, tag := range normalized {
+		if err := checkTag(tag); err != nil {
+			return nil, err
+		}
+	}
+	return normalized, nil
+}
+
+func checkTag(tag string) error {
+	if tag == "" {
+		return fmt.Errorf("%w: tag must not be empty", ErrInvalidTag)
+	}
+	if len(tag) > maxTagLength {
+		return fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidTag, tag, maxTagLength)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTechNotFound     = errors.New("technology not found")
+	ErrTechConflict     = errors.New("name or alias is already used by another technology")
+	ErrTechInUse        = errors.New("technology is used by ideas, deprecate it instead")
+	ErrInvalidTech      = errors.New("invalid technology")
+	ErrInvalidTechStack = errors.New("invalid tech stack")
+)
+
+var hexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+type TechStackService struct {
+	store storage.TechStorage
+}
+
+func NewTechStackService(store storage.TechStorage) *TechStackService {
+	return &TechStackService{store}
+}
+
+func (s *TechStackService) GetTechnologies(includeDeprecated bool) utils.Result[[]model.Technology] {
+	result := s.store.GetTechnologies()
+	if result.Err != nil || includeDeprecated {
+		return result
+	}
+
+	active := []model.Technology{}
+	for _, tech := range result.Data {
+		if !tech.Deprecated {
+			active = append(active, tech)
+		}
+	}
+	return utils.Result[[]model.Technology]{Data: active}
+}
+
+func (s *TechStackService) CreateTechnology(payload model.CreateTechnologyPayload) utils.Result[model.Technology] {
+	now := time.Now()
+	tech := model.Technology{
+		ID:        uuid.New(),
+		Name:      strings.TrimSpace(payload.Name),
+		Icon:      payload.Icon,
+		Color:     payload.Color,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	tech.Aliases = cleanAliases(tech.Name, payload.Aliases)
+
+	if err := s.check(tech); err != nil {
+		return utils.Result[model.Technology]{Err: err}
+	}
+
+	if err := s.store.CreateTechnology(tech); err != nil {
+		return utils.Result[model.Technology]{Err: err}
+	}
+	return utils.Result[model.Technology]{Data: tech}
+}
+
+// UpdateTechnology changes a catalog entry. A renamed entry keeps its old name
+// as an alias, so ideas that still use the old name stay valid.
+func (s *TechStackService) UpdateTechnology(id uuid.UUID, payload model.UpdateTechnologyPayload) utils.Result[model.Technology] {
+	result := s.store.GetTechnology(id)
+	if result.Err != nil {
+		return utils.Result[model.Technology]{Err: fmt.Errorf("%w: %s", ErrTechNotFound, id)}
+	}
+
+	tech := result.Data
+	if payload.Aliases != nil {
+		tech.Aliases = *payload.Aliases
+	}
+	if payload.Name != nil {
+		if name := strings.TrimSpace(*payload.Name); name != tech.Name {
+			tech.Aliases = append(tech.Aliases, tech.Name)
+			tech.Name = name
+		}
+	}
+	tech.Aliases = cleanAliases(tech.Name, tech.Aliases)
+	if payload.Icon != nil {
+		tech.Icon = *payload.Icon
+	}
+	if payload.Color != nil {
+		tech.Color = *payload.Color
+	}
+	if payload.Deprecated != nil {
+		tech.Deprecated = *payload.Deprecated
+	}
+
+	if err := s.check(tech); err != nil {
+		return utils.Result[model.Technology]{Err: err}
+	}
+
+	if err := s.store.UpdateTechnology(tech); err != nil {
+		return utils.Result[model.Technology]{Err: err}
+	}
+	return utils.Result[model.Technology]{Data: tech}
+}
+
+// DeleteTechnology only deletes entries no idea uses, used ones can be deprecated
+func (s *TechStackService) DeleteTechnology(id uuid.UUID) error {
+	result := s.store.GetTechnology(id)
+	if result.Err != nil {
+		return fmt.Errorf("%w: %s", ErrTechNotFound, id)
+	}
+
+	used, err := s.store.CountIdeasUsingTechnology(append([]string{result.Data.Name}, result.Data.Aliases...))
+	if err != nil {
+		return err
+	}
+	if used > 0 {
+		return fmt.Errorf("%w: %d ideas", ErrTechInUse, used)
+	}
+
+	return s.store.DeleteTechnology(id)
+}
+
+// NormalizeTechStack checks an idea's tech stack against the catalog and
+// returns it with aliases replaced by catalog names and duplicates removed.
+// Deprecated entries are only accepted if the idea already had them.
+func (s *TechStackService) NormalizeTechStack(names, previous []string) ([]string, error) {
+	normalized := []string{}
+	if len(names) == 0 {
+		return normalized, nil
+	}
+
+	result := s.store.GetTechnologies()
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	lookup := make(map[string]model.Technology)
+	for _, tech := range result.Data {
+		lookup[strings.ToLower(tech.Name)] = tech
+		for _, alias := range tech.Aliases {
+			lookup[strings.ToLower(alias)] = tech
+		}
+	}
+
+	// Previous entries are resolved too, they may have been stored under a
+	// name that has since become an alias
+	had := make(map[uuid.UUID]bool)
+	for _, name := range previous {
+		if tech, ok := lookup[strings.ToLower(name)]; ok {
+			had[tech.ID] = true
+		}
+	}
+
+	var unknown, deprecated []string
+	seen := make(map[uuid.UUID]bool)
+	for _, name := range names {
+		tech, ok := lookup[strings.ToLower(strings.TrimSpace(name))]
+		switch {
+		case !ok:
+			unknown = append(unknown, name)
+		case tech.Deprecated && !had[tech.ID]:
+			deprecated = append(deprecated, tech.Name)
+		case !seen[tech.ID]:
+			seen[tech.ID] = true
+			normalized = append(normalized, tech.Name)
+		}
+	}
+
+	var problems []string
+	if len(unknown) > 0 {
+		problems = append(problems, "unknown: "+strings.Join(unknown, ", "))
+	}
+	if len(deprecated) > 0 {
+		problems = append(problems, "deprecated: "+strings.Join(deprecated, ", "))
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTechStack, strings.Join(problems, "; "))
+	}
+
+	return normalized, nil
+}
+
+// check validates an entry and makes sure its name and aliases are not used
+// by another entry, compared case-insensitively
+func (s *TechStackService) check(tech model.Technology) error {
+	if tech.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTech)
+	}
+	if tech.Color != "" && !hexColor.MatchString(tech.Color) {
+		return fmt.Errorf("%w: color must be a hex color like #00ADD8", ErrInvalidTech)
+	}
+
+	result := s.store.GetTechnologies()
+	if result.Err != nil {
+		return result.Err
+	}
+
+	taken := make(map[string]string)
+	for _, other := range result.Data {
+		if other.ID == tech.ID {
+			continue
+		}
+		taken[strings.ToLower(other.Name)] = other.Name
+		for _, alias := range other.Aliases {
+			taken[strings.ToLower(alias)] = other.Name
+		}
+	}
+
+	for _, name := range append([]string{tech.Name}, tech.Aliases...) {
+		if owner, ok := taken[strings.ToLower(name)]; ok {
+			return fmt.Errorf("%w: %q belongs to %s", ErrTechConflict, name, owner)
+		}
+	}
+	return nil
+}
+
+// cleanAliases trims aliases and drops empty ones, repeated ones and the
+// entry's own name
+func cleanAliases(name string, aliases []string) []string {
+	cleaned := []string{}
+	seen := map[string]bool{strings.ToLower(name): true}
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" || seen[strings.ToLower(alias)] {
+			continue
+		}
+		seen[strings.ToLower(alias)] = true
+		cleaned = append(cleaned, alias)
+	}
+	return cleaned
+}
@@ -3,34 +3,105 @@ package service
 import (
 	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"test_project/test/internal/config"
 	"test_project/test/internal/model"
 	"test_project/test/internal/storage"
 	utils "test_project/test/pkg"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidTOTPCode    = errors.New("invalid two-factor code")
+	ErrTOTPNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTOTPRequired       = errors.New("two-factor authentication is required for this role")
+	ErrTOTPLocked         = storage.ErrTOTPLocked
+	ErrEmailTaken         = errors.New("email is already in use")
+	ErrRegistrationClosed = errors.New("registration requires an invite code")
+	ErrInvalidInvite      = errors.New("invite code is invalid, expired or used up")
+	ErrEmailDomain        = errors.New("registration is not open for this email domain")
 )
 
 type UserService struct {
-	store storage.UserStorage
+	store     storage.UserStorage
+	sessions  *SessionService
+	passwords *PasswordService
+	events    *EventBus
+	cfg       config.AuthConfig
 }
 
-func NewUserService(store storage.UserStorage) *UserService {
-	return &UserService{store}
+func NewUserService(store storage.UserStorage, sessions *SessionService, passwords *PasswordService, events *EventBus, cfg config.AuthConfig) *UserService {
+	return &UserService{store, sessions, passwords, events, cfg}
 }
 
 func (s *UserService) CreateUser(req model.RegisterRequest) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err := s.checkRegistrationPolicy(req); err != nil {
+		return err
+	}
+
+	if err := s.passwords.Validate(req.Password, req.Username, req.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwords.Hash(req.Password)
 	if err != nil {
 		return err
 	}
 
 	user := model.User{
+		ID:       uuid.New(),
 		Username: req.Username,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
 		Email:    req.Email,
+		Role:     model.RoleUser,
+	}
+
+	event, err := Event(model.UserRegistered{UserID: user.ID, Username: user.Username})
+	if err != nil {
+		return err
 	}
 
-	return s.store.CreateUser(user)
+	if req.InviteCode == "" {
+		err = s.store.CreateUser(user, event)
+	} else {
+		err = s.store.CreateUserWithInvite(user, utils.HashSecret(req.InviteCode), event)
+		if errors.Is(err, storage.ErrInviteUnavailable) {
+			return ErrInvalidInvite
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	s.events.Wake()
+	return nil
+}
+
+// checkRegistrationPolicy runs before any work is done for a registration.
+// The invite itself is only checked when it is redeemed, together with the
+// user insert.
+func (s *UserService) checkRegistrationPolicy(req model.RegisterRequest) error {
+	switch s.cfg.RegistrationMode {
+	case model.RegistrationInviteOnly:
+		if req.InviteCode == "" {
+			return ErrRegistrationClosed
+		}
+	case model.RegistrationDomain:
+		if req.InviteCode == "" && !s.cfg.EmailDomainAllowed(req.Email) {
+			return ErrEmailDomain
+		}
+	case model.RegistrationOpen, "":
+	default:
+		return fmt.Errorf("unknown registration mode %q", s.cfg.RegistrationMode)
+	}
+
+	return nil
 }
 
 func (s *UserService) ValidateCredentials(username, password string) (bool, error) {
@@ -39,14 +110,208 @@ func (s *UserService) ValidateCredentials(username, password string) (bool, erro
 		return false, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if err != nil {
-		return false, errors.New("invalid credentials")
+	if ok, _ := s.passwords.Verify(user.Password, password); !ok {
+		return false, ErrInvalidCredentials
 	}
 
 	return true, nil
 }
 
+// Login checks the password and decides what the client gets back: a session
+// token, a TOTP challenge, or an enrollment-only token when the user's role
+// requires 2FA that has not been set up yet.
+func (s *UserService) Login(username, password string, info model.ClientInfo) utils.Result[model.LoginResponse] {
+	user, err := s.store.GetUserByUsername(username)
+	if err != nil {
+		return utils.Result[model.LoginResponse]{Err: ErrInvalidCredentials}
+	}
+
+	ok, needsRehash := s.passwords.Verify(user.Password, password)
+	if !ok {
+		return utils.Result[model.LoginResponse]{Err: ErrInvalidCredentials}
+	}
+
+	// The plain password is only available here, so this is where old hashes
+	// get upgraded. A failure just means we try again next login.
+	if needsRehash {
+		if hashed, err := s.passwords.Hash(password); err == nil {
+			user.Password = hashed
+			if err := s.store.UpdateUser(user); err != nil {
+				log.Printf("failed to upgrade password hash for %s: %v", user.Username, err)
+			}
+		}
+	}
+
+	return s.StartSession(user, info)
+}
+
+// StartSession runs the second half of a login once the user has proven who
+// they are, by password or through single sign-on
+func (s *UserService) StartSession(user model.User, info model.ClientInfo) utils.Result[model.LoginResponse] {
+	if user.TOTPEnabled {
+		challenge, err := s.scopedToken(user, utils.ScopeTOTPChallenge, s.cfg.TOTPChallengeTTL)
+		if err != nil {
+			return utils.Result[model.LoginResponse]{Err: err}
+		}
+		return utils.Result[model.LoginResponse]{Data: model.LoginResponse{ChallengeToken: challenge, TOTPRequired: true}}
+	}
+
+	if s.cfg.RequiresTOTP(user.EffectiveRole()) {
+		token, err := s.scopedToken(user, utils.ScopeTOTPEnroll, s.cfg.TOTPChallengeTTL)
+		if err != nil {
+			return utils.Result[model.LoginResponse]{Err: err}
+		}
+		return utils.Result[model.LoginResponse]{Data: model.LoginResponse{Token: token, TOTPSetupRequired: true}}
+	}
+
+	return s.IssueSession(user, info)
+}
+
+// CompleteTOTPLogin exchanges a challenge token plus a TOTP or recovery code
+// for a session token
+func (s *UserService) CompleteTOTPLogin(req model.TOTPLoginRequest, info model.ClientInfo) utils.Result[model.LoginResponse] {
+	claims, err := utils.ParseToken(req.ChallengeToken)
+	if err != nil || utils.ClaimScope(claims) != utils.ScopeTOTPChallenge {
+		return utils.Result[model.LoginResponse]{Err: ErrInvalidCredentials}
+	}
+
+	userID, err := utils.ClaimUserID(claims)
+	if err != nil {
+		return utils.Result[model.LoginResponse]{Err: ErrInvalidCredentials}
+	}
+
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return utils.Result[model.LoginResponse]{Err: ErrInvalidCredentials}
+	}
+
+	if !user.TOTPEnabled {
+		return utils.Result[model.LoginResponse]{Err: ErrTOTPNotEnabled}
+	}
+
+	if req.Code != "" {
+		err = s.verifyTOTP(&user, req.Code)
+	} else {
+		err = s.countTOTPAttempt(&user, func() error {
+			if err := s.store.UseRecoveryCode(user.ID, utils.HashSecret(req.RecoveryCode)); err != nil {
+				return ErrInvalidTOTPCode
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return utils.Result[model.LoginResponse]{Err: err}
+	}
+
+	return s.IssueSession(user, info)
+}
+
+// BeginTOTPEnrollment generates a fresh secret. It is not active until
+// ConfirmTOTPEnrollment sees a valid code for it.
+func (s *UserService) BeginTOTPEnrollment(userID uuid.UUID) utils.Result[model.TOTPEnrollResponse] {
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return utils.Result[model.TOTPEnrollResponse]{Err: err}
+	}
+
+	if user.TOTPEnabled {
+		return utils.Result[model.TOTPEnrollResponse]{Err: ErrTOTPAlreadyEnabled}
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return utils.Result[model.TOTPEnrollResponse]{Err: err}
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := s.store.UpdateUser(user); err != nil {
+		return utils.Result[model.TOTPEnrollResponse]{Err: err}
+	}
+
+	return utils.Result[model.TOTPEnrollResponse]{Data: model.TOTPEnrollResponse{
+		Secret: secret,
+		URI:    utils.TOTPURI(s.cfg.TOTPIssuer, user.Username, secret),
+	}}
+}
+
+// ConfirmTOTPEnrollment enables 2FA and returns the recovery codes. This is the
+// only time the plain codes are available.
+func (s *UserService) ConfirmTOTPEnrollment(userID uuid.UUID, code string) utils.Result[[]string] {
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return utils.Result[[]string]{Err: err}
+	}
+
+	if user.TOTPEnabled {
+		return utils.Result[[]string]{Err: ErrTOTPAlreadyEnabled}
+	}
+
+	if user.TOTPSecret == "" {
+		return utils.Result[[]string]{Err: ErrTOTPNotEnabled}
+	}
+
+	if err := s.verifyTOTP(&user, code); err != nil {
+		return utils.Result[[]string]{Err: err}
+	}
+
+	user.TOTPEnabled = true
+	if err := s.store.UpdateUser(user); err != nil {
+		return utils.Result[[]string]{Err: err}
+	}
+
+	return s.newRecoveryCodes(user.ID)
+}
+
+func (s *UserService) RegenerateRecoveryCodes(userID uuid.UUID, code string) utils.Result[[]string] {
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return utils.Result[[]string]{Err: err}
+	}
+
+	if !user.TOTPEnabled {
+		return utils.Result[[]string]{Err: ErrTOTPNotEnabled}
+	}
+
+	if err := s.verifyTOTP(&user, code); err != nil {
+		return utils.Result[[]string]{Err: err}
+	}
+
+	return s.newRecoveryCodes(user.ID)
+}
+
+func (s *UserService) DisableTOTP(userID uuid.UUID, password, code string) error {
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+
+	if s.cfg.RequiresTOTP(user.EffectiveRole()) {
+		return ErrTOTPRequired
+	}
+
+	if ok, _ := s.passwords.Verify(user.Password, password); !ok {
+		return ErrInvalidCredentials
+	}
+
+	if err := s.verifyTOTP(&user, code); err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	if err := s.store.UpdateUser(user); err != nil {
+		return err
+	}
+
+	return s.store.ReplaceRecoveryCodes(user.ID, nil)
+}
+
 func (s *UserService) GetUserByUsername(username string) utils.Result[model.User] {
 	user, err := s.store.GetUserByUsername(username)
 	if err != nil {
@@ -66,14 +331,209 @@ func (s *UserService) DeleteUser(username, password string) (bool, error) {
 		return false, fmt.Errorf("failed to fetch user: %w", err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return false, fmt.Errorf("invalid credentials")
+	if err := s.DeleteAccount(user.ID, password); err != nil {
+		return false, err
 	}
 
-	_, err = s.store.DeleteUser(username)
+	return true, nil
+}
+
+func (s *UserService) GetUserByID(id uuid.UUID) utils.Result[model.User] {
+	user, err := s.store.GetUserByID(id)
 	if err != nil {
-		return false, fmt.Errorf("failed to delete user: %w", err)
+		return utils.Result[model.User]{Err: err}
 	}
 
-	return true, nil
+	return utils.Result[model.User]{Data: user}
+}
+
+func (s *UserService) UpdateProfile(userID uuid.UUID, req model.UpdateProfileRequest) utils.Result[model.User] {
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return utils.Result[model.User]{Err: err}
+	}
+
+	if req.Email != nil && !strings.EqualFold(*req.Email, user.Email) {
+		// A stolen token alone must not be enough to take over the account
+		// through the email
+		if ok, _ := s.passwords.Verify(user.Password, req.CurrentPassword); !ok {
+			return utils.Result[model.User]{Err: ErrInvalidCredentials}
+		}
+		if _, err := s.store.GetUserByEmail(*req.Email); err == nil {
+			return utils.Result[model.User]{Err: ErrEmailTaken}
+		}
+		user.Email = *req.Email
+	}
+
+	if req.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+
+	if req.AutoFollowCreated != nil {
+		user.AutoFollowCreated = *req.AutoFollowCreated
+	}
+
+	if req.AutoFollowVoted != nil {
+		user.AutoFollowVoted = *req.AutoFollowVoted
+	}
+
+	if err := s.store.UpdateUser(user); err != nil {
+		return utils.Result[model.User]{Err: err}
+	}
+
+	return utils.Result[model.User]{Data: user}
+}
+
+// ChangePassword also logs out every other session, the current one stays
+func (s *UserService) ChangePassword(userID, currentSessionID uuid.UUID, req model.ChangePasswordRequest) error {
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if ok, _ := s.passwords.Verify(user.Password, req.CurrentPassword); !ok {
+		return ErrInvalidCredentials
+	}
+
+	if err := s.passwords.Validate(req.NewPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwords.Hash(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	user.Password = hashedPassword
+	if err := s.store.UpdateUser(user); err != nil {
+		return err
+	}
+
+	return s.sessions.RevokeOthers(user.ID, currentSessionID).Err
+}
+
+// DeleteAccount checks the password and deletes the user, applying the
+// configured policy to the ideas and votes they leave behind
+func (s *UserService) DeleteAccount(userID uuid.UUID, password string) error {
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	if ok, _ := s.passwords.Verify(user.Password, password); !ok {
+		return ErrInvalidCredentials
+	}
+
+	var target model.User
+	if s.cfg.DeletionPolicy == model.DeletionReassign {
+		target, err = s.store.GetUserByUsername(s.cfg.DeletionReassignTo)
+		if err != nil {
+			return fmt.Errorf("failed to find reassignment target %q: %w", s.cfg.DeletionReassignTo, err)
+		}
+		if target.ID == user.ID {
+			return errors.New("the reassignment target account can't be deleted")
+		}
+	}
+
+	event, err := Event(model.UserDeleted{UserID: user.ID, Policy: s.cfg.DeletionPolicy})
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.DeleteAccount(user.ID, s.cfg.DeletionPolicy, target, event); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	s.events.Wake()
+
+	return nil
+}
+
+// verifyTOTP checks the code and records the step it matched, so the same
+// code can't be used twice within its validity window
+// verifyTOTP checks a code for every 2FA operation, not only logins, so a
+// stolen session can't guess its way to new recovery codes either
+func (s *UserService) verifyTOTP(user *model.User, code string) error {
+	return s.countTOTPAttempt(user, func() error {
+		step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+		if !ok || step <= user.TOTPLastStep {
+			return ErrInvalidTOTPCode
+		}
+
+		if err := s.store.UseTOTPStep(user.ID, step); err != nil {
+			return ErrInvalidTOTPCode
+		}
+		user.TOTPLastStep = step
+		return nil
+	})
+}
+
+// countTOTPAttempt runs check as one 2FA attempt. Too many failures in a row
+// lock the user's 2FA for a while, whatever the attempts were for.
+func (s *UserService) countTOTPAttempt(user *model.User, check func() error) error {
+	if err := s.store.CountTOTPAttempt(user.ID, s.cfg.TOTPMaxFailures, s.cfg.TOTPLockout); err != nil {
+		return err
+	}
+
+	if err := check(); err != nil {
+		return err
+	}
+
+	if err := s.store.ResetTOTPFailures(user.ID); err != nil {
+		return err
+	}
+	// Callers may save the user afterwards, keep it in line with the store
+	user.TOTPFailures = 0
+	user.TOTPLockedUntil = nil
+	return nil
+}
+
+func (s *UserService) newRecoveryCodes(userID uuid.UUID) utils.Result[[]string] {
+	codes, err := utils.GenerateRecoveryCodes(s.cfg.RecoveryCodeCount)
+	if err != nil {
+		return utils.Result[[]string]{Err: err}
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, utils.HashSecret(code))
+	}
+
+	if err := s.store.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return utils.Result[[]string]{Err: err}
+	}
+
+	return utils.Result[[]string]{Data: codes}
+}
+
+// IssueSession records a session and signs a regular token for it
+func (s *UserService) IssueSession(user model.User, info model.ClientInfo) utils.Result[model.LoginResponse] {
+	sessionID, err := s.sessions.Create(user.ID, info, s.cfg.TokenTTL)
+	if err != nil {
+		return utils.Result[model.LoginResponse]{Err: fmt.Errorf("error creating session: %v", err)}
+	}
+
+	token, err := utils.GenerateToken(jwt.MapClaims{
+		"jti":      sessionID.String(),
+		"username": user.Username,
+		"user_id":  user.ID.String(),
+		"role":     string(user.EffectiveRole()),
+	}, s.cfg.TokenTTL)
+	if err != nil {
+		return utils.Result[model.LoginResponse]{Err: fmt.Errorf("error generating token: %v", err)}
+	}
+
+	return utils.Result[model.LoginResponse]{Data: model.LoginResponse{Token: token}}
+}
+
+func (s *UserService) scopedToken(user model.User, scope string, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateToken(jwt.MapClaims{
+		"username": user.Username,
+		"user_id":  user.ID.String(),
+		"scope":    scope,
+	}, ttl)
+	if err != nil {
+		return "", fmt.Errorf("error generating token: %v", err)
+	}
+
+	return token, nil
 }
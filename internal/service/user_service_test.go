@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"test_project/test/internal/config"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// totpUserStore adds the 2FA bookkeeping of the Postgres store. UpdateUser is
+// left out on purpose, checking a code must not save the whole user.
+type totpUserStore struct {
+	*memUserStore
+	recoveryCodes []string
+}
+
+func (m *totpUserStore) CountTOTPAttempt(userID uuid.UUID, maxFailures int, lockout time.Duration) error {
+	user := m.users[userID]
+	if user.TOTPLockedUntil != nil && user.TOTPLockedUntil.After(time.Now()) {
+		return ErrTOTPLocked
+	}
+
+	user.TOTPFailures++
+	if user.TOTPFailures >= maxFailures {
+		lockedUntil := time.Now().Add(lockout)
+		user.TOTPFailures = 0
+		user.TOTPLockedUntil = &lockedUntil
+	}
+	m.users[userID] = user
+	return nil
+}
+
+func (m *totpUserStore) ResetTOTPFailures(userID uuid.UUID) error {
+	user := m.users[userID]
+	user.TOTPFailures = 0
+	user.TOTPLockedUntil = nil
+	m.users[userID] = user
+	return nil
+}
+
+func (m *totpUserStore) UseTOTPStep(userID uuid.UUID, step int64) error {
+	user := m.users[userID]
+	if user.TOTPLastStep >= step {
+		return errors.New("two-factor code already used")
+	}
+	user.TOTPLastStep = step
+	m.users[userID] = user
+	return nil
+}
+
+func (m *totpUserStore) ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	m.recoveryCodes = codeHashes
+	return nil
+}
+
+func newTOTPUser(t *testing.T) (*UserService, *totpUserStore, model.User) {
+	t.Helper()
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+
+	store := &totpUserStore{memUserStore: newMemUserStore()}
+	user := model.User{ID: uuid.New(), Username: "ada", Role: model.RoleUser, TOTPSecret: secret, TOTPEnabled: true}
+	store.users[user.ID] = user
+
+	s := NewUserService(store, nil, nil, nil, config.AuthConfig{RecoveryCodeCount: 2, TOTPMaxFailures: 3, TOTPLockout: time.Minute})
+	return s, store, user
+}
+
+func currentTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+	return code
+}
+
+func TestTOTPChecksAreLimited(t *testing.T) {
+	s, store, user := newTOTPUser(t)
+
+	for i := 0; i < 3; i++ {
+		if result := s.RegenerateRecoveryCodes(user.ID, "000000"); !errors.Is(result.Err, ErrInvalidTOTPCode) {
+			t.Fatalf("guess %d: %v, want %v", i+1, result.Err, ErrInvalidTOTPCode)
+		}
+	}
+
+	// Locked now, even the right code is refused
+	code := currentTOTPCode(t, user.TOTPSecret)
+	if result := s.RegenerateRecoveryCodes(user.ID, code); !errors.Is(result.Err, ErrTOTPLocked) {
+		t.Errorf("RegenerateRecoveryCodes while locked: %v, want %v", result.Err, ErrTOTPLocked)
+	}
+	if len(store.recoveryCodes) != 0 {
+		t.Errorf("got %d recovery codes, want none", len(store.recoveryCodes))
+	}
+
+	lockedUntil := time.Now().Add(-time.Second)
+	locked := store.users[user.ID]
+	locked.TOTPLockedUntil = &lockedUntil
+	store.users[user.ID] = locked
+
+	if result := s.RegenerateRecoveryCodes(user.ID, code); result.Err != nil {
+		t.Fatalf("RegenerateRecoveryCodes after the lockout: %v", result.Err)
+	}
+	if got := store.users[user.ID]; got.TOTPFailures != 0 || got.TOTPLockedUntil != nil {
+		t.Errorf("user = %+v, want the failures reset", got)
+	}
+}
+
+func TestTOTPCodeWorksOnce(t *testing.T) {
+	s, store, user := newTOTPUser(t)
+	code := currentTOTPCode(t, user.TOTPSecret)
+
+	if result := s.RegenerateRecoveryCodes(user.ID, code); result.Err != nil {
+		t.Fatalf("RegenerateRecoveryCodes: %v", result.Err)
+	}
+	if got := store.users[user.ID].TOTPLastStep; got != utils.TOTPStep(time.Now()) && got != utils.TOTPStep(time.Now())-1 {
+		t.Errorf("last step = %d, want the current one", got)
+	}
+
+	if result := s.RegenerateRecoveryCodes(user.ID, code); !errors.Is(result.Err, ErrInvalidTOTPCode) {
+		t.Errorf("reusing the code: %v, want %v", result.Err, ErrInvalidTOTPCode)
+	}
+	if got := store.users[user.ID].TOTPFailures; got != 1 {
+		t.Errorf("failures = %d, want the reuse counted", got)
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"errors"
 	"fmt"
+	"test_project/test/internal/model"
 	"test_project/test/internal/storage"
 	utils "test_project/test/pkg"
 
@@ -10,14 +11,23 @@ import (
 )
 
 type VoteService struct {
-	store storage.VoteStorage
+	store  storage.VoteStorage
+	ideas  storage.IdeaStorage
+	boards *BoardService
+	events *EventBus
 }
 
-func NewVoteService(store storage.VoteStorage) *VoteService {
-	return &VoteService{store}
+func NewVoteService(store storage.VoteStorage, ideas storage.IdeaStorage, boards *BoardService, events *EventBus) *VoteService {
+	return &VoteService{store, ideas, boards, events}
 }
 
-func (s *VoteService) AddVote(userId uuid.UUID, ideaId uuid.UUID) utils.Result[string] {
+func (s *VoteService) AddVote(actor model.Actor, ideaId uuid.UUID) utils.Result[string] {
+	userId := actor.ID
+	idea, err := s.checkVoting(ideaId, actor)
+	if err != nil {
+		return utils.Result[string]{Err: err}
+	}
+
 	// if user has already voted or not
 	hasVoted := s.store.HasUserVoted(userId, ideaId)
 	if hasVoted.Err != nil {
@@ -32,10 +42,27 @@ func (s *VoteService) AddVote(userId uuid.UUID, ideaId uuid.UUID) utils.Result[s
 		}
 	}
 
-	return s.store.AddVote(userId, ideaId)
+	event, err := Event(model.VoteAdded{IdeaID: ideaId, UserID: userId, Visibility: idea.EffectiveVisibility()})
+	if err != nil {
+		return utils.Result[string]{Err: err}
+	}
+
+	result := s.store.AddVote(userId, ideaId, event)
+	if result.Err == nil {
+		s.events.Wake()
+	}
+
+	return result
 }
 
-func (s *VoteService) RemoveVote(userId uuid.UUID, ideaId uuid.UUID) utils.Result[string] {
+func (s *VoteService) RemoveVote(actor model.Actor, ideaId uuid.UUID) utils.Result[string] {
+	userId := actor.ID
+	// Votes are frozen while voting is closed, taking one back included
+	idea, err := s.checkVoting(ideaId, actor)
+	if err != nil && !errors.Is(err, ErrNotBoardMember) {
+		return utils.Result[string]{Err: err}
+	}
+
 	hasVoted := s.store.HasUserVoted(userId, ideaId)
 	if hasVoted.Err != nil {
 		return utils.Result[string]{
@@ -48,13 +75,62 @@ func (s *VoteService) RemoveVote(userId uuid.UUID, ideaId uuid.UUID) utils.Resul
 		}
 	}
 
-	return s.store.RemoveVote(userId, ideaId)
+	event, err := Event(model.VoteRemoved{IdeaID: ideaId, UserID: userId, Visibility: idea.EffectiveVisibility()})
+	if err != nil {
+		return utils.Result[string]{Err: err}
+	}
+
+	result := s.store.RemoveVote(userId, ideaId, event)
+	if result.Err == nil {
+		s.events.Wake()
+	}
+
+	return result
 }
 
-func (s *VoteService) HasUserVoted(userId uuid.UUID, ideaId uuid.UUID) utils.Result[bool] {
-	return s.store.HasUserVoted(userId, ideaId)
+func (s *VoteService) HasUserVoted(actor model.Actor, ideaId uuid.UUID) utils.Result[bool] {
+	if _, err := s.getIdea(ideaId, actor); err != nil {
+		return utils.Result[bool]{Err: err}
+	}
+
+	return s.store.HasUserVoted(actor.ID, ideaId)
 }
 
-func (s *VoteService) GetVoteCount(ideaId uuid.UUID) utils.Result[int] {
+func (s *VoteService) GetVoteCount(ideaId uuid.UUID, viewer model.Actor) utils.Result[int] {
+	if _, err := s.getIdea(ideaId, viewer); err != nil {
+		return utils.Result[int]{Err: err}
+	}
+
 	return s.store.GetVoteCount(ideaId)
 }
+
+// checkVoting makes sure the idea exists and its board takes votes from the
+// actor. The idea is returned along with ErrNotBoardMember.
+func (s *VoteService) checkVoting(ideaID uuid.UUID, actor model.Actor) (model.Idea, error) {
+	idea, err := s.getIdea(ideaID, actor)
+	if err != nil {
+		return model.Idea{}, err
+	}
+
+	board := s.boards.BoardOf(idea)
+	if board.Err != nil {
+		return model.Idea{}, board.Err
+	}
+	if !board.Data.VotingOpen {
+		return model.Idea{}, fmt.Errorf("%w: %s", ErrVotingClosed, board.Data.Slug)
+	}
+	return idea, s.boards.CheckContributor(board.Data, actor)
+}
+
+// getIdea makes sure the idea exists, the viewer can see it and it is not a
+// merge redirect, whose votes were moved to the idea it was merged into
+func (s *VoteService) getIdea(ideaID uuid.UUID, viewer model.Actor) (model.Idea, error) {
+	idea := s.ideas.GetIdea(ideaID)
+	if idea.Err != nil || idea.Data.ID == uuid.Nil || !idea.Data.VisibleTo(viewer) {
+		return model.Idea{}, fmt.Errorf("%w: %s", ErrIdeaNotFound, ideaID)
+	}
+	if idea.Data.MergedInto != nil {
+		return model.Idea{}, fmt.Errorf("%w: %s", ErrIdeaMerged, *idea.Data.MergedInto)
+	}
+	return idea.Data, nil
+}
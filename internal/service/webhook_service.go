@@ -0,0 +1,465 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"test_project/test/internal/config"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Deliveries are loaded from the queue in batches of this size
+	deliveryBatchSize    = 50
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+var (
+	ErrWebhookNotFound  = storage.ErrWebhookNotFound
+	ErrDeliveryNotFound = storage.ErrDeliveryNotFound
+	ErrWebhookInactive  = errors.New("webhook is disabled, enable it before redelivering")
+	ErrInvalidWebhook   = errors.New("invalid webhook")
+)
+
+// WebhookService manages webhook subscriptions and delivers events to them.
+// Domain events are queued as deliveries and sent by a background worker,
+// failed attempts are retried with exponential backoff.
+type WebhookService struct {
+	store  storage.WebhookStorage
+	votes  storage.VoteStorage
+	client *http.Client
+	cfg    config.WebhookConfig
+	wake   chan struct{}
+}
+
+// NewWebhookService uses client to post deliveries, nil means a client with
+// the configured timeout
+func NewWebhookService(store storage.WebhookStorage, votes storage.VoteStorage, client *http.Client, cfg config.WebhookConfig) *WebhookService {
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	return &WebhookService{store, votes, client, cfg, make(chan struct{}, 1)}
+}
+
+func (s *WebhookService) CreateWebhook(payload model.CreateWebhookPayload) utils.Result[model.CreateWebhookResponse] {
+	if err := checkWebhookURL(payload.URL); err != nil {
+		return utils.Result[model.CreateWebhookResponse]{Err: err}
+	}
+
+	random, err := randomURLString(24)
+	if err != nil {
+		return utils.Result[model.CreateWebhookResponse]{Err: err}
+	}
+	secret := "whsec_" + random
+
+	now := time.Now()
+	webhook := model.Webhook{
+		ID:          uuid.New(),
+		URL:         payload.URL,
+		Secret:      secret,
+		Events:      uniqueEvents(payload.Events),
+		Description: strings.TrimSpace(payload.Description),
+		Active:      true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.store.CreateWebhook(webhook); err != nil {
+		return utils.Result[model.CreateWebhookResponse]{Err: err}
+	}
+
+	return utils.Result[model.CreateWebhookResponse]{Data: model.CreateWebhookResponse{Webhook: webhook, Secret: secret}}
+}
+
+func (s *WebhookService) GetWebhooks() utils.Result[[]model.Webhook] {
+	result := s.store.GetWebhooks()
+	if result.Err == nil && result.Data == nil {
+		result.Data = []model.Webhook{}
+	}
+	return result
+}
+
+func (s *WebhookService) GetWebhook(id uuid.UUID) utils.Result[model.Webhook] {
+	return s.store.GetWebhook(id)
+}
+
+// UpdateWebhook changes the given fields. Enabling a webhook clears its
+// failure streak.
+func (s *WebhookService) UpdateWebhook(id uuid.UUID, payload model.UpdateWebhookPayload) utils.Result[model.Webhook] {
+	result := s.store.GetWebhook(id)
+	if result.Err != nil {
+		return result
+	}
+
+	webhook := result.Data
+	if payload.URL != nil {
+		if err := checkWebhookURL(*payload.URL); err != nil {
+			return utils.Result[model.Webhook]{Err: err}
+		}
+		webhook.URL = *payload.URL
+	}
+	if payload.Events != nil {
+		webhook.Events = uniqueEvents(*payload.Events)
+	}
+	if payload.Description != nil {
+		webhook.Description = strings.TrimSpace(*payload.Description)
+	}
+	if payload.Active != nil {
+		if *payload.Active && !webhook.Active {
+			webhook.ConsecutiveFailures = 0
+			webhook.DisabledAt = nil
+		}
+		webhook.Active = *payload.Active
+	}
+
+	if err := s.store.UpdateWebhook(webhook); err != nil {
+		return utils.Result[model.Webhook]{Err: err}
+	}
+
+	return utils.Result[model.Webhook]{Data: webhook}
+}
+
+func (s *WebhookService) DeleteWebhook(id uuid.UUID) error {
+	return s.store.DeleteWebhook(id)
+}
+
+// GetDeliveries returns the delivery log of a webhook, newest first
+func (s *WebhookService) GetDeliveries(webhookID uuid.UUID, limit int) utils.Result[[]model.WebhookDelivery] {
+	if result := s.store.GetWebhook(webhookID); result.Err != nil {
+		return utils.Result[[]model.WebhookDelivery]{Err: result.Err}
+	}
+
+	if limit <= 0 {
+		limit = defaultDeliveryLimit
+	}
+	limit = min(limit, maxDeliveryLimit)
+
+	result := s.store.GetWebhookDeliveries(webhookID, limit)
+	if result.Err == nil && result.Data == nil {
+		result.Data = []model.WebhookDelivery{}
+	}
+	return result
+}
+
+// Redeliver queues the payload of an earlier delivery again as a new delivery
+func (s *WebhookService) Redeliver(webhookID, deliveryID uuid.UUID) utils.Result[model.WebhookDelivery] {
+	webhook := s.store.GetWebhook(webhookID)
+	if webhook.Err != nil {
+		return utils.Result[model.WebhookDelivery]{Err: webhook.Err}
+	}
+	if !webhook.Data.Active {
+		return utils.Result[model.WebhookDelivery]{Err: ErrWebhookInactive}
+	}
+
+	original := s.store.GetWebhookDelivery(deliveryID)
+	if original.Err != nil {
+		return original
+	}
+	if original.Data.WebhookID != webhookID {
+		return utils.Result[model.WebhookDelivery]{Err: ErrDeliveryNotFound}
+	}
+
+	delivery := newDelivery(webhookID, original.Data.Event, original.Data.Payload)
+	delivery.RedeliveryOf = &original.Data.ID
+
+	if err := s.store.CreateWebhookDeliveries([]model.WebhookDelivery{delivery}); err != nil {
+		return utils.Result[model.WebhookDelivery]{Err: err}
+	}
+	s.notifyWorker()
+
+	return utils.Result[model.WebhookDelivery]{Data: delivery}
+}
+
+// Subscribe queues webhook deliveries for the domain events webhooks can
+// subscribe to
+func (s *WebhookService) Subscribe(bus *EventBus) {
+	// Receivers only hear about public ideas, an idea that is published later
+	// is created as far as they are concerned
+	Subscribe(bus, "webhooks", func(event model.OutboxEvent, e model.IdeaCreated) error {
+		if e.Idea.EffectiveVisibility() != model.VisibilityPublic {
+			return nil
+		}
+		return s.publish(event, model.WebhookIdeaCreated, e.Idea)
+	})
+	Subscribe(bus, "webhooks", func(event model.OutboxEvent, e model.IdeaPublished) error {
+		return s.publish(event, model.WebhookIdeaCreated, e.Idea)
+	})
+	Subscribe(bus, "webhooks", func(event model.OutboxEvent, e model.IdeaStatusChanged) error {
+		if e.Idea.EffectiveVisibility() != model.VisibilityPublic {
+			return nil
+		}
+		return s.publish(event, model.WebhookIdeaStatusChanged, model.StatusChangedData{Idea: e.Idea, From: e.From, To: e.To})
+	})
+	// The idea of these events may be gone already, they carry its visibility
+	Subscribe(bus, "webhooks", func(event model.OutboxEvent, e model.IdeaDeleted) error {
+		if e.Visibility != model.VisibilityPublic {
+			return nil
+		}
+		return s.publish(event, model.WebhookIdeaDeleted, model.IdeaDeletedData{ID: e.IdeaID})
+	})
+	Subscribe(bus, "webhooks", func(event model.OutboxEvent, e model.VoteAdded) error {
+		if e.Visibility != model.VisibilityPublic {
+			return nil
+		}
+		return s.publishVote(event, model.WebhookVoteAdded, e.IdeaID, e.UserID)
+	})
+	Subscribe(bus, "webhooks", func(event model.OutboxEvent, e model.VoteRemoved) error {
+		if e.Visibility != model.VisibilityPublic {
+			return nil
+		}
+		return s.publishVote(event, model.WebhookVoteRemoved, e.IdeaID, e.UserID)
+	})
+}
+
+func (s *WebhookService) publishVote(event model.OutboxEvent, webhookEvent model.WebhookEvent, ideaID, userID uuid.UUID) error {
+	count := s.votes.GetVoteCount(ideaID)
+	if count.Err != nil {
+		return count.Err
+	}
+
+	return s.publish(event, webhookEvent, model.VoteData{IdeaID: ideaID, UserID: userID, VoteCount: count.Data})
+}
+
+// publish queues a delivery for every active webhook subscribed to the
+// event. The payload and delivery IDs derive from the domain event, so a
+// redelivered event is not queued twice.
+func (s *WebhookService) publish(event model.OutboxEvent, webhookEvent model.WebhookEvent, data any) error {
+	webhooks := s.store.GetSubscribedWebhooks(webhookEvent)
+	if webhooks.Err != nil {
+		return webhooks.Err
+	}
+	if len(webhooks.Data) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(model.WebhookPayload{
+		ID:        event.ID,
+		Event:     webhookEvent,
+		CreatedAt: event.OccurredAt.UTC(),
+		Data:      raw,
+	})
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]model.WebhookDelivery, 0, len(webhooks.Data))
+	for _, webhook := range webhooks.Data {
+		delivery := newDelivery(webhook.ID, webhookEvent, string(payload))
+		delivery.ID = uuid.NewSHA1(event.ID, webhook.ID[:])
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := s.store.CreateWebhookDeliveries(deliveries); err != nil {
+		return err
+	}
+	s.notifyWorker()
+
+	return nil
+}
+
+// StartDelivering sends due deliveries in the background, every PollInterval
+// and whenever something is queued
+func (s *WebhookService) StartDelivering() {
+	go func() {
+		ticker := time.NewTicker(s.cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.DeliverDue(); err != nil {
+				log.Printf("failed to deliver webhooks: %v", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// DeliverDue makes one attempt at every delivery that is due
+func (s *WebhookService) DeliverDue() error {
+	webhooks := map[uuid.UUID]*model.Webhook{}
+
+	for {
+		due := s.store.GetDueWebhookDeliveries(time.Now(), deliveryBatchSize)
+		if due.Err != nil {
+			return due.Err
+		}
+
+		for _, delivery := range due.Data {
+			webhook, ok := webhooks[delivery.WebhookID]
+			if !ok {
+				if result := s.store.GetWebhook(delivery.WebhookID); result.Err == nil {
+					webhook = &result.Data
+				}
+				webhooks[delivery.WebhookID] = webhook
+			}
+
+			if err := s.attempt(webhook, delivery); err != nil {
+				return err
+			}
+		}
+
+		if len(due.Data) < deliveryBatchSize {
+			return nil
+		}
+	}
+}
+
+// attempt posts the delivery once and schedules a retry if it failed
+func (s *WebhookService) attempt(webhook *model.Webhook, delivery model.WebhookDelivery) error {
+	now := time.Now()
+
+	// Deliveries of deleted or disabled webhooks are dropped
+	if webhook == nil || !webhook.Active {
+		delivery.Status = model.DeliveryFailed
+		delivery.Error = "webhook is disabled"
+		delivery.NextAttemptAt = nil
+		return s.store.UpdateWebhookDelivery(delivery)
+	}
+
+	delivery.Attempts++
+	delivery.ResponseCode, delivery.Error = s.post(webhook, delivery, now)
+
+	if delivery.Error == "" {
+		delivery.Status = model.DeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	} else if delivery.Attempts >= s.cfg.MaxAttempts {
+		delivery.Status = model.DeliveryFailed
+		delivery.NextAttemptAt = nil
+	} else {
+		next := now.Add(s.retryBackoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := s.store.UpdateWebhookDelivery(delivery); err != nil {
+		return err
+	}
+
+	// Only final outcomes count towards disabling the webhook
+	if delivery.Status == model.DeliveryPending {
+		return nil
+	}
+	disabled, err := s.store.RecordWebhookResult(webhook.ID, delivery.Status == model.DeliverySucceeded, s.cfg.DisableAfter)
+	if err != nil {
+		return err
+	}
+	if disabled {
+		webhook.Active = false
+		log.Printf("disabled webhook %s after %d failed deliveries in a row", webhook.ID, s.cfg.DisableAfter)
+	}
+
+	return nil
+}
+
+// post sends the signed payload and returns the response code and, when the
+// attempt failed, why
+func (s *WebhookService) post(webhook *model.Webhook, delivery model.WebhookDelivery, now time.Time) (int, string) {
+	body := []byte(delivery.Payload)
+	timestamp := now.Unix()
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Go-Ideas-Webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", string(delivery.Event))
+	req.Header.Set("X-Webhook-Delivery", delivery.ID.String())
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	// Drain a little so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Sprintf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, ""
+}
+
+// retryBackoff is the wait after the given number of failed attempts
+func (s *WebhookService) retryBackoff(attempts int) time.Duration {
+	backoff := s.cfg.RetryBackoff
+	for i := 1; i < attempts && backoff < s.cfg.MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, s.cfg.MaxRetryBackoff)
+}
+
+func (s *WebhookService) notifyWorker() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// SignWebhookPayload is the hex HMAC-SHA256 of "timestamp.body" with the
+// webhook secret, receivers compute the same to verify a delivery
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func checkWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	return nil
+}
+
+func newDelivery(webhookID uuid.UUID, event model.WebhookEvent, payload string) model.WebhookDelivery {
+	now := time.Now()
+	return model.WebhookDelivery{
+		ID:            uuid.New(),
+		WebhookID:     webhookID,
+		Event:         event,
+		Payload:       payload,
+		Status:        model.DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+func uniqueEvents(events []model.WebhookEvent) []model.WebhookEvent {
+	unique := make([]model.WebhookEvent, 0, len(events))
+	seen := map[model.WebhookEvent]bool{}
+	for _, event := range events {
+		if !seen[event] {
+			seen[event] = true
+			unique = append(unique, event)
+		}
+	}
+	return unique
+}
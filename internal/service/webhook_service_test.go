@@ -0,0 +1,493 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"test_project/test/internal/config"
+	"test_project/test/internal/model"
+	"test_project/test/internal/storage"
+	utils "test_project/test/pkg"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memWebhookStore keeps webhooks and deliveries in memory, it behaves like
+// the Postgres store as far as the service can tell
+type memWebhookStore struct {
+	webhooks   map[uuid.UUID]model.Webhook
+	deliveries map[uuid.UUID]model.WebhookDelivery
+}
+
+func newMemWebhookStore() *memWebhookStore {
+	return &memWebhookStore{map[uuid.UUID]model.Webhook{}, map[uuid.UUID]model.WebhookDelivery{}}
+}
+
+func (m *memWebhookStore) CreateWebhook(webhook model.Webhook) error {
+	m.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+func (m *memWebhookStore) GetWebhooks() utils.Result[[]model.Webhook] {
+	var webhooks []model.Webhook
+	for _, webhook := range m.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	return utils.Result[[]model.Webhook]{Data: webhooks}
+}
+
+func (m *memWebhookStore) GetWebhook(id uuid.UUID) utils.Result[model.Webhook] {
+	webhook, ok := m.webhooks[id]
+	if !ok {
+		return utils.Result[model.Webhook]{Err: storage.ErrWebhookNotFound}
+	}
+	return utils.Result[model.Webhook]{Data: webhook}
+}
+
+func (m *memWebhookStore) GetSubscribedWebhooks(event model.WebhookEvent) utils.Result[[]model.Webhook] {
+	var webhooks []model.Webhook
+	for _, webhook := range m.webhooks {
+		if !webhook.Active {
+			continue
+		}
+		for _, e := range webhook.Events {
+			if e == event {
+				webhooks = append(webhooks, webhook)
+			}
+		}
+	}
+	return utils.Result[[]model.Webhook]{Data: webhooks}
+}
+
+func (m *memWebhookStore) UpdateWebhook(webhook model.Webhook) error {
+	m.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+func (m *memWebhookStore) DeleteWebhook(id uuid.UUID) error {
+	delete(m.webhooks, id)
+	return nil
+}
+
+func (m *memWebhookStore) RecordWebhookResult(id uuid.UUID, succeeded bool, disableAfter int) (bool, error) {
+	webhook := m.webhooks[id]
+	defer func() { m.webhooks[id] = webhook }()
+
+	if succeeded {
+		webhook.ConsecutiveFailures = 0
+		return false, nil
+	}
+
+	webhook.ConsecutiveFailures++
+	if webhook.Active && webhook.ConsecutiveFailures >= disableAfter {
+		now := time.Now()
+		webhook.Active = false
+		webhook.DisabledAt = &now
+		return true, nil
+	}
+	return false, nil
+}
+
+func (m *memWebhookStore) CreateWebhookDeliveries(deliveries []model.WebhookDelivery) error {
+	for _, delivery := range deliveries {
+		if _, ok := m.deliveries[delivery.ID]; !ok {
+			m.deliveries[delivery.ID] = delivery
+		}
+	}
+	return nil
+}
+
+func (m *memWebhookStore) GetDueWebhookDeliveries(now time.Time, limit int) utils.Result[[]model.WebhookDelivery] {
+	var due []model.WebhookDelivery
+	for _, delivery := range m.deliveries {
+		if delivery.Status == model.DeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].CreatedAt.Before(due[j].CreatedAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return utils.Result[[]model.WebhookDelivery]{Data: due}
+}
+
+func (m *memWebhookStore) GetWebhookDeliveries(webhookID uuid.UUID, limit int) utils.Result[[]model.WebhookDelivery] {
+	var deliveries []model.WebhookDelivery
+	for _, delivery := range m.deliveries {
+		if delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return utils.Result[[]model.WebhookDelivery]{Data: deliveries}
+}
+
+func (m *memWebhookStore) GetWebhookDelivery(id uuid.UUID) utils.Result[model.WebhookDelivery] {
+	delivery, ok := m.deliveries[id]
+	if !ok {
+		return utils.Result[model.WebhookDelivery]{Err: storage.ErrDeliveryNotFound}
+	}
+	return utils.Result[model.WebhookDelivery]{Data: delivery}
+}
+
+func (m *memWebhookStore) UpdateWebhookDelivery(delivery model.WebhookDelivery) error {
+	m.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+// receiver is a webhook endpoint answering with the given status codes in
+// turn, the last one repeats
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, receivedRequest{req.Header.Clone(), body})
+		status := r.statuses[min(len(r.requests), len(r.statuses))-1]
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedRequest(nil), r.requests...)
+}
+
+func testWebhookConfig() config.WebhookConfig {
+	return config.WebhookConfig{
+		Timeout:         5 * time.Second,
+		MaxAttempts:     3,
+		RetryBackoff:    time.Minute,
+		MaxRetryBackoff: 3 * time.Minute,
+		DisableAfter:    2,
+		PollInterval:    time.Hour,
+	}
+}
+
+// newTestWebhook creates a webhook for the receiver subscribed to created
+// ideas and returns it with its secret
+func newTestWebhook(t *testing.T, s *WebhookService, url string) (model.Webhook, string) {
+	t.Helper()
+	result := s.CreateWebhook(model.CreateWebhookPayload{URL: url, Events: []model.WebhookEvent{model.WebhookIdeaCreated}})
+	if result.Err != nil {
+		t.Fatalf("CreateWebhook: %v", result.Err)
+	}
+	return result.Data.Webhook, result.Data.Secret
+}
+
+func publishIdeaCreated(t *testing.T, s *WebhookService, title string) {
+	t.Helper()
+	event := model.OutboxEvent{ID: uuid.New(), OccurredAt: time.Now()}
+	if err := s.publish(event, model.WebhookIdeaCreated, model.Idea{ID: uuid.New(), Title: title}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+}
+
+// makeDue moves every pending retry to now, so the next DeliverDue sends it
+func makeDue(store *memWebhookStore) {
+	now := time.Now()
+	for id, delivery := range store.deliveries {
+		if delivery.NextAttemptAt != nil {
+			delivery.NextAttemptAt = &now
+			store.deliveries[id] = delivery
+		}
+	}
+}
+
+func onlyDelivery(t *testing.T, store *memWebhookStore) model.WebhookDelivery {
+	t.Helper()
+	if len(store.deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(store.deliveries))
+	}
+	for _, delivery := range store.deliveries {
+		return delivery
+	}
+	return model.WebhookDelivery{}
+}
+
+func TestWebhookDeliveryIsSigned(t *testing.T) {
+	recv := newReceiver(t, http.StatusOK)
+	store := newMemWebhookStore()
+	s := NewWebhookService(store, nil, recv.Client(), testWebhookConfig())
+	_, secret := newTestWebhook(t, s, recv.URL)
+
+	publishIdeaCreated(t, s, "Dark mode")
+	if err := s.DeliverDue(); err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+
+	requests := recv.received()
+	if len(requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+
+	timestamp := req.header.Get("X-Webhook-Timestamp")
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(req.body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.header.Get("X-Webhook-Signature") != want {
+		t.Errorf("signature = %q, want %q", req.header.Get("X-Webhook-Signature"), want)
+	}
+	if ts, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(ts, 0)) > time.Minute {
+		t.Errorf("timestamp = %q, want the current unix time", timestamp)
+	}
+	if got := req.header.Get("X-Webhook-Event"); got != string(model.WebhookIdeaCreated) {
+		t.Errorf("event header = %q, want %q", got, model.WebhookIdeaCreated)
+	}
+
+	delivery := onlyDelivery(t, store)
+	if got := req.header.Get("X-Webhook-Delivery"); got != delivery.ID.String() {
+		t.Errorf("delivery header = %q, want %q", got, delivery.ID)
+	}
+
+	var payload model.WebhookPayload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	var idea model.Idea
+	if err := json.Unmarshal(payload.Data, &idea); err != nil || idea.Title != "Dark mode" {
+		t.Errorf("payload data = %s, want the created idea", payload.Data)
+	}
+
+	if delivery.Status != model.DeliverySucceeded || delivery.ResponseCode != http.StatusOK || delivery.DeliveredAt == nil {
+		t.Errorf("delivery = %+v, want succeeded with 200", delivery)
+	}
+}
+
+func TestWebhookSignatureRejectsOtherSecret(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	signature := SignWebhookPayload("whsec_a", 1700000000, body)
+
+	if signature == SignWebhookPayload("whsec_b", 1700000000, body) {
+		t.Error("different secrets give the same signature")
+	}
+	if signature == SignWebhookPayload("whsec_a", 1700000001, body) {
+		t.Error("different timestamps give the same signature")
+	}
+}
+
+func TestWebhookRetriesServerErrorsWithBackoff(t *testing.T) {
+	recv := newReceiver(t, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK)
+	store := newMemWebhookStore()
+	cfg := testWebhookConfig()
+	s := NewWebhookService(store, nil, recv.Client(), cfg)
+	webhook, _ := newTestWebhook(t, s, recv.URL)
+
+	publishIdeaCreated(t, s, "Dark mode")
+
+	for attempt, wantBackoff := range []time.Duration{time.Minute, 2 * time.Minute} {
+		before := time.Now()
+		if err := s.DeliverDue(); err != nil {
+			t.Fatalf("DeliverDue: %v", err)
+		}
+
+		delivery := onlyDelivery(t, store)
+		if delivery.Status != model.DeliveryPending || delivery.Attempts != attempt+1 {
+			t.Fatalf("after attempt %d: delivery = %+v, want pending", attempt+1, delivery)
+		}
+		if delivery.ResponseCode < 500 || delivery.Error == "" {
+			t.Errorf("after attempt %d: response %d, error %q, want the 5xx recorded", attempt+1, delivery.ResponseCode, delivery.Error)
+		}
+		if delivery.NextAttemptAt == nil || delivery.NextAttemptAt.Before(before.Add(wantBackoff)) || delivery.NextAttemptAt.After(time.Now().Add(wantBackoff)) {
+			t.Errorf("after attempt %d: next attempt at %v, want in %v", attempt+1, delivery.NextAttemptAt, wantBackoff)
+		}
+
+		// Not due yet, nothing is sent
+		if err := s.DeliverDue(); err != nil {
+			t.Fatalf("DeliverDue: %v", err)
+		}
+		if got := len(recv.received()); got != attempt+1 {
+			t.Fatalf("receiver got %d requests before the backoff ran out, want %d", got, attempt+1)
+		}
+		makeDue(store)
+	}
+
+	if err := s.DeliverDue(); err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+	if delivery := onlyDelivery(t, store); delivery.Status != model.DeliverySucceeded || delivery.Attempts != 3 {
+		t.Errorf("delivery = %+v, want succeeded on the third attempt", delivery)
+	}
+	if !store.webhooks[webhook.ID].Active || store.webhooks[webhook.ID].ConsecutiveFailures != 0 {
+		t.Errorf("webhook = %+v, want active without failures", store.webhooks[webhook.ID])
+	}
+}
+
+func TestWebhookRetryBackoffIsCapped(t *testing.T) {
+	s := NewWebhookService(newMemWebhookStore(), nil, nil, testWebhookConfig())
+
+	want := []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}
+	for i, backoff := range want {
+		if got := s.retryBackoff(i + 1); got != backoff {
+			t.Errorf("retryBackoff(%d) = %v, want %v", i+1, got, backoff)
+		}
+	}
+}
+
+func TestWebhookDisabledAfterFailedDeliveries(t *testing.T) {
+	recv := newReceiver(t, http.StatusInternalServerError)
+	store := newMemWebhookStore()
+	cfg := testWebhookConfig()
+	cfg.MaxAttempts = 1
+	s := NewWebhookService(store, nil, recv.Client(), cfg)
+	webhook, _ := newTestWebhook(t, s, recv.URL)
+
+	for _, title := range []string{"One", "Two", "Three"} {
+		publishIdeaCreated(t, s, title)
+		time.Sleep(time.Millisecond)
+	}
+	if err := s.DeliverDue(); err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+
+	// The first two used up their attempts and disabled the webhook, the
+	// third is dropped without being sent
+	if got := len(recv.received()); got != cfg.DisableAfter {
+		t.Errorf("receiver got %d requests, want %d", got, cfg.DisableAfter)
+	}
+	disabled := store.webhooks[webhook.ID]
+	if disabled.Active || disabled.DisabledAt == nil || disabled.ConsecutiveFailures != cfg.DisableAfter {
+		t.Errorf("webhook = %+v, want disabled after %d failures", disabled, cfg.DisableAfter)
+	}
+	for _, delivery := range store.deliveries {
+		if delivery.Status != model.DeliveryFailed || delivery.NextAttemptAt != nil {
+			t.Errorf("delivery = %+v, want failed without retry", delivery)
+		}
+	}
+
+	// Nothing new is queued for a disabled webhook
+	publishIdeaCreated(t, s, "Four")
+	if len(store.deliveries) != 3 {
+		t.Errorf("got %d deliveries, want 3", len(store.deliveries))
+	}
+
+	// Enabling it again clears the streak
+	active := true
+	if result := s.UpdateWebhook(webhook.ID, model.UpdateWebhookPayload{Active: &active}); result.Err != nil {
+		t.Fatalf("UpdateWebhook: %v", result.Err)
+	}
+	if enabled := store.webhooks[webhook.ID]; !enabled.Active || enabled.ConsecutiveFailures != 0 || enabled.DisabledAt != nil {
+		t.Errorf("webhook = %+v, want active without failures", enabled)
+	}
+}
+
+func TestWebhookRedeliverSendsThePayloadAgain(t *testing.T) {
+	recv := newReceiver(t, http.StatusOK)
+	store := newMemWebhookStore()
+	s := NewWebhookService(store, nil, recv.Client(), testWebhookConfig())
+	webhook, _ := newTestWebhook(t, s, recv.URL)
+
+	publishIdeaCreated(t, s, "Dark mode")
+	if err := s.DeliverDue(); err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+	original := onlyDelivery(t, store)
+
+	result := s.Redeliver(webhook.ID, original.ID)
+	if result.Err != nil {
+		t.Fatalf("Redeliver: %v", result.Err)
+	}
+	if result.Data.RedeliveryOf == nil || *result.Data.RedeliveryOf != original.ID || result.Data.ID == original.ID {
+		t.Errorf("redelivery = %+v, want a new delivery of %s", result.Data, original.ID)
+	}
+
+	if err := s.DeliverDue(); err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+	requests := recv.received()
+	if len(requests) != 2 || string(requests[1].body) != string(requests[0].body) {
+		t.Fatalf("receiver got %d requests, want the same payload twice", len(requests))
+	}
+	if got := requests[1].header.Get("X-Webhook-Delivery"); got != result.Data.ID.String() {
+		t.Errorf("delivery header = %q, want %q", got, result.Data.ID)
+	}
+
+	// Disabled webhooks can't redeliver
+	inactive := false
+	s.UpdateWebhook(webhook.ID, model.UpdateWebhookPayload{Active: &inactive})
+	if result := s.Redeliver(webhook.ID, original.ID); result.Err != ErrWebhookInactive {
+		t.Errorf("Redeliver on a disabled webhook: %v, want %v", result.Err, ErrWebhookInactive)
+	}
+}
+
+type countingVoteStore struct {
+	storage.VoteStorage
+}
+
+func (countingVoteStore) GetVoteCount(ideaID uuid.UUID) utils.Result[int] {
+	return utils.Result[int]{Data: 3}
+}
+
+func TestWebhookEventsOnlyForPublicIdeas(t *testing.T) {
+	store := newMemWebhookStore()
+	s := NewWebhookService(store, countingVoteStore{}, nil, testWebhookConfig())
+	result := s.CreateWebhook(model.CreateWebhookPayload{
+		URL:    "https://example.com/hook",
+		Events: []model.WebhookEvent{model.WebhookIdeaDeleted, model.WebhookVoteAdded, model.WebhookVoteRemoved},
+	})
+	if result.Err != nil {
+		t.Fatalf("CreateWebhook: %v", result.Err)
+	}
+
+	bus := NewEventBus(nil, config.EventConfig{})
+	s.Subscribe(bus)
+
+	ideaID, userID := uuid.New(), uuid.New()
+	for _, visibility := range []model.Visibility{model.VisibilityDraft, model.VisibilityPrivate, model.VisibilityUnlisted, model.VisibilityPublic} {
+		for _, payload := range []model.EventPayload{
+			model.IdeaDeleted{IdeaID: ideaID, Visibility: visibility},
+			model.VoteAdded{IdeaID: ideaID, UserID: userID, Visibility: visibility},
+			model.VoteRemoved{IdeaID: ideaID, UserID: userID, Visibility: visibility},
+		} {
+			event, err := Event(payload)
+			if err != nil {
+				t.Fatalf("Event: %v", err)
+			}
+			if dispatched := bus.dispatch(event); dispatched.LastError != "" {
+				t.Fatalf("dispatching %s: %s", event.Type, dispatched.LastError)
+			}
+		}
+	}
+
+	if len(store.deliveries) != 3 {
+		t.Fatalf("got %d deliveries, want one per event of the public idea", len(store.deliveries))
+	}
+	for _, delivery := range store.deliveries {
+		var payload model.WebhookPayload
+		if err := json.Unmarshal([]byte(delivery.Payload), &payload); err != nil {
+			t.Fatalf("payload is not JSON: %v", err)
+		}
+		if delivery.Event == model.WebhookVoteAdded {
+			var vote model.VoteData
+			if err := json.Unmarshal(payload.Data, &vote); err != nil || vote.IdeaID != ideaID || vote.VoteCount != 3 {
+				t.Errorf("vote data = %s, want the idea and its count", payload.Data)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"fmt"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AssignIdea is idempotent, the history entry is only recorded when the user
+// wasn't assigned yet
+func (ps *PostgresStore) AssignIdea(ideaID, userID uuid.UUID, entry model.StatusTransition) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		assignee := model.IdeaAssignee{IdeaID: ideaID, UserID: userID, CreatedAt: time.Now()}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&assignee)
+		if result.Error != nil {
+			return fmt.Errorf("failed to assign idea: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Create(&entry).Error; err != nil {
+			return fmt.Errorf("failed to record assignment: %v", err)
+		}
+		return nil
+	})
+}
+
+// UnassignIdea is idempotent like AssignIdea
+func (ps *PostgresStore) UnassignIdea(ideaID, userID uuid.UUID, entry model.StatusTransition) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("idea_id = ? AND user_id = ?", ideaID, userID).Delete(&model.IdeaAssignee{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to unassign idea: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Create(&entry).Error; err != nil {
+			return fmt.Errorf("failed to record assignment: %v", err)
+		}
+		return nil
+	})
+}
+
+// GetAssignedIdeas returns the ideas the user is assigned to, most recently
+// assigned first
+func (ps *PostgresStore) GetAssignedIdeas(userID uuid.UUID) utils.Result[[]model.Idea] {
+	var ideas []model.Idea
+	err := ps.db.Preload("Votes").
+		Joins("JOIN idea_assignees ON idea_assignees.idea_id = ideas.id").
+		Where("idea_assignees.user_id = ? AND ideas.merged_into IS NULL", userID).
+		Order("idea_assignees.created_at DESC").
+		Find(&ideas).Error
+	if err != nil {
+		return utils.Result[[]model.Idea]{Err: fmt.Errorf("failed to get assigned ideas: %v", err)}
+	}
+
+	for i := range ideas {
+		ideas[i].VoteCount = len(ideas[i].Votes)
+	}
+	if err := ps.fillCommentCounts(ideas); err != nil {
+		return utils.Result[[]model.Idea]{Err: err}
+	}
+	if err := ps.fillTags(ideas); err != nil {
+		return utils.Result[[]model.Idea]{Err: err}
+	}
+	if err := ps.fillAssignees(ideas); err != nil {
+		return utils.Result[[]model.Idea]{Err: err}
+	}
+	if err := ps.fillLinks(ideas); err != nil {
+		return utils.Result[[]model.Idea]{Err: err}
+	}
+
+	return utils.Result[[]model.Idea]{Data: ideas}
+}
+
+// fillAssignees sets Assignees on each idea, sorted by username
+func (ps *PostgresStore) fillAssignees(ideas []model.Idea) error {
+	if len(ideas) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(ideas))
+	for _, idea := range ideas {
+		ids = append(ids, idea.ID)
+	}
+
+	var rows []struct {
+		IdeaID   uuid.UUID
+		Username string
+	}
+	if err := ps.db.Table("idea_assignees").
+		Select("idea_assignees.idea_id, users.username").
+		Joins("JOIN users ON users.id = idea_assignees.user_id").
+		Where("idea_assignees.idea_id IN ?", ids).
+		Order("users.username").
+		Scan(&rows).Error; err != nil {
+		return fmt.Errorf("failed to get idea assignees: %v", err)
+	}
+
+	assignees := make(map[uuid.UUID][]string)
+	for _, row := range rows {
+		assignees[row.IdeaID] = append(assignees[row.IdeaID], row.Username)
+	}
+	for i := range ideas {
+		ideas[i].Assignees = assignees[ideas[i].ID]
+		if ideas[i].Assignees == nil {
+			ideas[i].Assignees = []string{}
+		}
+	}
+	return nil
+}
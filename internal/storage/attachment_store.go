@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+func (ps *PostgresStore) CreateAttachment(attachment model.Attachment) error {
+	if err := ps.db.Create(&attachment).Error; err != nil {
+		return fmt.Errorf("failed to save attachment: %v", err)
+	}
+	return nil
+}
+
+// GetAttachments returns the attachments of an idea, oldest first
+func (ps *PostgresStore) GetAttachments(ideaID uuid.UUID) utils.Result[[]model.Attachment] {
+	attachments := []model.Attachment{}
+	if err := ps.db.Where("idea_id = ?", ideaID).Order("created_at").Find(&attachments).Error; err != nil {
+		return utils.Result[[]model.Attachment]{Err: fmt.Errorf("failed to get attachments: %v", err)}
+	}
+	return utils.Result[[]model.Attachment]{Data: attachments}
+}
+
+func (ps *PostgresStore) GetAttachment(id uuid.UUID) utils.Result[model.Attachment] {
+	var attachment model.Attachment
+	if err := ps.db.First(&attachment, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.Result[model.Attachment]{Err: fmt.Errorf("%w: %s", ErrAttachmentNotFound, id)}
+		}
+		return utils.Result[model.Attachment]{Err: fmt.Errorf("failed to get attachment: %v", err)}
+	}
+	return utils.Result[model.Attachment]{Data: attachment}
+}
+
+func (ps *PostgresStore) CountAttachments(ideaID uuid.UUID) (int64, error) {
+	var count int64
+	if err := ps.db.Model(&model.Attachment{}).Where("idea_id = ?", ideaID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count attachments: %v", err)
+	}
+	return count, nil
+}
+
+// DeleteAttachment removes the record, the blob is left to the garbage
+// collector
+func (ps *PostgresStore) DeleteAttachment(id uuid.UUID) error {
+	result := ps.db.Where("id = ?", id).Delete(&model.Attachment{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete attachment: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrAttachmentNotFound, id)
+	}
+	return nil
+}
+
+// GetAttachedBlobKeys returns which of the keys still belong to an attachment
+func (ps *PostgresStore) GetAttachedBlobKeys(keys []string) (map[string]bool, error) {
+	attached := make(map[string]bool, len(keys))
+	if len(keys) == 0 {
+		return attached, nil
+	}
+
+	var found []string
+	if err := ps.db.Model(&model.Attachment{}).Where("blob_key IN ?", keys).Pluck("blob_key", &found).Error; err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %v", err)
+	}
+	for _, key := range found {
+		attached[key] = true
+	}
+	return attached, nil
+}
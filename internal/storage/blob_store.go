@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBlobNotFound   = errors.New("blob not found")
+	ErrInvalidBlobKey = errors.New("invalid blob key")
+)
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// BlobStore keeps file contents outside the database. Keys are chosen by the
+// caller and consist of letters, digits and dashes.
+type BlobStore interface {
+	// Put stores the content under key and returns the number of bytes
+	// written. A failed Put leaves nothing behind.
+	Put(key string, content io.Reader) (int64, error)
+	// Open returns the content, seekable so it can serve range requests
+	Open(key string) (io.ReadSeekCloser, error)
+	Delete(key string) error
+	// List returns every stored blob
+	List() ([]BlobInfo, error)
+}
+
+// LocalBlobStore keeps blobs as files below a directory. Blobs are spread
+// over subdirectories named after the first two characters of the key.
+type LocalBlobStore struct {
+	root string
+}
+
+func NewLocalBlobStore(root string) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %v", err)
+	}
+	return &LocalBlobStore{root: root}, nil
+}
+
+func (s *LocalBlobStore) Put(key string, content io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return 0, fmt.Errorf("failed to store blob: %v", err)
+	}
+
+	// Written to a temporary file first so a half written blob never shows
+	// up under its key
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to store blob: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return n, fmt.Errorf("failed to store blob: %v", err)
+	}
+	return n, nil
+}
+
+func (s *LocalBlobStore) Open(key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, key)
+		}
+		return nil, fmt.Errorf("failed to open blob: %v", err)
+	}
+	return file, nil
+}
+
+func (s *LocalBlobStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %v", err)
+	}
+	return nil
+}
+
+func (s *LocalBlobStore) List() ([]BlobInfo, error) {
+	var blobs []BlobInfo
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skips directories and uploads still in progress
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, BlobInfo{Key: d.Name(), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blobs: %v", err)
+	}
+	return blobs, nil
+}
+
+func (s *LocalBlobStore) path(key string) (string, error) {
+	if len(key) < 3 || strings.IndexFunc(key, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-')
+	}) >= 0 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidBlobKey, key)
+	}
+	return filepath.Join(s.root, key[:2], key), nil
+}
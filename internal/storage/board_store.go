@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrBoardNotFound  = errors.New("board not found")
+	ErrBoardExists    = errors.New("a board with that slug already exists")
+	ErrMemberNotFound = errors.New("not a member of the board")
+)
+
+func (ps *PostgresStore) CreateBoard(board model.Board) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&model.Board{}).Where("slug = ?", board.Slug).Count(&existing).Error; err != nil {
+			return fmt.Errorf("failed to create board: %v", err)
+		}
+		if existing > 0 {
+			return fmt.Errorf("%w: %s", ErrBoardExists, board.Slug)
+		}
+
+		if err := tx.Create(&board).Error; err != nil {
+			return fmt.Errorf("failed to create board: %v", err)
+		}
+		return nil
+	})
+}
+
+func (ps *PostgresStore) GetBoards() utils.Result[[]model.Board] {
+	var boards []model.Board
+	if err := ps.db.Order("name").Find(&boards).Error; err != nil {
+		return utils.Result[[]model.Board]{Err: fmt.Errorf("failed to get boards: %v", err)}
+	}
+
+	return utils.Result[[]model.Board]{Data: boards}
+}
+
+func (ps *PostgresStore) GetBoard(id uuid.UUID) utils.Result[model.Board] {
+	return ps.getBoard("id = ?", id)
+}
+
+func (ps *PostgresStore) GetBoardBySlug(slug string) utils.Result[model.Board] {
+	return ps.getBoard("slug = ?", slug)
+}
+
+func (ps *PostgresStore) getBoard(query string, arg any) utils.Result[model.Board] {
+	var board model.Board
+	if err := ps.db.First(&board, query, arg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.Result[model.Board]{Err: ErrBoardNotFound}
+		}
+		return utils.Result[model.Board]{Err: fmt.Errorf("failed to get board: %v", err)}
+	}
+
+	return utils.Result[model.Board]{Data: board}
+}
+
+func (ps *PostgresStore) UpdateBoard(board model.Board) error {
+	board.UpdatedAt = time.Now()
+
+	// Save writes zero values too, which matters for the flags
+	if err := ps.db.Save(&board).Error; err != nil {
+		return fmt.Errorf("failed to update board: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteBoard deletes an empty board together with its members
+func (ps *PostgresStore) DeleteBoard(id uuid.UUID) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("board_id = ?", id).Delete(&model.BoardMembership{}).Error; err != nil {
+			return fmt.Errorf("failed to delete board members: %v", err)
+		}
+
+		result := tx.Where("id = ?", id).Delete(&model.Board{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete board: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrBoardNotFound
+		}
+
+		return nil
+	})
+}
+
+// CountBoardIdeas counts the ideas on the board, merged ones included
+func (ps *PostgresStore) CountBoardIdeas(boardID uuid.UUID) (int64, error) {
+	var count int64
+	if err := ps.db.Model(&model.Idea{}).Where("board_id = ?", boardID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count board ideas: %v", err)
+	}
+
+	return count, nil
+}
+
+// GetBoardMembers returns the members with their usernames, owners first
+func (ps *PostgresStore) GetBoardMembers(boardID uuid.UUID) utils.Result[[]model.BoardMembership] {
+	var members []model.BoardMembership
+	if err := ps.db.Table("board_memberships").
+		Select("board_memberships.*, users.username").
+		Joins("JOIN users ON users.id = board_memberships.user_id").
+		Where("board_memberships.board_id = ?", boardID).
+		Order("board_memberships.role = 'owner' DESC, users.username").
+		Scan(&members).Error; err != nil {
+		return utils.Result[[]model.BoardMembership]{Err: fmt.Errorf("failed to get board members: %v", err)}
+	}
+
+	return utils.Result[[]model.BoardMembership]{Data: members}
+}
+
+// GetBoardMembership returns ErrMemberNotFound for users who are not members
+func (ps *PostgresStore) GetBoardMembership(boardID, userID uuid.UUID) (model.BoardMembership, error) {
+	var member model.BoardMembership
+	if err := ps.db.First(&member, "board_id = ? AND user_id = ?", boardID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.BoardMembership{}, ErrMemberNotFound
+		}
+		return model.BoardMembership{}, fmt.Errorf("failed to get board member: %v", err)
+	}
+
+	return member, nil
+}
+
+// SetBoardMember adds the user to the board or changes their role
+func (ps *PostgresStore) SetBoardMember(member model.BoardMembership) error {
+	if err := ps.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "board_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(&member).Error; err != nil {
+		return fmt.Errorf("failed to save board member: %v", err)
+	}
+
+	return nil
+}
+
+func (ps *PostgresStore) RemoveBoardMember(boardID, userID uuid.UUID) error {
+	result := ps.db.Where("board_id = ? AND user_id = ?", boardID, userID).Delete(&model.BoardMembership{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove board member: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrMemberNotFound
+	}
+
+	return nil
+}
+
+// seedDefaultBoard creates the default board and moves ideas from before
+// boards existed onto it
+func seedDefaultBoard(db *gorm.DB) error {
+	board := model.Board{
+		ID:         uuid.New(),
+		Slug:       model.DefaultBoardSlug,
+		Name:       "Ideas",
+		VotingOpen: true,
+	}
+	if err := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "slug"}}, DoNothing: true}).Create(&board).Error; err != nil {
+		return fmt.Errorf("failed to create the default board: %v", err)
+	}
+	if err := db.First(&board, "slug = ?", model.DefaultBoardSlug).Error; err != nil {
+		return fmt.Errorf("failed to get the default board: %v", err)
+	}
+
+	if err := db.Model(&model.Idea{}).Where("board_id IS NULL OR board_id = ?", uuid.Nil).
+		Update("board_id", board.ID).Error; err != nil {
+		return fmt.Errorf("failed to move ideas to the default board: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"test_project/test/pkg/markdown"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (ps *PostgresStore) CreateComment(comment model.Comment, events ...model.OutboxEvent) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return fmt.Errorf("failed to create comment: %v", err)
+		}
+		return writeOutbox(tx, events)
+	})
+}
+
+func (ps *PostgresStore) GetComment(id uuid.UUID) utils.Result[model.Comment] {
+	var comment model.Comment
+	if err := ps.db.Where("id = ?", id).First(&comment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.Result[model.Comment]{Err: fmt.Errorf("comment with id %s not found", id)}
+		}
+		return utils.Result[model.Comment]{Err: fmt.Errorf("failed to get comment: %v", err)}
+	}
+	return utils.Result[model.Comment]{Data: comment}
+}
+
+// GetComments returns all comments of an idea, oldest first and not nested
+func (ps *PostgresStore) GetComments(ideaID uuid.UUID) utils.Result[[]model.Comment] {
+	var comments []model.Comment
+	if err := ps.db.Where("idea_id = ?", ideaID).Order("created_at").Find(&comments).Error; err != nil {
+		return utils.Result[[]model.Comment]{Err: fmt.Errorf("failed to get comments: %v", err)}
+	}
+	return utils.Result[[]model.Comment]{Data: comments}
+}
+
+func (ps *PostgresStore) UpdateComment(comment model.Comment) error {
+	if err := ps.db.Model(&model.Comment{}).Where("id = ?", comment.ID).
+		Updates(map[string]any{"body": comment.Body, "body_html": comment.BodyHTML, "edited": true, "updated_at": comment.UpdatedAt}).Error; err != nil {
+		return fmt.Errorf("failed to update comment: %v", err)
+	}
+	return nil
+}
+
+// DeleteComment deletes the comment together with its replies
+func (ps *PostgresStore) DeleteComment(id uuid.UUID) error {
+	result := ps.db.Where("id = ? OR parent_id = ?", id, id).Delete(&model.Comment{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete comment: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("comment with id %s not found", id)
+	}
+	return nil
+}
+
+// fillCommentCounts sets CommentCount on each idea with a single grouped query
+func (ps *PostgresStore) fillCommentCounts(ideas []model.Idea) error {
+	if len(ideas) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(ideas))
+	for _, idea := range ideas {
+		ids = append(ids, idea.ID)
+	}
+
+	var rows []struct {
+		IdeaID uuid.UUID
+		Count  int
+	}
+	if err := ps.db.Model(&model.Comment{}).
+		Select("idea_id, COUNT(*) AS count").
+		Where("idea_id IN ?", ids).
+		Group("idea_id").
+		Scan(&rows).Error; err != nil {
+		return fmt.Errorf("failed to count comments: %v", err)
+	}
+
+	counts := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		counts[row.IdeaID] = row.Count
+	}
+	for i := range ideas {
+		ideas[i].CommentCount = counts[ideas[i].ID]
+	}
+
+	return nil
+}
+
+// migrateAnonymizedComments clears the author of comments anonymized before
+// the author became optional, those were given the zero ID
+func migrateAnonymizedComments(db *gorm.DB) error {
+	if err := db.Model(&model.Comment{}).Where("author_id = ?", uuid.Nil).Update("author_id", nil).Error; err != nil {
+		return fmt.Errorf("failed to clear anonymized comment authors: %v", err)
+	}
+	return nil
+}
+
+// migrateCommentBodyHTML renders the bodies of comments written before they
+// were rendered to HTML
+func migrateCommentBodyHTML(db *gorm.DB) error {
+	var comments []model.Comment
+	if err := db.Select("id, body").Where("body_html IS NULL OR body_html = ''").Find(&comments).Error; err != nil {
+		return fmt.Errorf("failed to read comment bodies: %v", err)
+	}
+
+	for _, comment := range comments {
+		if err := db.Model(&model.Comment{}).Where("id = ?", comment.ID).Update("body_html", markdown.Render(comment.Body)).Error; err != nil {
+			return fmt.Errorf("failed to render comment body: %v", err)
+		}
+	}
+	return nil
+}
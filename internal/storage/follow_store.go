@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"fmt"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// FollowIdea is idempotent, following an idea twice keeps the first follow
+func (ps *PostgresStore) FollowIdea(ideaID, userID uuid.UUID) error {
+	follow := model.Follow{IdeaID: ideaID, UserID: userID, CreatedAt: time.Now()}
+	if err := ps.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error; err != nil {
+		return fmt.Errorf("failed to follow idea: %v", err)
+	}
+
+	return nil
+}
+
+func (ps *PostgresStore) UnfollowIdea(ideaID, userID uuid.UUID) error {
+	if err := ps.db.Where("idea_id = ? AND user_id = ?", ideaID, userID).Delete(&model.Follow{}).Error; err != nil {
+		return fmt.Errorf("failed to unfollow idea: %v", err)
+	}
+
+	return nil
+}
+
+// GetFollowedIdeas returns the ideas the user follows, most recently followed first
+func (ps *PostgresStore) GetFollowedIdeas(userID uuid.UUID) utils.Result[[]model.Idea] {
+	var ideas []model.Idea
+	err := ps.db.Preload("Votes").
+		Joins("JOIN follows ON follows.idea_id = ideas.id").
+		Where("follows.user_id = ? AND ideas.merged_into IS NULL", userID).
+		Order("follows.created_at DESC").
+		Find(&ideas).Error
+	if err != nil {
+		return utils.Result[[]model.Idea]{Err: fmt.Errorf("failed to get followed ideas: %v", err)}
+	}
+
+	for i := range ideas {
+		ideas[i].VoteCount = len(ideas[i].Votes)
+	}
+	if err := ps.fillCommentCounts(ideas); err != nil {
+		return utils.Result[[]model.Idea]{Err: err}
+	}
+	if err := ps.fillTags(ideas); err != nil {
+		return utils.Result[[]model.Idea]{Err: err}
+	}
+	if err := ps.fillAssignees(ideas); err != nil {
+		return utils.Result[[]model.Idea]{Err: err}
+	}
+	if err := ps.fillLinks(ideas); err != nil {
+		return utils.Result[[]model.Idea]{Err: err}
+	}
+
+	return utils.Result[[]model.Idea]{Data: ideas}
+}
+
+func (ps *PostgresStore) GetFollowerIDs(ideaID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := ps.db.Model(&model.Follow{}).Where("idea_id = ?", ideaID).Pluck("user_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to get followers: %v", err)
+	}
+
+	return ids, nil
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"test_project/test/internal/model"
 	utils "test_project/test/pkg"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -10,21 +11,168 @@ import (
 type IdeaStorage interface {
 	GetAllIdeas() utils.Result[[]model.Idea]
 	GetIdea(id uuid.UUID) utils.Result[model.Idea]
-	CreateIdea(idea model.Idea) utils.Result[string]
-	UpdateIdea(id uuid.UUID, idea model.Idea) utils.Result[string]
-	DeleteIdea(id uuid.UUID) utils.Result[string]
+	CreateIdea(idea model.Idea, events ...model.OutboxEvent) utils.Result[string]
+	UpdateIdea(id uuid.UUID, idea model.Idea, events ...model.OutboxEvent) utils.Result[string]
+	DeleteIdea(id uuid.UUID, events ...model.OutboxEvent) utils.Result[string]
+}
+
+// StatusHistoryStorage changes an idea's status and records the transition
+// in one step, so the history never misses a change
+type StatusHistoryStorage interface {
+	UpdateIdeaStatus(id uuid.UUID, idea model.Idea, transition model.StatusTransition, events ...model.OutboxEvent) utils.Result[string]
+	GetStatusHistory(ideaID uuid.UUID) utils.Result[[]model.StatusTransition]
+	GetStatusEnteredAt(status model.RequestStatus) (map[uuid.UUID]time.Time, error)
+}
+
+type MergeStorage interface {
+	MergeIdea(sourceID, targetID uuid.UUID, events ...model.OutboxEvent) error
+}
+
+type FollowStorage interface {
+	FollowIdea(ideaID, userID uuid.UUID) error
+	UnfollowIdea(ideaID, userID uuid.UUID) error
+	GetFollowedIdeas(userID uuid.UUID) utils.Result[[]model.Idea]
+	GetFollowerIDs(ideaID uuid.UUID) ([]uuid.UUID, error)
+}
+
+type NotificationStorage interface {
+	CreateNotifications(notifications []model.Notification) error
+	GetNotifications(userID uuid.UUID, unreadOnly bool, limit int) utils.Result[[]model.Notification]
+	CountUnreadNotifications(userID uuid.UUID) (int64, error)
+	MarkNotificationRead(userID, id uuid.UUID) error
+	MarkAllNotificationsRead(userID uuid.UUID) (int64, error)
+	PruneNotifications(olderThan, readOlderThan time.Time) (int64, error)
+	GetNotificationPreferences(userIDs []uuid.UUID) (map[uuid.UUID]model.NotificationPreferences, error)
+	SaveNotificationPreferences(prefs model.NotificationPreferences) error
+}
+
+type WebhookStorage interface {
+	CreateWebhook(webhook model.Webhook) error
+	GetWebhooks() utils.Result[[]model.Webhook]
+	GetWebhook(id uuid.UUID) utils.Result[model.Webhook]
+	GetSubscribedWebhooks(event model.WebhookEvent) utils.Result[[]model.Webhook]
+	UpdateWebhook(webhook model.Webhook) error
+	DeleteWebhook(id uuid.UUID) error
+	RecordWebhookResult(id uuid.UUID, succeeded bool, disableAfter int) (bool, error)
+	CreateWebhookDeliveries(deliveries []model.WebhookDelivery) error
+	GetDueWebhookDeliveries(now time.Time, limit int) utils.Result[[]model.WebhookDelivery]
+	GetWebhookDeliveries(webhookID uuid.UUID, limit int) utils.Result[[]model.WebhookDelivery]
+	GetWebhookDelivery(id uuid.UUID) utils.Result[model.WebhookDelivery]
+	UpdateWebhookDelivery(delivery model.WebhookDelivery) error
+}
+
+// AttachmentStorage keeps the attachment records, the contents are in a
+// BlobStore
+type AttachmentStorage interface {
+	CreateAttachment(attachment model.Attachment) error
+	GetAttachments(ideaID uuid.UUID) utils.Result[[]model.Attachment]
+	GetAttachment(id uuid.UUID) utils.Result[model.Attachment]
+	CountAttachments(ideaID uuid.UUID) (int64, error)
+	DeleteAttachment(id uuid.UUID) error
+	GetAttachedBlobKeys(keys []string) (map[string]bool, error)
+}
+
+type BoardStorage interface {
+	CreateBoard(board model.Board) error
+	GetBoards() utils.Result[[]model.Board]
+	GetBoard(id uuid.UUID) utils.Result[model.Board]
+	GetBoardBySlug(slug string) utils.Result[model.Board]
+	UpdateBoard(board model.Board) error
+	DeleteBoard(id uuid.UUID) error
+	CountBoardIdeas(boardID uuid.UUID) (int64, error)
+	GetBoardMembers(boardID uuid.UUID) utils.Result[[]model.BoardMembership]
+	GetBoardMembership(boardID, userID uuid.UUID) (model.BoardMembership, error)
+	SetBoardMember(member model.BoardMembership) error
+	RemoveBoardMember(boardID, userID uuid.UUID) error
+}
+
+type AssigneeStorage interface {
+	AssignIdea(ideaID, userID uuid.UUID, entry model.StatusTransition) error
+	UnassignIdea(ideaID, userID uuid.UUID, entry model.StatusTransition) error
+	GetAssignedIdeas(userID uuid.UUID) utils.Result[[]model.Idea]
+}
+
+type MilestoneStorage interface {
+	CreateMilestone(milestone model.Milestone) error
+	GetMilestones(state model.MilestoneState) utils.Result[[]model.Milestone]
+	GetMilestone(id uuid.UUID) utils.Result[model.Milestone]
+	UpdateMilestone(milestone model.Milestone) error
+	DeleteMilestone(id uuid.UUID) error
+	SetIdeaMilestone(ideaID uuid.UUID, milestoneID *uuid.UUID) error
+}
+
+// OutboxStorage is the dispatching side of the outbox. Events are written by
+// the storage methods that take them, in the same transaction as the change.
+type OutboxStorage interface {
+	GetPendingEvents(now time.Time, limit int) utils.Result[[]model.OutboxEvent]
+	UpdateOutboxEvent(event model.OutboxEvent) error
+	PruneOutbox(before time.Time) (int64, error)
+}
+
+type TagStorage interface {
+	SearchTags(prefix string, limit int) utils.Result[[]model.TagUsage]
+	RenameTag(from, to string) error
+	MergeTags(sources []string, target string) error
+}
+
+type TechStorage interface {
+	CreateTechnology(tech model.Technology) error
+	GetTechnologies() utils.Result[[]model.Technology]
+	GetTechnology(id uuid.UUID) utils.Result[model.Technology]
+	UpdateTechnology(tech model.Technology) error
+	DeleteTechnology(id uuid.UUID) error
+	CountIdeasUsingTechnology(names []string) (int64, error)
+}
+
+type RoadmapStorage interface {
+	SetRoadmapRanks(statuses []model.RequestStatus, ideaIDs []uuid.UUID) error
+}
+
+type CommentStorage interface {
+	CreateComment(comment model.Comment, events ...model.OutboxEvent) error
+	GetComment(id uuid.UUID) utils.Result[model.Comment]
+	GetComments(ideaID uuid.UUID) utils.Result[[]model.Comment]
+	UpdateComment(comment model.Comment) error
+	DeleteComment(id uuid.UUID) error
 }
 
 type UserStorage interface {
-	CreateUser(user model.User) error
+	CreateUser(user model.User, events ...model.OutboxEvent) error
+	CreateUserWithInvite(user model.User, codeHash string, events ...model.OutboxEvent) error
 	GetUserByUsername(username string) (model.User, error)
 	GetAllUsers() utils.Result[[]model.User]
-	DeleteUser(username string) (model.User, error)
+	DeleteAccount(userID uuid.UUID, policy model.DeletionPolicy, reassignTo model.User, events ...model.OutboxEvent) error
+	GetUserByID(id uuid.UUID) (model.User, error)
+	UpdateUser(user model.User) error
+	ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(userID uuid.UUID, codeHash string) error
+	CountTOTPAttempt(userID uuid.UUID, maxFailures int, lockout time.Duration) error
+	ResetTOTPFailures(userID uuid.UUID) error
+	UseTOTPStep(userID uuid.UUID, step int64) error
+	GetUserByEmail(email string) (model.User, error)
+	GetUserByIdentity(issuer, subject string) (model.User, error)
+	CreateIdentity(identity model.UserIdentity) error
+}
+
+type InviteStorage interface {
+	CreateInvite(invite model.Invite) error
+	GetInvites() utils.Result[[]model.Invite]
+	RevokeInvite(id uuid.UUID) error
+	GetInviteRedemptions(inviteID uuid.UUID) utils.Result[[]model.InviteRedemption]
+}
+
+type SessionStorage interface {
+	CreateSession(session model.Session) error
+	GetSession(id uuid.UUID) (model.Session, error)
+	GetActiveSessions(userID uuid.UUID) ([]model.Session, error)
+	TouchSession(id uuid.UUID, seenAt time.Time) error
+	RevokeSession(userID, id uuid.UUID) error
+	RevokeUserSessions(userID, keep uuid.UUID) (int64, error)
 }
 
 type VoteStorage interface {
-	AddVote(userID uuid.UUID, ideaID uuid.UUID) utils.Result[string]
-	RemoveVote(userID uuid.UUID, ideaID uuid.UUID) utils.Result[string]
+	AddVote(userID uuid.UUID, ideaID uuid.UUID, events ...model.OutboxEvent) utils.Result[string]
+	RemoveVote(userID uuid.UUID, ideaID uuid.UUID, events ...model.OutboxEvent) utils.Result[string]
 	HasUserVoted(userID uuid.UUID, ideaID uuid.UUID) utils.Result[bool]
 	GetVoteCount(ideaID uuid.UUID) utils.Result[int]
 }
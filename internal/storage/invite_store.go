@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (ps *PostgresStore) CreateInvite(invite model.Invite) error {
+	if err := ps.db.Create(&invite).Error; err != nil {
+		return fmt.Errorf("failed to create invite: %v", err)
+	}
+	return nil
+}
+
+func (ps *PostgresStore) GetInvites() utils.Result[[]model.Invite] {
+	var invites []model.Invite
+	if err := ps.db.Order("created_at DESC").Find(&invites).Error; err != nil {
+		return utils.Result[[]model.Invite]{Err: fmt.Errorf("failed to get invites: %v", err)}
+	}
+	return utils.Result[[]model.Invite]{Data: invites}
+}
+
+func (ps *PostgresStore) RevokeInvite(id uuid.UUID) error {
+	result := ps.db.Model(&model.Invite{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke invite: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invite not found")
+	}
+	return nil
+}
+
+func (ps *PostgresStore) GetInviteRedemptions(inviteID uuid.UUID) utils.Result[[]model.InviteRedemption] {
+	var redemptions []model.InviteRedemption
+	err := ps.db.Table("invite_redemptions").
+		Select("invite_redemptions.*, users.username").
+		Joins("LEFT JOIN users ON users.id = invite_redemptions.user_id").
+		Where("invite_redemptions.invite_id = ?", inviteID).
+		Order("invite_redemptions.redeemed_at").
+		Scan(&redemptions).Error
+	if err != nil {
+		return utils.Result[[]model.InviteRedemption]{Err: fmt.Errorf("failed to get redemptions: %v", err)}
+	}
+	return utils.Result[[]model.InviteRedemption]{Data: redemptions}
+}
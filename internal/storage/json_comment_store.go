@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+
+	"github.com/google/uuid"
+)
+
+func (js *JsonStore) CreateComment(comment model.Comment, events ...model.OutboxEvent) error {
+	comments, err := js.readComments()
+	if err != nil {
+		return err
+	}
+
+	if err := js.writeComments(append(comments, comment)); err != nil {
+		return err
+	}
+
+	return js.appendEvents(events)
+}
+
+func (js *JsonStore) GetComment(id uuid.UUID) utils.Result[model.Comment] {
+	comments, err := js.readComments()
+	if err != nil {
+		return utils.Result[model.Comment]{Err: err}
+	}
+
+	for _, comment := range comments {
+		if comment.ID == id {
+			return utils.Result[model.Comment]{Data: comment}
+		}
+	}
+
+	return utils.Result[model.Comment]{Err: fmt.Errorf("comment with id %s not found", id)}
+}
+
+func (js *JsonStore) GetComments(ideaID uuid.UUID) utils.Result[[]model.Comment] {
+	comments, err := js.readComments()
+	if err != nil {
+		return utils.Result[[]model.Comment]{Err: err}
+	}
+
+	// Comments are appended, so the file is already oldest first
+	var found []model.Comment
+	for _, comment := range comments {
+		if comment.IdeaID == ideaID {
+			found = append(found, comment)
+		}
+	}
+
+	return utils.Result[[]model.Comment]{Data: found}
+}
+
+func (js *JsonStore) UpdateComment(updated model.Comment) error {
+	comments, err := js.readComments()
+	if err != nil {
+		return err
+	}
+
+	for i, comment := range comments {
+		if comment.ID == updated.ID {
+			comments[i].Body = updated.Body
+			comments[i].BodyHTML = updated.BodyHTML
+			comments[i].Edited = true
+			comments[i].UpdatedAt = updated.UpdatedAt
+			return js.writeComments(comments)
+		}
+	}
+
+	return fmt.Errorf("comment with id %s not found", updated.ID)
+}
+
+// DeleteComment deletes the comment together with its replies
+func (js *JsonStore) DeleteComment(id uuid.UUID) error {
+	comments, err := js.readComments()
+	if err != nil {
+		return err
+	}
+
+	remaining := []model.Comment{}
+	for _, comment := range comments {
+		if comment.ID == id || (comment.ParentID != nil && *comment.ParentID == id) {
+			continue
+		}
+		remaining = append(remaining, comment)
+	}
+
+	if len(remaining) == len(comments) {
+		return fmt.Errorf("comment with id %s not found", id)
+	}
+
+	return js.writeComments(remaining)
+}
+
+func (js *JsonStore) deleteIdeaComments(ideaID uuid.UUID) error {
+	comments, err := js.readComments()
+	if err != nil {
+		return err
+	}
+
+	remaining := []model.Comment{}
+	for _, comment := range comments {
+		if comment.IdeaID != ideaID {
+			remaining = append(remaining, comment)
+		}
+	}
+
+	if len(remaining) == len(comments) {
+		return nil
+	}
+
+	return js.writeComments(remaining)
+}
+
+func (js *JsonStore) fillCommentCounts(ideas []model.Idea) error {
+	comments, err := js.readComments()
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[uuid.UUID]int)
+	for _, comment := range comments {
+		counts[comment.IdeaID]++
+	}
+	for i := range ideas {
+		ideas[i].CommentCount = counts[ideas[i].ID]
+	}
+
+	return nil
+}
+
+// readComments treats a missing comments file as no comments, so existing
+// ideas files keep working without one
+func (js *JsonStore) readComments() ([]model.Comment, error) {
+	file, err := os.Open(js.commentsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []model.Comment{}, nil
+		}
+		return nil, fmt.Errorf("failed to open comments file: %v", err)
+	}
+	defer file.Close()
+
+	fc, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read comments file: %v", err)
+	}
+
+	var comments []model.Comment
+	if err := utils.UnmarshalJson(fc, &comments); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal comments: %v", err)
+	}
+
+	return comments, nil
+}
+
+func (js *JsonStore) writeComments(comments []model.Comment) error {
+	data, err := json.Marshal(comments)
+	if err != nil {
+		return fmt.Errorf("failed to marshal comments: %v", err)
+	}
+
+	if result := js.WriteJson(js.commentsPath, data); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"time"
+)
+
+// appendEvents adds the events to the outbox file once the change they
+// describe was written. Two files can't be written atomically, so a failure
+// in between is returned to the caller instead of being lost silently.
+func (js *JsonStore) appendEvents(events []model.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	outbox, err := js.readEvents()
+	if err != nil {
+		return fmt.Errorf("change saved but its events were not: %v", err)
+	}
+
+	if err := js.writeEvents(append(outbox, events...)); err != nil {
+		return fmt.Errorf("change saved but its events were not: %v", err)
+	}
+
+	return nil
+}
+
+// GetPendingEvents returns undispatched events that are due, oldest first
+func (js *JsonStore) GetPendingEvents(now time.Time, limit int) utils.Result[[]model.OutboxEvent] {
+	outbox, err := js.readEvents()
+	if err != nil {
+		return utils.Result[[]model.OutboxEvent]{Err: err}
+	}
+
+	var pending []model.OutboxEvent
+	for _, event := range outbox {
+		if event.DispatchedAt == nil && !event.NextAttemptAt.After(now) {
+			pending = append(pending, event)
+		}
+	}
+
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].OccurredAt.Before(pending[j].OccurredAt) })
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+
+	return utils.Result[[]model.OutboxEvent]{Data: pending}
+}
+
+func (js *JsonStore) UpdateOutboxEvent(updated model.OutboxEvent) error {
+	outbox, err := js.readEvents()
+	if err != nil {
+		return err
+	}
+
+	for i, event := range outbox {
+		if event.ID == updated.ID {
+			outbox[i] = updated
+			return js.writeEvents(outbox)
+		}
+	}
+
+	return fmt.Errorf("event with id %s not found", updated.ID)
+}
+
+// PruneOutbox deletes events dispatched before the given time
+func (js *JsonStore) PruneOutbox(before time.Time) (int64, error) {
+	outbox, err := js.readEvents()
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := []model.OutboxEvent{}
+	for _, event := range outbox {
+		if event.DispatchedAt == nil || !event.DispatchedAt.Before(before) {
+			remaining = append(remaining, event)
+		}
+	}
+
+	pruned := int64(len(outbox) - len(remaining))
+	if pruned == 0 {
+		return 0, nil
+	}
+
+	return pruned, js.writeEvents(remaining)
+}
+
+// readEvents treats a missing outbox file as an empty outbox, like
+// readComments
+func (js *JsonStore) readEvents() ([]model.OutboxEvent, error) {
+	file, err := os.Open(js.outboxPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []model.OutboxEvent{}, nil
+		}
+		return nil, fmt.Errorf("failed to open outbox file: %v", err)
+	}
+	defer file.Close()
+
+	fc, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox file: %v", err)
+	}
+
+	var outbox []model.OutboxEvent
+	if err := utils.UnmarshalJson(fc, &outbox); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal outbox: %v", err)
+	}
+
+	return outbox, nil
+}
+
+func (js *JsonStore) writeEvents(outbox []model.OutboxEvent) error {
+	data, err := json.Marshal(outbox)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox: %v", err)
+	}
+
+	if result := js.WriteJson(js.outboxPath, data); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
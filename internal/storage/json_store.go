@@ -3,6 +3,8 @@ package storage
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"test_project/test/internal/model"
 	utils "test_project/test/pkg"
 
@@ -11,14 +13,29 @@ import (
 
 type JsonStore struct {
 	filepath string
+	// Comments and the event outbox live next to the ideas file, "ideas.json"
+	// keeps its comments in "ideas.comments.json" and its events in
+	// "ideas.outbox.json"
+	commentsPath string
+	outboxPath   string
 }
 
 func NewJsonStore(fp string) *JsonStore {
-	return &JsonStore{fp}
+	base := strings.TrimSuffix(fp, filepath.Ext(fp))
+	return &JsonStore{fp, base + ".comments" + filepath.Ext(fp), base + ".outbox" + filepath.Ext(fp)}
 }
 
 func (js *JsonStore) GetAllIdeas() utils.Result[[]model.Idea] {
-	return js.ReadFile()
+	result := js.ReadFile()
+	if result.Err != nil {
+		return result
+	}
+
+	if err := js.fillCommentCounts(result.Data); err != nil {
+		return utils.Result[[]model.Idea]{Err: err}
+	}
+
+	return result
 }
 
 func (js *JsonStore) GetIdea(id uuid.UUID) utils.Result[model.Idea] {
@@ -29,20 +46,27 @@ func (js *JsonStore) GetIdea(id uuid.UUID) utils.Result[model.Idea] {
 
 	for _, idea := range result.Data {
 		if idea.ID == id {
-			return utils.Result[model.Idea]{Data: idea}
+			ideas := []model.Idea{idea}
+			if err := js.fillCommentCounts(ideas); err != nil {
+				return utils.Result[model.Idea]{Err: err}
+			}
+			return utils.Result[model.Idea]{Data: ideas[0]}
 		}
 	}
 
 	return utils.Result[model.Idea]{Err: fmt.Errorf(`idea with ID %s not found`, id)}
 }
 
-func (js *JsonStore) CreateIdea(idea model.Idea) utils.Result[string] {
+func (js *JsonStore) CreateIdea(idea model.Idea, events ...model.OutboxEvent) utils.Result[string] {
 	result := js.ReadFile()
 	if result.Err != nil {
 		return utils.Result[string]{Err: result.Err}
 	}
 
-	idea.ID = uuid.MustParse(utils.GenId())
+	// The service picks the ID so its events and response can refer to it
+	if idea.ID == uuid.Nil {
+		idea.ID = uuid.MustParse(utils.GenId())
+	}
 	ideas := append(result.Data, idea)
 
 	data, err := json.Marshal(ideas)
@@ -55,10 +79,14 @@ func (js *JsonStore) CreateIdea(idea model.Idea) utils.Result[string] {
 		return writeResult
 	}
 
+	if err := js.appendEvents(events); err != nil {
+		return utils.Result[string]{Err: err}
+	}
+
 	return utils.Result[string]{Data: "Idea created successfully"}
 }
 
-func (js *JsonStore) UpdateIdea(id uuid.UUID, updatedIdea model.Idea) utils.Result[string] {
+func (js *JsonStore) UpdateIdea(id uuid.UUID, updatedIdea model.Idea, events ...model.OutboxEvent) utils.Result[string] {
 	result := js.ReadFile()
 	if result.Err != nil {
 		return utils.Result[string]{Err: result.Err}
@@ -80,6 +108,10 @@ func (js *JsonStore) UpdateIdea(id uuid.UUID, updatedIdea model.Idea) utils.Resu
 				return writeResult
 			}
 
+			if err := js.appendEvents(events); err != nil {
+				return utils.Result[string]{Err: err}
+			}
+
 			return utils.Result[string]{Data: "Idea updated successfully"}
 		}
 	}
@@ -87,7 +119,7 @@ func (js *JsonStore) UpdateIdea(id uuid.UUID, updatedIdea model.Idea) utils.Resu
 	return utils.Result[string]{Err: fmt.Errorf("idea with ID %s not found", id)}
 }
 
-func (js *JsonStore) DeleteIdea(id uuid.UUID) utils.Result[string] {
+func (js *JsonStore) DeleteIdea(id uuid.UUID, events ...model.OutboxEvent) utils.Result[string] {
 	result := js.ReadFile()
 	if result.Err != nil {
 		return utils.Result[string]{Err: result.Err}
@@ -123,5 +155,13 @@ func (js *JsonStore) DeleteIdea(id uuid.UUID) utils.Result[string] {
 		return writeResult
 	}
 
+	if err := js.deleteIdeaComments(id); err != nil {
+		return utils.Result[string]{Err: err}
+	}
+
+	if err := js.appendEvents(events); err != nil {
+		return utils.Result[string]{Err: err}
+	}
+
 	return utils.Result[string]{Data: "Idea deleted successfully"}
 }
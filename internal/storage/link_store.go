@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"fmt"
+	"test_project/test/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// setIdeaLinks replaces the links of the idea
+func setIdeaLinks(tx *gorm.DB, ideaID uuid.UUID, links []model.IdeaLink) error {
+	if err := tx.Where("idea_id = ?", ideaID).Delete(&model.IdeaLink{}).Error; err != nil {
+		return fmt.Errorf("failed to clear idea links: %v", err)
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	rows := make([]model.IdeaLink, len(links))
+	for i, link := range links {
+		link.IdeaID = ideaID
+		link.Position = i
+		rows[i] = link
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return fmt.Errorf("failed to save idea links: %v", err)
+	}
+	return nil
+}
+
+// fillLinks sets Links on each idea in the order they were given
+func (ps *PostgresStore) fillLinks(ideas []model.Idea) error {
+	if len(ideas) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(ideas))
+	for _, idea := range ideas {
+		ids = append(ids, idea.ID)
+	}
+
+	var rows []model.IdeaLink
+	if err := ps.db.Where("idea_id IN ?", ids).Order("position").Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to get idea links: %v", err)
+	}
+
+	links := make(map[uuid.UUID][]model.IdeaLink)
+	for _, row := range rows {
+		links[row.IdeaID] = append(links[row.IdeaID], row)
+	}
+	for i := range ideas {
+		ideas[i].Links = links[ideas[i].ID]
+		if ideas[i].Links == nil {
+			ideas[i].Links = []model.IdeaLink{}
+		}
+	}
+	return nil
+}
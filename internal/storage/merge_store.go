@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"fmt"
+	"test_project/test/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MergeIdea moves votes, comments, attachments and followers of the source idea to the
+// target and turns the source into a redirect stub. A user who voted for both
+// keeps one vote.
+func (ps *PostgresStore) MergeIdea(sourceID, targetID uuid.UUID, events ...model.OutboxEvent) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		targetVoters := tx.Model(&model.Vote{}).Select("user_id").Where("idea_id = ?", targetID)
+		if err := tx.Where("idea_id = ? AND user_id IN (?)", sourceID, targetVoters).
+			Delete(&model.Vote{}).Error; err != nil {
+			return fmt.Errorf("failed to merge votes: %v", err)
+		}
+		if err := tx.Model(&model.Vote{}).Where("idea_id = ?", sourceID).
+			Update("idea_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to merge votes: %v", err)
+		}
+
+		if err := tx.Model(&model.Comment{}).Where("idea_id = ?", sourceID).
+			Update("idea_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to merge comments: %v", err)
+		}
+		if err := tx.Model(&model.Attachment{}).Where("idea_id = ?", sourceID).
+			Update("idea_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to merge attachments: %v", err)
+		}
+
+		targetFollowers := tx.Model(&model.Follow{}).Select("user_id").Where("idea_id = ?", targetID)
+		if err := tx.Where("idea_id = ? AND user_id IN (?)", sourceID, targetFollowers).
+			Delete(&model.Follow{}).Error; err != nil {
+			return fmt.Errorf("failed to merge followers: %v", err)
+		}
+		if err := tx.Model(&model.Follow{}).Where("idea_id = ?", sourceID).
+			Update("idea_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to merge followers: %v", err)
+		}
+
+		targetAssignees := tx.Model(&model.IdeaAssignee{}).Select("user_id").Where("idea_id = ?", targetID)
+		if err := tx.Where("idea_id = ? AND user_id IN (?)", sourceID, targetAssignees).
+			Delete(&model.IdeaAssignee{}).Error; err != nil {
+			return fmt.Errorf("failed to merge assignees: %v", err)
+		}
+		if err := tx.Model(&model.IdeaAssignee{}).Where("idea_id = ?", sourceID).
+			Update("idea_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to merge assignees: %v", err)
+		}
+
+		if err := tx.Model(&model.Idea{}).Where("id = ?", sourceID).
+			Updates(map[string]any{"merged_into": targetID, "roadmap_rank": 0}).Error; err != nil {
+			return fmt.Errorf("failed to merge idea: %v", err)
+		}
+		// Ideas merged into the source earlier now point straight at the target
+		if err := tx.Model(&model.Idea{}).Where("merged_into = ?", sourceID).
+			Update("merged_into", targetID).Error; err != nil {
+			return fmt.Errorf("failed to merge idea: %v", err)
+		}
+
+		return writeOutbox(tx, events)
+	})
+}
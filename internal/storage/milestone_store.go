@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrMilestoneNotFound = errors.New("milestone not found")
+
+func (ps *PostgresStore) CreateMilestone(milestone model.Milestone) error {
+	if err := ps.db.Create(&milestone).Error; err != nil {
+		return fmt.Errorf("failed to create milestone: %v", err)
+	}
+
+	return nil
+}
+
+// GetMilestones returns the milestones by target date, an empty state
+// returns them all
+func (ps *PostgresStore) GetMilestones(state model.MilestoneState) utils.Result[[]model.Milestone] {
+	query := ps.db.Order("target_date, name")
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+
+	var milestones []model.Milestone
+	if err := query.Find(&milestones).Error; err != nil {
+		return utils.Result[[]model.Milestone]{Err: fmt.Errorf("failed to get milestones: %v", err)}
+	}
+
+	return utils.Result[[]model.Milestone]{Data: milestones}
+}
+
+func (ps *PostgresStore) GetMilestone(id uuid.UUID) utils.Result[model.Milestone] {
+	var milestone model.Milestone
+	if err := ps.db.First(&milestone, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.Result[model.Milestone]{Err: ErrMilestoneNotFound}
+		}
+		return utils.Result[model.Milestone]{Err: fmt.Errorf("failed to get milestone: %v", err)}
+	}
+
+	return utils.Result[model.Milestone]{Data: milestone}
+}
+
+func (ps *PostgresStore) UpdateMilestone(milestone model.Milestone) error {
+	milestone.UpdatedAt = time.Now()
+
+	// Save writes zero values too, which matters for reopening
+	if err := ps.db.Save(&milestone).Error; err != nil {
+		return fmt.Errorf("failed to update milestone: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteMilestone deletes the milestone, its ideas are no longer planned for
+// any milestone
+func (ps *PostgresStore) DeleteMilestone(id uuid.UUID) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Idea{}).Where("milestone_id = ?", id).
+			Update("milestone_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to unassign ideas: %v", err)
+		}
+
+		result := tx.Where("id = ?", id).Delete(&model.Milestone{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete milestone: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrMilestoneNotFound
+		}
+
+		return nil
+	})
+}
+
+// SetIdeaMilestone assigns the idea to the milestone, nil unassigns it
+func (ps *PostgresStore) SetIdeaMilestone(ideaID uuid.UUID, milestoneID *uuid.UUID) error {
+	if err := ps.db.Model(&model.Idea{}).Where("id = ?", ideaID).
+		Update("milestone_id", milestoneID).Error; err != nil {
+		return fmt.Errorf("failed to assign milestone: %v", err)
+	}
+
+	return nil
+}
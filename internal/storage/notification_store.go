@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+func (ps *PostgresStore) CreateNotifications(notifications []model.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	// Notifications that already exist are kept, the event was redelivered
+	if err := ps.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&notifications).Error; err != nil {
+		return fmt.Errorf("failed to save notifications: %v", err)
+	}
+
+	return nil
+}
+
+// GetNotifications returns the user's notifications, newest first
+func (ps *PostgresStore) GetNotifications(userID uuid.UUID, unreadOnly bool, limit int) utils.Result[[]model.Notification] {
+	query := ps.db.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var notifications []model.Notification
+	if err := query.Order("created_at DESC").Limit(limit).Find(&notifications).Error; err != nil {
+		return utils.Result[[]model.Notification]{Err: fmt.Errorf("failed to get notifications: %v", err)}
+	}
+
+	return utils.Result[[]model.Notification]{Data: notifications}
+}
+
+func (ps *PostgresStore) CountUnreadNotifications(userID uuid.UUID) (int64, error) {
+	var count int64
+	if err := ps.db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count notifications: %v", err)
+	}
+
+	return count, nil
+}
+
+// MarkNotificationRead keeps the first read time when called again
+func (ps *PostgresStore) MarkNotificationRead(userID, id uuid.UUID) error {
+	result := ps.db.Model(&model.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", time.Now()))
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark notification as read: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
+
+func (ps *PostgresStore) MarkAllNotificationsRead(userID uuid.UUID) (int64, error) {
+	result := ps.db.Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %v", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// PruneNotifications deletes notifications created before olderThan and read
+// ones created before readOlderThan
+func (ps *PostgresStore) PruneNotifications(olderThan, readOlderThan time.Time) (int64, error) {
+	result := ps.db.Where("created_at < ? OR (read_at IS NOT NULL AND created_at < ?)", olderThan, readOlderThan).
+		Delete(&model.Notification{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune notifications: %v", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// GetNotificationPreferences returns the preferences of each user, users who
+// never saved any get the defaults
+func (ps *PostgresStore) GetNotificationPreferences(userIDs []uuid.UUID) (map[uuid.UUID]model.NotificationPreferences, error) {
+	var saved []model.NotificationPreferences
+	if err := ps.db.Where("user_id IN ?", userIDs).Find(&saved).Error; err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %v", err)
+	}
+
+	prefs := make(map[uuid.UUID]model.NotificationPreferences, len(userIDs))
+	for _, id := range userIDs {
+		prefs[id] = model.DefaultNotificationPreferences(id)
+	}
+	for _, p := range saved {
+		prefs[p.UserID] = p
+	}
+
+	return prefs, nil
+}
+
+func (ps *PostgresStore) SaveNotificationPreferences(prefs model.NotificationPreferences) error {
+	err := ps.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		UpdateAll: true,
+	}).Create(&prefs).Error
+	if err != nil {
+		return fmt.Errorf("failed to save notification preferences: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"fmt"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// writeOutbox adds the events to the outbox as part of tx, so they are only
+// recorded when the change they describe is
+func writeOutbox(tx *gorm.DB, events []model.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	if err := tx.Create(&events).Error; err != nil {
+		return fmt.Errorf("failed to write events: %v", err)
+	}
+
+	return nil
+}
+
+// GetPendingEvents returns undispatched events that are due, oldest first
+func (ps *PostgresStore) GetPendingEvents(now time.Time, limit int) utils.Result[[]model.OutboxEvent] {
+	var events []model.OutboxEvent
+	err := ps.db.Where("dispatched_at IS NULL AND next_attempt_at <= ?", now).
+		Order("occurred_at").Limit(limit).Find(&events).Error
+	if err != nil {
+		return utils.Result[[]model.OutboxEvent]{Err: fmt.Errorf("failed to get events: %v", err)}
+	}
+
+	return utils.Result[[]model.OutboxEvent]{Data: events}
+}
+
+func (ps *PostgresStore) UpdateOutboxEvent(event model.OutboxEvent) error {
+	if err := ps.db.Save(&event).Error; err != nil {
+		return fmt.Errorf("failed to update event: %v", err)
+	}
+
+	return nil
+}
+
+// PruneOutbox deletes events dispatched before the given time
+func (ps *PostgresStore) PruneOutbox(before time.Time) (int64, error) {
+	result := ps.db.Where("dispatched_at < ?", before).Delete(&model.OutboxEvent{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune events: %v", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"test_project/test/internal/model"
 	utils "test_project/test/pkg"
+	"test_project/test/pkg/markdown"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,23 +24,59 @@ func NewPostgresStore(dbstring string) (*PostgresStore, error) {
 	}
 
 	// We must add the models here for creation of the tables
-	if err := db.AutoMigrate(&model.Idea{}, &model.User{}, &model.Vote{}); err != nil {
+	if err := db.AutoMigrate(&model.Idea{}, &model.User{}, &model.Vote{}, &model.RecoveryCode{}, &model.UserIdentity{}, &model.Session{}, &model.Invite{}, &model.InviteRedemption{}, &model.Comment{}, &model.StatusTransition{}, &model.Technology{}, &model.Tag{}, &model.IdeaTag{}, &model.Follow{}, &model.Notification{}, &model.NotificationPreferences{}, &model.Webhook{}, &model.WebhookDelivery{}, &model.OutboxEvent{}, &model.Attachment{}, &model.Board{}, &model.BoardMembership{}, &model.Milestone{}, &model.IdeaAssignee{}, &model.IdeaLink{}); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %v", err)
 	}
 
+	if err := migrateIdeaTags(db); err != nil {
+		return nil, err
+	}
+
+	if err := seedTechnologies(db); err != nil {
+		return nil, err
+	}
+
+	if err := migrateDescriptionHTML(db); err != nil {
+		return nil, err
+	}
+
+	if err := migrateCommentBodyHTML(db); err != nil {
+		return nil, err
+	}
+
+	if err := migrateAnonymizedComments(db); err != nil {
+		return nil, err
+	}
+
+	if err := seedDefaultBoard(db); err != nil {
+		return nil, err
+	}
+
 	return &PostgresStore{db: db}, nil
 }
 
 func (ps *PostgresStore) GetAllIdeas() utils.Result[[]model.Idea] {
 	var ideas []model.Idea
-	// Preload Votes so the slice is filled
-	if err := ps.db.Preload("Votes.User").Find(&ideas).Error; err != nil {
+	// Preload Votes so the slice is filled, merged ideas are only redirects
+	if err := ps.db.Preload("Votes.User").Where("merged_into IS NULL").Find(&ideas).Error; err != nil {
 		return utils.Result[[]model.Idea]{Err: fmt.Errorf("failed to get all ideas: %v", err)}
 	}
 	// Set Count for each idea
 	for i := range ideas {
 		ideas[i].VoteCount = len(ideas[i].Votes)
 	}
+	if err := ps.fillCommentCounts(ideas); err != nil {
+		return utils.Result[[]model.Idea]{Err: err}
+	}
+	if err := ps.fillTags(ideas); err != nil {
+		return utils.Result[[]model.Idea]{Err: err}
+	}
+	if err := ps.fillAssignees(ideas); err != nil {
+		return utils.Result[[]model.Idea]{Err: err}
+	}
+	if err := ps.fillLinks(ideas); err != nil {
+		return utils.Result[[]model.Idea]{Err: err}
+	}
 	return utils.Result[[]model.Idea]{Data: ideas}
 }
 
@@ -52,10 +89,24 @@ func (ps *PostgresStore) GetIdea(id uuid.UUID) utils.Result[model.Idea] {
 		return utils.Result[model.Idea]{Err: fmt.Errorf("failed to get idea: %v", err)}
 	}
 
-	return utils.Result[model.Idea]{Data: idea}
+	ideas := []model.Idea{idea}
+	if err := ps.fillCommentCounts(ideas); err != nil {
+		return utils.Result[model.Idea]{Err: err}
+	}
+	if err := ps.fillTags(ideas); err != nil {
+		return utils.Result[model.Idea]{Err: err}
+	}
+	if err := ps.fillAssignees(ideas); err != nil {
+		return utils.Result[model.Idea]{Err: err}
+	}
+	if err := ps.fillLinks(ideas); err != nil {
+		return utils.Result[model.Idea]{Err: err}
+	}
+
+	return utils.Result[model.Idea]{Data: ideas[0]}
 }
 
-func (ps *PostgresStore) CreateIdea(idea model.Idea) utils.Result[string] {
+func (ps *PostgresStore) CreateIdea(idea model.Idea, events ...model.OutboxEvent) utils.Result[string] {
 	if idea.ID == uuid.Nil {
 		idea.ID = uuid.New()
 	}
@@ -72,14 +123,26 @@ func (ps *PostgresStore) CreateIdea(idea model.Idea) utils.Result[string] {
 		idea.UpdatedAt = time.Now()
 	}
 
-	if err := ps.db.Create(&idea).Error; err != nil {
+	err := ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&idea).Error; err != nil {
+			return err
+		}
+		if err := setIdeaTags(tx, idea.ID, idea.Tags); err != nil {
+			return err
+		}
+		if err := setIdeaLinks(tx, idea.ID, idea.Links); err != nil {
+			return err
+		}
+		return writeOutbox(tx, events)
+	})
+	if err != nil {
 		return utils.Result[string]{Err: fmt.Errorf("failed to create an idea: %v", err)}
 	}
 
 	return utils.Result[string]{Data: "idea created successfully"}
 }
 
-func (ps *PostgresStore) UpdateIdea(id uuid.UUID, updatedIdea model.Idea) utils.Result[string] {
+func (ps *PostgresStore) UpdateIdea(id uuid.UUID, updatedIdea model.Idea, events ...model.OutboxEvent) utils.Result[string] {
 	var existing model.Idea
 	if err := ps.db.First(&existing, "id=?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -91,14 +154,29 @@ func (ps *PostgresStore) UpdateIdea(id uuid.UUID, updatedIdea model.Idea) utils.
 	updatedIdea.UpdatedAt = time.Now()
 	updatedIdea.ID = id
 
-	if err := ps.db.Model(&existing).Updates(updatedIdea).Error; err != nil {
+	err := ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&existing).Updates(updatedIdea).Error; err != nil {
+			return err
+		}
+		if err := updateDescription(tx, &existing, updatedIdea); err != nil {
+			return err
+		}
+		if err := setIdeaTags(tx, id, updatedIdea.Tags); err != nil {
+			return err
+		}
+		if err := setIdeaLinks(tx, id, updatedIdea.Links); err != nil {
+			return err
+		}
+		return writeOutbox(tx, events)
+	})
+	if err != nil {
 		return utils.Result[string]{Err: fmt.Errorf("failed to update the idea: %v", err)}
 	}
 
 	return utils.Result[string]{Data: "Idea updated successfully"}
 }
 
-func (ps *PostgresStore) DeleteIdea(id uuid.UUID) utils.Result[string] {
+func (ps *PostgresStore) DeleteIdea(id uuid.UUID, events ...model.OutboxEvent) utils.Result[string] {
 	var existing model.Idea
 	if err := ps.db.First(&existing, "id=?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -107,9 +185,79 @@ func (ps *PostgresStore) DeleteIdea(id uuid.UUID) utils.Result[string] {
 		return utils.Result[string]{Err: fmt.Errorf("failed to get the idea: %v", err)}
 	}
 
-	if err := ps.db.Delete(&existing).Error; err != nil {
+	err := ps.db.Transaction(func(tx *gorm.DB) error {
+		// Redirect stubs of ideas merged into this one go with it
+		var ids []uuid.UUID
+		if err := tx.Model(&model.Idea{}).Where("merged_into = ?", id).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		ids = append(ids, id)
+
+		if err := tx.Where("idea_id IN ?", ids).Delete(&model.Vote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("idea_id IN ?", ids).Delete(&model.Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("idea_id IN ?", ids).Delete(&model.StatusTransition{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("idea_id IN ?", ids).Delete(&model.IdeaTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("idea_id IN ?", ids).Delete(&model.Follow{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("idea_id IN ?", ids).Delete(&model.IdeaAssignee{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("idea_id IN ?", ids).Delete(&model.IdeaLink{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("idea_id IN ?", ids).Delete(&model.Notification{}).Error; err != nil {
+			return err
+		}
+		// The blobs are removed by the garbage collector
+		if err := tx.Where("idea_id IN ?", ids).Delete(&model.Attachment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&model.Idea{}).Error; err != nil {
+			return err
+		}
+		return writeOutbox(tx, events)
+	})
+	if err != nil {
 		return utils.Result[string]{Err: fmt.Errorf("failed to delete the idea: %v", err)}
 	}
 
 	return utils.Result[string]{Data: "Idea deleted successfully"}
 }
+
+// updateDescription writes the description columns even when they are empty,
+// Updates skips zero fields and a cleared description would keep its HTML
+func updateDescription(tx *gorm.DB, existing *model.Idea, updatedIdea model.Idea) error {
+	return tx.Model(existing).Updates(map[string]any{
+		"description":      updatedIdea.Description,
+		"description_html": updatedIdea.DescriptionHTML,
+	}).Error
+}
+
+// migrateDescriptionHTML renders the descriptions of ideas created before
+// descriptions were markdown, and of those with heading IDs from before they
+// were prefixed
+func migrateDescriptionHTML(db *gorm.DB) error {
+	var ideas []model.Idea
+	if err := db.Select("id, description").
+		Where("description <> '' AND (description_html IS NULL OR description_html = '' OR (description_html LIKE ? AND description_html NOT LIKE ?))",
+			`%<h_ id="%`, `%<h_ id="user-content-%`).
+		Find(&ideas).Error; err != nil {
+		return fmt.Errorf("failed to read idea descriptions: %v", err)
+	}
+
+	for _, idea := range ideas {
+		if err := db.Model(&model.Idea{}).Where("id = ?", idea.ID).Update("description_html", markdown.Render(idea.Description)).Error; err != nil {
+			return fmt.Errorf("failed to render idea description: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrIdeaNotInColumn = errors.New("idea is not in this roadmap column")
+
+// SetRoadmapRanks ranks the given ideas 1..n and clears the rank of every
+// other idea with one of the column's statuses
+func (ps *PostgresStore) SetRoadmapRanks(statuses []model.RequestStatus, ideaIDs []uuid.UUID) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Idea{}).Where("status IN ?", statuses).
+			Update("roadmap_rank", 0).Error; err != nil {
+			return fmt.Errorf("failed to reset roadmap ranks: %v", err)
+		}
+
+		for i, id := range ideaIDs {
+			result := tx.Model(&model.Idea{}).Where("id = ? AND status IN ?", id, statuses).
+				Update("roadmap_rank", i+1)
+			if result.Error != nil {
+				return fmt.Errorf("failed to rank idea: %v", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("%w: %s", ErrIdeaNotInColumn, id)
+			}
+		}
+
+		return nil
+	})
+}
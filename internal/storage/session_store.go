@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (ps *PostgresStore) CreateSession(session model.Session) error {
+	if err := ps.db.Create(&session).Error; err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+	return nil
+}
+
+func (ps *PostgresStore) GetSession(id uuid.UUID) (model.Session, error) {
+	var session model.Session
+	if err := ps.db.Where("id = ?", id).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Session{}, errors.New("session not found")
+		}
+		return model.Session{}, fmt.Errorf("failed to get session: %v", err)
+	}
+	return session, nil
+}
+
+func (ps *PostgresStore) GetActiveSessions(userID uuid.UUID) ([]model.Session, error) {
+	var sessions []model.Session
+	err := ps.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %v", err)
+	}
+	return sessions, nil
+}
+
+func (ps *PostgresStore) TouchSession(id uuid.UUID, seenAt time.Time) error {
+	if err := ps.db.Model(&model.Session{}).Where("id = ?", id).
+		Update("last_seen_at", seenAt).Error; err != nil {
+		return fmt.Errorf("failed to update session: %v", err)
+	}
+	return nil
+}
+
+func (ps *PostgresStore) RevokeSession(userID, id uuid.UUID) error {
+	result := ps.db.Model(&model.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke session: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every active session of the user except keep,
+// pass uuid.Nil to revoke all of them
+func (ps *PostgresStore) RevokeUserSessions(userID, keep uuid.UUID) (int64, error) {
+	result := ps.db.Model(&model.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keep).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
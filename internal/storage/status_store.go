@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (ps *PostgresStore) UpdateIdeaStatus(id uuid.UUID, updatedIdea model.Idea, transition model.StatusTransition, events ...model.OutboxEvent) utils.Result[string] {
+	err := ps.db.Transaction(func(tx *gorm.DB) error {
+		var existing model.Idea
+		if err := tx.First(&existing, "id=?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("idea with id %s not found", id)
+			}
+			return fmt.Errorf("failed to get the idea: %v", err)
+		}
+
+		updatedIdea.UpdatedAt = time.Now()
+		updatedIdea.ID = id
+
+		if err := tx.Model(&existing).Updates(updatedIdea).Error; err != nil {
+			return fmt.Errorf("failed to update the idea: %v", err)
+		}
+		if err := updateDescription(tx, &existing, updatedIdea); err != nil {
+			return fmt.Errorf("failed to update the idea: %v", err)
+		}
+		if err := setIdeaTags(tx, id, updatedIdea.Tags); err != nil {
+			return err
+		}
+		if err := setIdeaLinks(tx, id, updatedIdea.Links); err != nil {
+			return err
+		}
+		// The idea moves to another roadmap column, its rank there is unknown
+		if err := tx.Model(&existing).Update("roadmap_rank", 0).Error; err != nil {
+			return fmt.Errorf("failed to update the idea: %v", err)
+		}
+		if err := tx.Create(&transition).Error; err != nil {
+			return fmt.Errorf("failed to record status change: %v", err)
+		}
+		return writeOutbox(tx, events)
+	})
+	if err != nil {
+		return utils.Result[string]{Err: err}
+	}
+
+	return utils.Result[string]{Data: "Idea updated successfully"}
+}
+
+// GetStatusHistory returns the history of an idea, status and assignment
+// changes, oldest first
+func (ps *PostgresStore) GetStatusHistory(ideaID uuid.UUID) utils.Result[[]model.StatusTransition] {
+	var history []model.StatusTransition
+	if err := ps.db.Where("idea_id = ?", ideaID).Order("created_at").Find(&history).Error; err != nil {
+		return utils.Result[[]model.StatusTransition]{Err: fmt.Errorf("failed to get status history: %v", err)}
+	}
+	return utils.Result[[]model.StatusTransition]{Data: history}
+}
+
+// GetStatusEnteredAt returns when each idea last moved into the status.
+// Ideas that never changed into it, like ideas still in their first status,
+// are missing from the map.
+func (ps *PostgresStore) GetStatusEnteredAt(status model.RequestStatus) (map[uuid.UUID]time.Time, error) {
+	var rows []struct {
+		IdeaID    uuid.UUID
+		EnteredAt time.Time
+	}
+	if err := ps.db.Model(&model.StatusTransition{}).
+		Select("idea_id, MAX(created_at) AS entered_at").
+		Where("\"to\" = ?", status).
+		Group("idea_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get status changes: %v", err)
+	}
+
+	enteredAt := make(map[uuid.UUID]time.Time, len(rows))
+	for _, row := range rows {
+		enteredAt[row.IdeaID] = row.EnteredAt
+	}
+	return enteredAt, nil
+}
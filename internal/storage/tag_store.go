@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("a tag with that name already exists, merge the tags instead")
+)
+
+// migrateIdeaTags moves tags from the old ideas.tags jsonb column into the
+// tags and idea_tags tables, then drops the column. A value that is neither a
+// list of strings nor a comma-separated string aborts the migration, so the
+// column is only dropped once every value has been moved.
+func migrateIdeaTags(db *gorm.DB) error {
+	if !db.Migrator().HasColumn("ideas", "tags") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			ID   uuid.UUID
+			Tags []byte
+		}
+		if err := tx.Table("ideas").Select("id, tags").Where("tags IS NOT NULL").Scan(&rows).Error; err != nil {
+			return fmt.Errorf("failed to read idea tags: %v", err)
+		}
+
+		unreadable := 0
+		for _, row := range rows {
+			names, err := parseLegacyTags(row.Tags)
+			if err != nil {
+				log.Printf("idea %s: can't migrate tags %s: %v", row.ID, row.Tags, err)
+				unreadable++
+				continue
+			}
+			if err := setIdeaTags(tx, row.ID, names); err != nil {
+				return err
+			}
+		}
+		if unreadable > 0 {
+			return fmt.Errorf("failed to migrate ideas.tags: %d ideas have unreadable tags, fix them and restart", unreadable)
+		}
+
+		if err := tx.Migrator().DropColumn("ideas", "tags"); err != nil {
+			return fmt.Errorf("failed to drop ideas.tags: %v", err)
+		}
+		return nil
+	})
+}
+
+// parseLegacyTags reads an ideas.tags value, either a list of strings or a
+// comma-separated string
+func parseLegacyTags(raw []byte) ([]string, error) {
+	var names []string
+	if err := json.Unmarshal(raw, &names); err == nil {
+		return names, nil
+	}
+
+	var list string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, errors.New("not a list of strings or a comma-separated string")
+	}
+	return strings.Split(list, ","), nil
+}
+
+// setIdeaTags replaces the tags of an idea, creating tags that do not exist yet
+func setIdeaTags(tx *gorm.DB, ideaID uuid.UUID, names []string) error {
+	if err := tx.Where("idea_id = ?", ideaID).Delete(&model.IdeaTag{}).Error; err != nil {
+		return fmt.Errorf("failed to clear idea tags: %v", err)
+	}
+
+	names = model.NormalizeTags(names)
+	if len(names) == 0 {
+		return nil
+	}
+
+	tags, err := ensureTags(tx, names)
+	if err != nil {
+		return err
+	}
+
+	links := make([]model.IdeaTag, 0, len(tags))
+	for _, tag := range tags {
+		links = append(links, model.IdeaTag{IdeaID: ideaID, TagID: tag.ID})
+	}
+	if err := tx.Create(&links).Error; err != nil {
+		return fmt.Errorf("failed to tag idea: %v", err)
+	}
+	return nil
+}
+
+// ensureTags returns the tags with the given names, creating missing ones
+func ensureTags(tx *gorm.DB, names []string) ([]model.Tag, error) {
+	now := time.Now()
+	candidates := make([]model.Tag, 0, len(names))
+	for _, name := range names {
+		candidates = append(candidates, model.Tag{ID: uuid.New(), Name: name, CreatedAt: now})
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to create tags: %v", err)
+	}
+
+	var tags []model.Tag
+	if err := tx.Where("name IN ?", names).Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tags: %v", err)
+	}
+	return tags, nil
+}
+
+// fillTags sets Tags on each idea, sorted by name
+func (ps *PostgresStore) fillTags(ideas []model.Idea) error {
+	if len(ideas) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(ideas))
+	for _, idea := range ideas {
+		ids = append(ids, idea.ID)
+	}
+
+	var rows []struct {
+		IdeaID uuid.UUID
+		Name   string
+	}
+	if err := ps.db.Table("idea_tags").
+		Select("idea_tags.idea_id, tags.name").
+		Joins("JOIN tags ON tags.id = idea_tags.tag_id").
+		Where("idea_tags.idea_id IN ?", ids).
+		Order("tags.name").
+		Scan(&rows).Error; err != nil {
+		return fmt.Errorf("failed to get idea tags: %v", err)
+	}
+
+	tags := make(map[uuid.UUID][]string)
+	for _, row := range rows {
+		tags[row.IdeaID] = append(tags[row.IdeaID], row.Name)
+	}
+	for i := range ideas {
+		ideas[i].Tags = tags[ideas[i].ID]
+		if ideas[i].Tags == nil {
+			ideas[i].Tags = []string{}
+		}
+	}
+	return nil
+}
+
+// SearchTags returns tags in use that start with prefix, most used first
+func (ps *PostgresStore) SearchTags(prefix string, limit int) utils.Result[[]model.TagUsage] {
+	var usage []model.TagUsage
+	if err := ps.db.Table("tags").
+		Select("tags.name, COUNT(idea_tags.idea_id) AS count").
+		Joins("JOIN idea_tags ON idea_tags.tag_id = tags.id").
+		Where("tags.name LIKE ?", escapeLike(prefix)+"%").
+		Group("tags.name").
+		Order("count DESC, tags.name").
+		Limit(limit).
+		Scan(&usage).Error; err != nil {
+		return utils.Result[[]model.TagUsage]{Err: fmt.Errorf("failed to search tags: %v", err)}
+	}
+	return utils.Result[[]model.TagUsage]{Data: usage}
+}
+
+func (ps *PostgresStore) RenameTag(from, to string) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&model.Tag{}).Where("name = ?", to).Count(&existing).Error; err != nil {
+			return fmt.Errorf("failed to rename tag: %v", err)
+		}
+		if existing > 0 {
+			return fmt.Errorf("%w: %s", ErrTagExists, to)
+		}
+
+		result := tx.Model(&model.Tag{}).Where("name = ?", from).Update("name", to)
+		if result.Error != nil {
+			return fmt.Errorf("failed to rename tag: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: %s", ErrTagNotFound, from)
+		}
+		return nil
+	})
+}
+
+// MergeTags moves every idea tagged with one of the sources to the target,
+// which is created if needed, and deletes the sources
+func (ps *PostgresStore) MergeTags(sources []string, target string) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		var sourceTags []model.Tag
+		if err := tx.Where("name IN ? AND name <> ?", sources, target).Find(&sourceTags).Error; err != nil {
+			return fmt.Errorf("failed to get tags: %v", err)
+		}
+		if len(sourceTags) == 0 {
+			return fmt.Errorf("%w: %v", ErrTagNotFound, sources)
+		}
+
+		targetTags, err := ensureTags(tx, []string{target})
+		if err != nil {
+			return err
+		}
+
+		sourceIDs := make([]uuid.UUID, 0, len(sourceTags))
+		for _, tag := range sourceTags {
+			sourceIDs = append(sourceIDs, tag.ID)
+		}
+
+		if err := tx.Exec(`INSERT INTO idea_tags (idea_id, tag_id)
+			SELECT DISTINCT idea_id, ? FROM idea_tags WHERE tag_id IN ?
+			ON CONFLICT DO NOTHING`, targetTags[0].ID, sourceIDs).Error; err != nil {
+			return fmt.Errorf("failed to merge tags: %v", err)
+		}
+		if err := tx.Where("tag_id IN ?", sourceIDs).Delete(&model.IdeaTag{}).Error; err != nil {
+			return fmt.Errorf("failed to merge tags: %v", err)
+		}
+		if err := tx.Where("id IN ?", sourceIDs).Delete(&model.Tag{}).Error; err != nil {
+			return fmt.Errorf("failed to delete merged tags: %v", err)
+		}
+		return nil
+	})
+}
+
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
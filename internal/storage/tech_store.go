@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// defaultTechnologies seeds an empty catalog with the tech stacks that used to
+// be hardcoded
+var defaultTechnologies = []model.Technology{
+	{Name: "Rust"},
+	{Name: "Go", Aliases: []string{"golang"}},
+	{Name: "Next", Aliases: []string{"nextjs", "next.js"}},
+	{Name: "React", Aliases: []string{"reactjs"}},
+	{Name: "Axum"},
+	{Name: "Postgres", Aliases: []string{"postgresql"}},
+	{Name: "MySQL"},
+	{Name: "Docker"},
+	{Name: "ActixWeb", Aliases: []string{"actix", "actix-web"}},
+	{Name: "ChiRouter", Aliases: []string{"chi"}},
+	{Name: "Node", Aliases: []string{"nodejs", "node.js"}},
+}
+
+func seedTechnologies(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&model.Technology{}).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count technologies: %v", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	now := time.Now()
+	techs := make([]model.Technology, len(defaultTechnologies))
+	for i, t := range defaultTechnologies {
+		t.ID = uuid.New()
+		t.CreatedAt = now
+		t.UpdatedAt = now
+		if t.Aliases == nil {
+			t.Aliases = []string{}
+		}
+		techs[i] = t
+	}
+
+	if err := db.Create(&techs).Error; err != nil {
+		return fmt.Errorf("failed to seed technologies: %v", err)
+	}
+	return nil
+}
+
+func (ps *PostgresStore) CreateTechnology(tech model.Technology) error {
+	if err := ps.db.Create(&tech).Error; err != nil {
+		return fmt.Errorf("failed to create technology: %v", err)
+	}
+	return nil
+}
+
+func (ps *PostgresStore) GetTechnologies() utils.Result[[]model.Technology] {
+	var techs []model.Technology
+	if err := ps.db.Order("name").Find(&techs).Error; err != nil {
+		return utils.Result[[]model.Technology]{Err: fmt.Errorf("failed to get technologies: %v", err)}
+	}
+	return utils.Result[[]model.Technology]{Data: techs}
+}
+
+func (ps *PostgresStore) GetTechnology(id uuid.UUID) utils.Result[model.Technology] {
+	var tech model.Technology
+	if err := ps.db.Where("id = ?", id).First(&tech).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.Result[model.Technology]{Err: fmt.Errorf("technology with id %s not found", id)}
+		}
+		return utils.Result[model.Technology]{Err: fmt.Errorf("failed to get technology: %v", err)}
+	}
+	return utils.Result[model.Technology]{Data: tech}
+}
+
+func (ps *PostgresStore) UpdateTechnology(tech model.Technology) error {
+	tech.UpdatedAt = time.Now()
+
+	// Save writes zero values too, so un-deprecating and clearing fields works
+	if err := ps.db.Save(&tech).Error; err != nil {
+		return fmt.Errorf("failed to update technology: %v", err)
+	}
+	return nil
+}
+
+func (ps *PostgresStore) DeleteTechnology(id uuid.UUID) error {
+	result := ps.db.Where("id = ?", id).Delete(&model.Technology{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete technology: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("technology with id %s not found", id)
+	}
+	return nil
+}
+
+// CountIdeasUsingTechnology counts ideas whose tech stack contains one of the
+// given names
+func (ps *PostgresStore) CountIdeasUsingTechnology(names []string) (int64, error) {
+	query := ps.db.Model(&model.Idea{}).Where("1 = 0")
+	for _, name := range names {
+		contains, err := json.Marshal([]string{name})
+		if err != nil {
+			return 0, err
+		}
+		query = query.Or("tech_stack @> ?::jsonb", string(contains))
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count ideas: %v", err)
+	}
+	return count, nil
+}
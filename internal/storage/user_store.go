@@ -11,7 +11,63 @@ import (
 	"gorm.io/gorm"
 )
 
-func (ps *PostgresStore) CreateUser(user model.User) error {
+var (
+	ErrInviteUnavailable = errors.New("invite code is invalid, expired or used up")
+	ErrTOTPLocked        = errors.New("too many failed two-factor attempts, try again later")
+)
+
+func (ps *PostgresStore) CreateUser(user model.User, events ...model.OutboxEvent) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := createUser(tx, user); err != nil {
+			return err
+		}
+		return writeOutbox(tx, events)
+	})
+}
+
+// CreateUserWithInvite redeems the invite and creates the user atomically, a
+// failed registration does not use up the invite
+func (ps *PostgresStore) CreateUserWithInvite(user model.User, codeHash string, events ...model.OutboxEvent) error {
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.Invite{}).
+			Where("code_hash = ? AND revoked_at IS NULL AND uses < max_uses AND (expires_at IS NULL OR expires_at > ?)", codeHash, now).
+			UpdateColumn("uses", gorm.Expr("uses + 1"))
+		if result.Error != nil {
+			return fmt.Errorf("failed to redeem invite: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrInviteUnavailable
+		}
+
+		var invite model.Invite
+		if err := tx.Where("code_hash = ?", codeHash).First(&invite).Error; err != nil {
+			return fmt.Errorf("failed to redeem invite: %v", err)
+		}
+
+		if err := createUser(tx, user); err != nil {
+			return err
+		}
+
+		redemption := model.InviteRedemption{
+			ID:         uuid.New(),
+			InviteID:   invite.ID,
+			UserID:     user.ID,
+			RedeemedAt: now,
+		}
+		if err := tx.Create(&redemption).Error; err != nil {
+			return fmt.Errorf("failed to record invite redemption: %v", err)
+		}
+
+		return writeOutbox(tx, events)
+	})
+}
+
+func createUser(db *gorm.DB, user model.User) error {
 	if user.ID == uuid.Nil {
 		user.ID = uuid.New()
 	}
@@ -25,16 +81,16 @@ func (ps *PostgresStore) CreateUser(user model.User) error {
 	}
 
 	var existingUser model.User
-	result := ps.db.Where("username = ?", user.Username).First(&existingUser)
+	result := db.Where("username = ?", user.Username).First(&existingUser)
 	if result.Error == nil {
 		return errors.New("username already exists")
 	}
 
-	result = ps.db.Where("email = ?", user.Email).First(&existingUser)
+	result = db.Where("email = ?", user.Email).First(&existingUser)
 	if result.Error == nil {
 	}
 
-	if err := ps.db.Create(&user).Error; err != nil {
+	if err := db.Create(&user).Error; err != nil {
 		return fmt.Errorf("failed to create user: %v", err)
 	}
 
@@ -63,21 +119,307 @@ func (ps *PostgresStore) GetUserByUsername(username string) (model.User, error)
 	return user, nil
 }
 
-func (ps *PostgresStore) DeleteUser(username string) (model.User, error) {
+// DeleteAccount removes the user and applies the deletion policy to their ideas
+// and votes in a single transaction. reassignTo is only used by DeletionReassign.
+func (ps *PostgresStore) DeleteAccount(userID uuid.UUID, policy model.DeletionPolicy, reassignTo model.User, events ...model.OutboxEvent) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		switch policy {
+		case model.DeletionAnonymize:
+			if err := tx.Model(&model.Idea{}).Where("author_id = ?", userID).
+				Updates(map[string]any{"author_id": nil, "requested_by": "deleted user"}).Error; err != nil {
+				return fmt.Errorf("failed to anonymize ideas: %v", err)
+			}
+			// A vote without a voter can't be told apart from another one
+			if err := tx.Where("user_id = ?", userID).Delete(&model.Vote{}).Error; err != nil {
+				return fmt.Errorf("failed to delete votes: %v", err)
+			}
+			if err := tx.Model(&model.Comment{}).Where("author_id = ?", userID).
+				Updates(map[string]any{"author_id": nil, "author_name": "deleted user"}).Error; err != nil {
+				return fmt.Errorf("failed to anonymize comments: %v", err)
+			}
+			if err := tx.Model(&model.StatusTransition{}).Where("actor_id = ?", userID).
+				Update("actor_name", "deleted user").Error; err != nil {
+				return fmt.Errorf("failed to anonymize status history: %v", err)
+			}
+			if err := tx.Model(&model.StatusTransition{}).Where("assignee_id = ?", userID).
+				Update("assignee", "deleted user").Error; err != nil {
+				return fmt.Errorf("failed to anonymize status history: %v", err)
+			}
+
+		case model.DeletionReassign:
+			if reassignTo.ID == uuid.Nil || reassignTo.ID == userID {
+				return errors.New("invalid reassignment target")
+			}
+			if err := tx.Model(&model.Idea{}).Where("author_id = ?", userID).
+				Updates(map[string]any{"author_id": reassignTo.ID, "requested_by": reassignTo.Username}).Error; err != nil {
+				return fmt.Errorf("failed to reassign ideas: %v", err)
+			}
+			// One vote per user and idea, drop the ones the target already has
+			if err := tx.Where("user_id = ? AND idea_id IN (?)", userID,
+				tx.Model(&model.Vote{}).Select("idea_id").Where("user_id = ?", reassignTo.ID)).
+				Delete(&model.Vote{}).Error; err != nil {
+				return fmt.Errorf("failed to reassign votes: %v", err)
+			}
+			if err := tx.Model(&model.Vote{}).Where("user_id = ?", userID).
+				Update("user_id", reassignTo.ID).Error; err != nil {
+				return fmt.Errorf("failed to reassign votes: %v", err)
+			}
+			if err := tx.Model(&model.Comment{}).Where("author_id = ?", userID).
+				Updates(map[string]any{"author_id": reassignTo.ID, "author_name": reassignTo.Username}).Error; err != nil {
+				return fmt.Errorf("failed to reassign comments: %v", err)
+			}
+
+		case model.DeletionCascade:
+			ownIdeas := tx.Model(&model.Idea{}).Select("id").Where("author_id = ?", userID)
+			if err := tx.Where("idea_id IN (?)", ownIdeas).Delete(&model.Vote{}).Error; err != nil {
+				return fmt.Errorf("failed to delete votes: %v", err)
+			}
+			if err := tx.Where("idea_id IN (?)", ownIdeas).Delete(&model.Comment{}).Error; err != nil {
+				return fmt.Errorf("failed to delete comments: %v", err)
+			}
+			if err := tx.Where("idea_id IN (?)", ownIdeas).Delete(&model.StatusTransition{}).Error; err != nil {
+				return fmt.Errorf("failed to delete status history: %v", err)
+			}
+			if err := tx.Where("idea_id IN (?)", ownIdeas).Delete(&model.IdeaTag{}).Error; err != nil {
+				return fmt.Errorf("failed to delete idea tags: %v", err)
+			}
+			if err := tx.Where("idea_id IN (?)", ownIdeas).Delete(&model.Follow{}).Error; err != nil {
+				return fmt.Errorf("failed to delete followers: %v", err)
+			}
+			if err := tx.Where("idea_id IN (?)", ownIdeas).Delete(&model.IdeaAssignee{}).Error; err != nil {
+				return fmt.Errorf("failed to delete assignees: %v", err)
+			}
+			if err := tx.Where("idea_id IN (?)", ownIdeas).Delete(&model.IdeaLink{}).Error; err != nil {
+				return fmt.Errorf("failed to delete idea links: %v", err)
+			}
+			if err := tx.Where("idea_id IN (?)", ownIdeas).Delete(&model.Notification{}).Error; err != nil {
+				return fmt.Errorf("failed to delete notifications: %v", err)
+			}
+			if err := tx.Where("idea_id IN (?)", ownIdeas).Delete(&model.Attachment{}).Error; err != nil {
+				return fmt.Errorf("failed to delete attachments: %v", err)
+			}
+			if err := tx.Where("author_id = ?", userID).Delete(&model.Idea{}).Error; err != nil {
+				return fmt.Errorf("failed to delete ideas: %v", err)
+			}
+			if err := tx.Where("user_id = ?", userID).Delete(&model.Vote{}).Error; err != nil {
+				return fmt.Errorf("failed to delete votes: %v", err)
+			}
+			// Comments on other ideas go with their replies, like a deleted comment
+			ownComments := tx.Model(&model.Comment{}).Select("id").Where("author_id = ?", userID)
+			if err := tx.Where("parent_id IN (?)", ownComments).Delete(&model.Comment{}).Error; err != nil {
+				return fmt.Errorf("failed to delete comments: %v", err)
+			}
+			if err := tx.Where("author_id = ?", userID).Delete(&model.Comment{}).Error; err != nil {
+				return fmt.Errorf("failed to delete comments: %v", err)
+			}
+
+		default:
+			return fmt.Errorf("unknown deletion policy %q", policy)
+		}
+
+		// Status changes stay in the history under the name they were made with
+		if err := tx.Model(&model.StatusTransition{}).Where("actor_id = ?", userID).
+			Update("actor_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to detach status history: %v", err)
+		}
+		if err := tx.Model(&model.StatusTransition{}).Where("assignee_id = ?", userID).
+			Update("assignee_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to detach status history: %v", err)
+		}
+		// Attachments on other ideas stay, under the name they were uploaded with
+		if err := tx.Model(&model.Attachment{}).Where("uploader_id = ?", userID).
+			Update("uploader_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to detach attachments: %v", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.Follow{}).Error; err != nil {
+			return fmt.Errorf("failed to delete follows: %v", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.BoardMembership{}).Error; err != nil {
+			return fmt.Errorf("failed to delete board memberships: %v", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.IdeaAssignee{}).Error; err != nil {
+			return fmt.Errorf("failed to delete assignments: %v", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.Notification{}).Error; err != nil {
+			return fmt.Errorf("failed to delete notifications: %v", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.NotificationPreferences{}).Error; err != nil {
+			return fmt.Errorf("failed to delete notification preferences: %v", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %v", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.UserIdentity{}).Error; err != nil {
+			return fmt.Errorf("failed to delete identities: %v", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.Session{}).Error; err != nil {
+			return fmt.Errorf("failed to delete sessions: %v", err)
+		}
+		if err := tx.Where("id = ?", userID).Delete(&model.User{}).Error; err != nil {
+			return fmt.Errorf("failed to delete user: %v", err)
+		}
+
+		return writeOutbox(tx, events)
+	})
+}
+
+func (ps *PostgresStore) GetUserByID(id uuid.UUID) (model.User, error) {
 	var user model.User
 
-	// Check if user exists
-	result := ps.db.Where("username = ?", username).First(&user)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return model.User{}, fmt.Errorf("user not found")
+	if err := ps.db.Where("id = ?", id).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.User{}, errors.New("user not found")
+		}
+
+		return model.User{}, fmt.Errorf("failed to get user: %v", err)
+	}
+	return user, nil
+}
+
+func (ps *PostgresStore) UpdateUser(user model.User) error {
+	user.UpdatedAt = time.Now()
+
+	// Save writes zero values too, which matters for flags like TOTPEnabled
+	if err := ps.db.Save(&user).Error; err != nil {
+		return fmt.Errorf("failed to update user: %v", err)
+	}
+
+	return nil
+}
+
+func (ps *PostgresStore) ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %v", err)
+		}
+
+		if len(codeHashes) == 0 {
+			return nil
+		}
+
+		codes := make([]model.RecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, model.RecoveryCode{
+				ID:        uuid.New(),
+				UserID:    userID,
+				CodeHash:  hash,
+				CreatedAt: time.Now(),
+			})
+		}
+
+		if err := tx.Create(&codes).Error; err != nil {
+			return fmt.Errorf("failed to save recovery codes: %v", err)
 		}
-		return model.User{}, result.Error
+
+		return nil
+	})
+}
+
+func (ps *PostgresStore) UseRecoveryCode(userID uuid.UUID, codeHash string) error {
+	// The used_at IS NULL condition makes the update the single point of truth,
+	// two concurrent logins with the same code can't both succeed
+	result := ps.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to use recovery code: %v", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("invalid recovery code")
+	}
+
+	return nil
+}
+
+// UseTOTPStep records the time step of an accepted code. Like
+// UseRecoveryCode the condition makes the update decide, a code can't be
+// used twice even by concurrent requests.
+func (ps *PostgresStore) UseTOTPStep(userID uuid.UUID, step int64) error {
+	result := ps.db.Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return fmt.Errorf("failed to use two-factor code: %v", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("two-factor code already used")
+	}
+
+	return nil
+}
+
+// CountTOTPAttempt counts a 2FA attempt as failed before the code is
+// checked, so concurrent guesses can't get past the limit. The attempt that
+// reaches maxFailures locks the next ones out for lockout.
+func (ps *PostgresStore) CountTOTPAttempt(userID uuid.UUID, maxFailures int, lockout time.Duration) error {
+	now := time.Now()
+	result := ps.db.Model(&model.User{}).
+		Where("id = ? AND (totp_locked_until IS NULL OR totp_locked_until <= ?)", userID, now).
+		Update("totp_failures", gorm.Expr("totp_failures + 1"))
+	if result.Error != nil {
+		return fmt.Errorf("failed to count two-factor attempt: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrTOTPLocked
+	}
+
+	if err := ps.db.Model(&model.User{}).
+		Where("id = ? AND totp_failures >= ?", userID, maxFailures).
+		Updates(map[string]any{"totp_failures": 0, "totp_locked_until": now.Add(lockout)}).Error; err != nil {
+		return fmt.Errorf("failed to lock two-factor logins: %v", err)
+	}
+	return nil
+}
+
+// ResetTOTPFailures takes back the attempts counted since the last successful
+// 2FA check
+func (ps *PostgresStore) ResetTOTPFailures(userID uuid.UUID) error {
+	if err := ps.db.Model(&model.User{}).Where("id = ?", userID).
+		Updates(map[string]any{"totp_failures": 0, "totp_locked_until": nil}).Error; err != nil {
+		return fmt.Errorf("failed to reset two-factor attempts: %v", err)
 	}
+	return nil
+}
+
+func (ps *PostgresStore) GetUserByEmail(email string) (model.User, error) {
+	var user model.User
 
-	if err := ps.db.Delete(&user).Error; err != nil {
-		return model.User{}, fmt.Errorf("failed to delete user: %v", err)
+	if err := ps.db.Where("LOWER(email) = LOWER(?)", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.User{}, errors.New("user not found")
+		}
+
+		return model.User{}, fmt.Errorf("failed to get user: %v", err)
 	}
+	return user, nil
+}
 
+func (ps *PostgresStore) GetUserByIdentity(issuer, subject string) (model.User, error) {
+	var user model.User
+
+	err := ps.db.Joins("JOIN user_identities ON user_identities.user_id = users.id").
+		Where("user_identities.issuer = ? AND user_identities.subject = ?", issuer, subject).
+		First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.User{}, errors.New("user not found")
+		}
+
+		return model.User{}, fmt.Errorf("failed to get user: %v", err)
+	}
 	return user, nil
 }
+
+func (ps *PostgresStore) CreateIdentity(identity model.UserIdentity) error {
+	if identity.ID == uuid.Nil {
+		identity.ID = uuid.New()
+	}
+
+	if err := ps.db.Create(&identity).Error; err != nil {
+		return fmt.Errorf("failed to link identity: %v", err)
+	}
+
+	return nil
+}
@@ -9,31 +9,35 @@ import (
 	"gorm.io/gorm"
 )
 
-func (ps *PostgresStore) AddVote(userID uuid.UUID, ideaID uuid.UUID) utils.Result[string] {
+func (ps *PostgresStore) AddVote(userID uuid.UUID, ideaID uuid.UUID, events ...model.OutboxEvent) utils.Result[string] {
 	vote := model.Vote{
 		ID:        uuid.New().String(),
+		IdeaID:    ideaID,
+		UserID:    userID,
 		CreatedAt: time.Now(),
 	}
-	if err := ps.db.Create(&vote).Error; err != nil {
-		return utils.NewResult("", err)
-	}
-
-	if err := ps.db.Model(&model.Idea{}).Where("id = ?", ideaID).
-		UpdateColumn("votes", gorm.Expr("votes + ?", 1)).Error; err != nil {
+	err := ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&vote).Error; err != nil {
+			return err
+		}
+		return writeOutbox(tx, events)
+	})
+	if err != nil {
 		return utils.NewResult("", err)
 	}
 
 	return utils.NewResult("Successfully Added vote", nil)
 }
 
-func (ps *PostgresStore) RemoveVote(userID uuid.UUID, ideaID uuid.UUID) utils.Result[string] {
-	if err := ps.db.Where("user_id = ? AND idea_id = ?", userID, ideaID).
-		Delete(&model.Vote{}).Error; err != nil {
-		return utils.NewResult("", err)
-	}
-
-	if err := ps.db.Model(&model.Idea{}).Where("id = ?", ideaID).
-		UpdateColumn("votes", gorm.Expr("votes - ?", 1)).Error; err != nil {
+func (ps *PostgresStore) RemoveVote(userID uuid.UUID, ideaID uuid.UUID, events ...model.OutboxEvent) utils.Result[string] {
+	err := ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND idea_id = ?", userID, ideaID).
+			Delete(&model.Vote{}).Error; err != nil {
+			return err
+		}
+		return writeOutbox(tx, events)
+	})
+	if err != nil {
 		return utils.NewResult("", err)
 	}
 
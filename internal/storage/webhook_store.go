@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"test_project/test/internal/model"
+	utils "test_project/test/pkg"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+)
+
+func (ps *PostgresStore) CreateWebhook(webhook model.Webhook) error {
+	if err := ps.db.Create(&webhook).Error; err != nil {
+		return fmt.Errorf("failed to create webhook: %v", err)
+	}
+
+	return nil
+}
+
+func (ps *PostgresStore) GetWebhooks() utils.Result[[]model.Webhook] {
+	var webhooks []model.Webhook
+	if err := ps.db.Order("created_at").Find(&webhooks).Error; err != nil {
+		return utils.Result[[]model.Webhook]{Err: fmt.Errorf("failed to get webhooks: %v", err)}
+	}
+
+	return utils.Result[[]model.Webhook]{Data: webhooks}
+}
+
+func (ps *PostgresStore) GetWebhook(id uuid.UUID) utils.Result[model.Webhook] {
+	var webhook model.Webhook
+	if err := ps.db.First(&webhook, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.Result[model.Webhook]{Err: ErrWebhookNotFound}
+		}
+		return utils.Result[model.Webhook]{Err: fmt.Errorf("failed to get webhook: %v", err)}
+	}
+
+	return utils.Result[model.Webhook]{Data: webhook}
+}
+
+// GetSubscribedWebhooks returns the active webhooks that subscribed to the event
+func (ps *PostgresStore) GetSubscribedWebhooks(event model.WebhookEvent) utils.Result[[]model.Webhook] {
+	filter, err := json.Marshal([]model.WebhookEvent{event})
+	if err != nil {
+		return utils.Result[[]model.Webhook]{Err: err}
+	}
+
+	var webhooks []model.Webhook
+	if err := ps.db.Where("active AND events @> ?", string(filter)).Find(&webhooks).Error; err != nil {
+		return utils.Result[[]model.Webhook]{Err: fmt.Errorf("failed to get webhooks: %v", err)}
+	}
+
+	return utils.Result[[]model.Webhook]{Data: webhooks}
+}
+
+func (ps *PostgresStore) UpdateWebhook(webhook model.Webhook) error {
+	webhook.UpdatedAt = time.Now()
+
+	// Save writes zero values too, which matters for Active
+	if err := ps.db.Save(&webhook).Error; err != nil {
+		return fmt.Errorf("failed to update webhook: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteWebhook deletes the webhook together with its delivery log
+func (ps *PostgresStore) DeleteWebhook(id uuid.UUID) error {
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return fmt.Errorf("failed to delete deliveries: %v", err)
+		}
+
+		result := tx.Where("id = ?", id).Delete(&model.Webhook{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete webhook: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+
+		return nil
+	})
+}
+
+// RecordWebhookResult resets the failure streak after a successful delivery.
+// A failed one extends it and disables the webhook once the streak reaches
+// disableAfter, disabled reports whether that happened.
+func (ps *PostgresStore) RecordWebhookResult(id uuid.UUID, succeeded bool, disableAfter int) (disabled bool, err error) {
+	if succeeded {
+		if err := ps.db.Model(&model.Webhook{}).Where("id = ?", id).
+			UpdateColumn("consecutive_failures", 0).Error; err != nil {
+			return false, fmt.Errorf("failed to update webhook: %v", err)
+		}
+		return false, nil
+	}
+
+	err = ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Webhook{}).Where("id = ?", id).
+			UpdateColumn("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error; err != nil {
+			return fmt.Errorf("failed to update webhook: %v", err)
+		}
+
+		result := tx.Model(&model.Webhook{}).
+			Where("id = ? AND active AND consecutive_failures >= ?", id, disableAfter).
+			UpdateColumns(map[string]any{"active": false, "disabled_at": time.Now()})
+		if result.Error != nil {
+			return fmt.Errorf("failed to disable webhook: %v", result.Error)
+		}
+		disabled = result.RowsAffected > 0
+
+		return nil
+	})
+
+	return disabled, err
+}
+
+func (ps *PostgresStore) CreateWebhookDeliveries(deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	// Deliveries that already exist are kept, the event was redelivered
+	if err := ps.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error; err != nil {
+		return fmt.Errorf("failed to queue deliveries: %v", err)
+	}
+
+	return nil
+}
+
+// GetDueWebhookDeliveries returns pending deliveries whose next attempt is
+// due, oldest first
+func (ps *PostgresStore) GetDueWebhookDeliveries(now time.Time, limit int) utils.Result[[]model.WebhookDelivery] {
+	var deliveries []model.WebhookDelivery
+	err := ps.db.Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, now).
+		Order("next_attempt_at").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return utils.Result[[]model.WebhookDelivery]{Err: fmt.Errorf("failed to get deliveries: %v", err)}
+	}
+
+	return utils.Result[[]model.WebhookDelivery]{Data: deliveries}
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook, newest first
+func (ps *PostgresStore) GetWebhookDeliveries(webhookID uuid.UUID, limit int) utils.Result[[]model.WebhookDelivery] {
+	var deliveries []model.WebhookDelivery
+	err := ps.db.Where("webhook_id = ?", webhookID).Order("created_at DESC").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return utils.Result[[]model.WebhookDelivery]{Err: fmt.Errorf("failed to get deliveries: %v", err)}
+	}
+
+	return utils.Result[[]model.WebhookDelivery]{Data: deliveries}
+}
+
+func (ps *PostgresStore) GetWebhookDelivery(id uuid.UUID) utils.Result[model.WebhookDelivery] {
+	var delivery model.WebhookDelivery
+	if err := ps.db.First(&delivery, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.Result[model.WebhookDelivery]{Err: ErrDeliveryNotFound}
+		}
+		return utils.Result[model.WebhookDelivery]{Err: fmt.Errorf("failed to get delivery: %v", err)}
+	}
+
+	return utils.Result[model.WebhookDelivery]{Data: delivery}
+}
+
+func (ps *PostgresStore) UpdateWebhookDelivery(delivery model.WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now()
+
+	if err := ps.db.Save(&delivery).Error; err != nil {
+		return fmt.Errorf("failed to update delivery: %v", err)
+	}
+
+	return nil
+}
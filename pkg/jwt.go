@@ -4,34 +4,65 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"test_project/test/internal/model"
+	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 )
 
-func ExtractUserIDFromToken(r *http.Request) (uuid.UUID, error) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		return uuid.Nil, errors.New("no auth header")
-	}
+// Token scopes. A token without a scope is a regular session token, scoped
+// tokens are only accepted by the endpoints that ask for them.
+const (
+	ScopeTOTPChallenge = "totp_challenge"
+	ScopeTOTPEnroll    = "totp_enroll"
+	ScopeOIDCState     = "oidc_state"
+)
 
-	tokenStr := strings.Split(authHeader, " ")
-	if len(tokenStr) != 2 {
-		return uuid.Nil, errors.New("invalid token format")
-	}
+func jwtSecret() []byte {
+	return []byte(GetEnvOrDefault("JWT_SECRET", "default"))
+}
+
+func GenerateToken(claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	claims["exp"] = time.Now().Add(ttl).Unix()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret())
+}
 
-	token, err := jwt.Parse(tokenStr[1], func(token *jwt.Token) (interface{}, error) {
-		return []byte(GetEnvOrDefault("JWT_SECRET", "default")), nil
+func ParseToken(tokenStr string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return jwtSecret(), nil
 	})
 	if err != nil || !token.Valid {
-		return uuid.Nil, errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return uuid.Nil, errors.New("could not parse claims")
+		return nil, errors.New("could not parse claims")
 	}
 
+	return claims, nil
+}
+
+func ExtractClaimsFromToken(r *http.Request) (jwt.MapClaims, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, errors.New("no auth header")
+	}
+
+	tokenStr := strings.Split(authHeader, " ")
+	if len(tokenStr) != 2 {
+		return nil, errors.New("invalid token format")
+	}
+
+	return ParseToken(tokenStr[1])
+}
+
+func ClaimUserID(claims jwt.MapClaims) (uuid.UUID, error) {
 	userIDStr, ok := claims["user_id"].(string)
 	if !ok {
 		return uuid.Nil, errors.New("user_id not found in token")
@@ -44,3 +75,69 @@ func ExtractUserIDFromToken(r *http.Request) (uuid.UUID, error) {
 
 	return userID, nil
 }
+
+// ClaimSessionID returns the jti claim, the ID of the session the token belongs to
+func ClaimSessionID(claims jwt.MapClaims) (uuid.UUID, error) {
+	jti, ok := claims["jti"].(string)
+	if !ok {
+		return uuid.Nil, errors.New("token has no session")
+	}
+
+	sessionID, err := uuid.Parse(jti)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid session id in token")
+	}
+
+	return sessionID, nil
+}
+
+func ClaimScope(claims jwt.MapClaims) string {
+	scope, _ := claims["scope"].(string)
+	return scope
+}
+
+func ExtractUserIDFromToken(r *http.Request) (uuid.UUID, error) {
+	claims, err := ExtractClaimsFromToken(r)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// Scoped tokens are not sessions, they can't act on behalf of the user
+	if ClaimScope(claims) != "" {
+		return uuid.Nil, errors.New("token is not valid for this operation")
+	}
+
+	return ClaimUserID(claims)
+}
+
+func ExtractSessionIDFromToken(r *http.Request) (uuid.UUID, error) {
+	claims, err := ExtractClaimsFromToken(r)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return ClaimSessionID(claims)
+}
+
+// ExtractActorFromToken returns who is making the request. The role comes
+// from the token, it is refreshed on every login.
+func ExtractActorFromToken(r *http.Request) (model.Actor, error) {
+	claims, err := ExtractClaimsFromToken(r)
+	if err != nil {
+		return model.Actor{}, err
+	}
+
+	if ClaimScope(claims) != "" {
+		return model.Actor{}, errors.New("token is not valid for this operation")
+	}
+
+	userID, err := ClaimUserID(claims)
+	if err != nil {
+		return model.Actor{}, err
+	}
+
+	username, _ := claims["username"].(string)
+	role, _ := claims["role"].(string)
+
+	return model.Actor{ID: userID, Username: username, Role: model.Role(role)}, nil
+}
@@ -0,0 +1,442 @@
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	entityRe    = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	autolinkRe  = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^\s<>]*)>`)
+	emailLinkRe = regexp.MustCompile(`^<([A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*)>`)
+	tagRe       = regexp.MustCompile(`<[^>]*>`)
+)
+
+// inlineNode is a piece of inline output. Delimiter runs of * and _ become
+// emphasis once they are matched up, the rest is written as text.
+type inlineNode struct {
+	text string
+	html string
+
+	delim    byte
+	count    int
+	original int
+	canOpen  bool
+	canClose bool
+	active   bool
+	opens    []string
+	closes   []string
+}
+
+// inline renders the text of a paragraph or heading. Links can't nest, so
+// link text is rendered with inLink set.
+func (r *renderer) inline(s string, inLink bool) string {
+	var nodes []*inlineNode
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, &inlineNode{text: text.String()})
+			text.Reset()
+		}
+	}
+	addHTML := func(markup string) {
+		flush()
+		nodes = append(nodes, &inlineNode{html: markup})
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			addHTML("<br>\n")
+			i += 2
+
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			text.WriteByte(s[i+1])
+			i += 2
+
+		case c == '`':
+			n := runLength(s, i, '`')
+			if end := closingBackticks(s, i+n, n); end >= 0 {
+				addHTML("<code>" + html.EscapeString(codeSpan(s[i+n:end])) + "</code>")
+				i = end + n
+			} else {
+				text.WriteString(s[i : i+n])
+				i += n
+			}
+
+		case c == '*' || c == '_':
+			n := runLength(s, i, c)
+			before, _ := utf8.DecodeLastRuneInString(s[:i])
+			after, _ := utf8.DecodeRuneInString(s[i+n:])
+			if i == 0 {
+				before = ' '
+			}
+			if i+n == len(s) {
+				after = ' '
+			}
+			node := &inlineNode{delim: c, count: n, original: n, active: true}
+			node.canOpen, node.canClose = flanking(c, before, after)
+			flush()
+			nodes = append(nodes, node)
+			i += n
+
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if markup, end, ok := r.link(s, i+1, true, inLink); ok {
+				addHTML(markup)
+				i = end
+			} else {
+				text.WriteByte(c)
+				i++
+			}
+
+		case c == '[' && !inLink:
+			if markup, end, ok := r.link(s, i, false, inLink); ok {
+				addHTML(markup)
+				i = end
+			} else {
+				text.WriteByte(c)
+				i++
+			}
+
+		case c == '<':
+			if m := autolinkRe.FindStringSubmatch(s[i:]); m != nil && safeURL(m[1], false) {
+				addHTML(`<a href="` + html.EscapeString(m[1]) + `">` + html.EscapeString(m[1]) + "</a>")
+				i += len(m[0])
+			} else if m := emailLinkRe.FindStringSubmatch(s[i:]); m != nil {
+				addHTML(`<a href="mailto:` + html.EscapeString(m[1]) + `">` + html.EscapeString(m[1]) + "</a>")
+				i += len(m[0])
+			} else {
+				text.WriteByte(c)
+				i++
+			}
+
+		case c == '&':
+			if m := entityRe.FindString(s[i:]); m != "" && html.UnescapeString(m) != m {
+				text.WriteString(html.UnescapeString(m))
+				i += len(m)
+			} else {
+				text.WriteByte(c)
+				i++
+			}
+
+		case c == '\n':
+			// Two spaces at the end of a line make a hard break
+			line := text.String()
+			trimmed := strings.TrimRight(line, " ")
+			text.Reset()
+			text.WriteString(trimmed)
+			if len(line)-len(trimmed) >= 2 {
+				addHTML("<br>\n")
+			} else {
+				text.WriteByte('\n')
+			}
+			i++
+
+		default:
+			text.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+
+	matchEmphasis(nodes)
+
+	var out strings.Builder
+	for _, node := range nodes {
+		switch {
+		case node.delim != 0:
+			for _, tag := range node.closes {
+				out.WriteString(tag)
+			}
+			out.WriteString(strings.Repeat(string(node.delim), node.count))
+			for j := len(node.opens) - 1; j >= 0; j-- {
+				out.WriteString(node.opens[j])
+			}
+		case node.html != "":
+			out.WriteString(node.html)
+		default:
+			out.WriteString(html.EscapeString(node.text))
+		}
+	}
+	return out.String()
+}
+
+// matchEmphasis pairs up delimiter runs following the CommonMark rules: each
+// closer takes the nearest matching opener, two characters make <strong>,
+// one makes <em>, and runs that could both open and close only match when
+// their lengths don't add up to a multiple of three.
+func matchEmphasis(nodes []*inlineNode) {
+	for ci, closer := range nodes {
+		if closer.delim == 0 || !closer.canClose {
+			continue
+		}
+
+		for closer.count > 0 {
+			oi := -1
+			for j := ci - 1; j >= 0; j-- {
+				opener := nodes[j]
+				if opener.delim != closer.delim || !opener.canOpen || !opener.active || opener.count == 0 {
+					continue
+				}
+				if (opener.canClose || closer.canOpen) && (opener.original+closer.original)%3 == 0 &&
+					!(opener.original%3 == 0 && closer.original%3 == 0) {
+					continue
+				}
+				oi = j
+				break
+			}
+			if oi < 0 {
+				break
+			}
+
+			opener := nodes[oi]
+			tag := "em"
+			use := 1
+			if opener.count >= 2 && closer.count >= 2 {
+				tag, use = "strong", 2
+			}
+			opener.count -= use
+			closer.count -= use
+			opener.opens = append(opener.opens, "<"+tag+">")
+			closer.closes = append(closer.closes, "</"+tag+">")
+
+			// Runs between the two can no longer match, they stay text
+			for _, node := range nodes[oi+1 : ci] {
+				node.active = false
+			}
+		}
+	}
+}
+
+func flanking(delim byte, before, after rune) (canOpen, canClose bool) {
+	left := !unicode.IsSpace(after) && (!isPunct(after) || unicode.IsSpace(before) || isPunct(before))
+	right := !unicode.IsSpace(before) && (!isPunct(before) || unicode.IsSpace(after) || isPunct(after))
+	if delim == '*' {
+		return left, right
+	}
+	// Underscores don't emphasize inside words
+	return left && (!right || isPunct(before)), right && (!left || isPunct(after))
+}
+
+// link parses [text](destination "title") starting at the bracket, or the
+// image form when image is set. Reference links are not supported and stay
+// text.
+func (r *renderer) link(s string, start int, image, inLink bool) (string, int, bool) {
+	labelEnd := closingBracket(s, start)
+	if labelEnd < 0 || labelEnd+1 >= len(s) || s[labelEnd+1] != '(' {
+		return "", 0, false
+	}
+	destination, title, end, ok := linkTarget(s, labelEnd+2)
+	if !ok {
+		return "", 0, false
+	}
+	label := s[start+1 : labelEnd]
+
+	if image {
+		alt := plainText(r.inline(label, true))
+		if !safeURL(destination, true) {
+			return html.EscapeString(alt), end, true
+		}
+		markup := `<img src="` + html.EscapeString(destination) + `" alt="` + html.EscapeString(alt) + `"`
+		if title != "" {
+			markup += ` title="` + html.EscapeString(title) + `"`
+		}
+		return markup + ">", end, true
+	}
+
+	content := r.inline(label, true)
+	if inLink || !safeURL(destination, false) {
+		return content, end, true
+	}
+	markup := `<a href="` + html.EscapeString(destination) + `"`
+	if title != "" {
+		markup += ` title="` + html.EscapeString(title) + `"`
+	}
+	return markup + ">" + content + "</a>", end, true
+}
+
+// closingBracket finds the ] matching the [ at start, skipping escaped
+// brackets and code spans
+func closingBracket(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '`':
+			n := runLength(s, i, '`')
+			if end := closingBackticks(s, i+n, n); end >= 0 {
+				i = end + n - 1
+			} else {
+				i += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// linkTarget parses the part of a link after "(": the destination, an
+// optional title and the closing parenthesis
+func linkTarget(s string, i int) (destination, title string, end int, ok bool) {
+	i = skipSpace(s, i)
+	if i >= len(s) {
+		return "", "", 0, false
+	}
+
+	if s[i] == '<' {
+		j := i + 1
+		for ; j < len(s) && s[j] != '>'; j++ {
+			if s[j] == '\n' || s[j] == '<' {
+				return "", "", 0, false
+			}
+			if s[j] == '\\' {
+				j++
+			}
+		}
+		if j >= len(s) {
+			return "", "", 0, false
+		}
+		destination = strings.ReplaceAll(unescape(s[i+1:j]), " ", "%20")
+		i = j + 1
+	} else {
+		depth := 0
+		j := i
+	loop:
+		for ; j < len(s); j++ {
+			switch c := s[j]; {
+			case c == '\\' && j+1 < len(s) && isASCIIPunct(s[j+1]):
+				j++
+			case c == '(':
+				depth++
+			case c == ')':
+				if depth == 0 {
+					break loop
+				}
+				depth--
+			case c <= ' ':
+				break loop
+			}
+		}
+		destination = unescape(s[i:j])
+		i = j
+	}
+
+	j := skipSpace(s, i)
+	if j < len(s) && j > i && (s[j] == '"' || s[j] == '\'' || s[j] == '(') {
+		closing := s[j]
+		if closing == '(' {
+			closing = ')'
+		}
+		k := j + 1
+		for ; k < len(s) && s[k] != closing; k++ {
+			if s[k] == '\\' {
+				k++
+			}
+		}
+		if k >= len(s) {
+			return "", "", 0, false
+		}
+		title = unescape(s[j+1 : k])
+		j = skipSpace(s, k+1)
+	}
+
+	if j >= len(s) || s[j] != ')' {
+		return "", "", 0, false
+	}
+	return destination, title, j + 1, true
+}
+
+// safeURL allows relative URLs and http(s), links may also be mailto.
+// Anything else, like javascript: or data:, is dropped.
+func safeURL(raw string, image bool) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https":
+		return true
+	case "mailto":
+		return !image
+	}
+	return false
+}
+
+// unescape resolves backslash escapes and entities in link destinations and
+// titles
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return html.UnescapeString(b.String())
+}
+
+func closingBackticks(s string, from, n int) int {
+	for i := from; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		run := runLength(s, i, '`')
+		if run == n {
+			return i
+		}
+		i += run
+	}
+	return -1
+}
+
+// codeSpan normalizes the content of a code span: line endings become spaces
+// and one space of padding on both sides is removed
+func codeSpan(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) >= 2 && s[0] == ' ' && s[len(s)-1] == ' ' && strings.TrimSpace(s) != "" {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
+
+// plainText strips the tags from rendered inline HTML
+func plainText(markup string) string {
+	return html.UnescapeString(tagRe.ReplaceAllString(markup, ""))
+}
+
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && c > ' ' && c < 0x7f && !(c >= '0' && c <= '9') &&
+		!(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z')
+}
+
+func isPunct(c rune) bool {
+	return unicode.IsPunct(c) || unicode.IsSymbol(c)
+}
@@ -0,0 +1,410 @@
+// Package markdown renders CommonMark to HTML that is safe to embed in a
+// page. It covers the commonly used part of CommonMark: headings, paragraphs,
+// emphasis, code, block quotes, lists, links, images and thematic breaks.
+// Raw HTML in the source is shown as text, and the output is passed through
+// an allowlist sanitizer before it is returned.
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	atxHeadingRe = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	fenceRe      = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})(.*)$")
+	breakRe      = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	quoteRe      = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	listItemRe   = regexp.MustCompile(`^( {0,3})([-+*]|[0-9]{1,9}[.)])(?:([ \t]+)(.*))?$`)
+	setextH1Re   = regexp.MustCompile(`^ {0,3}=+[ \t]*$`)
+	setextH2Re   = regexp.MustCompile(`^ {0,3}-+[ \t]*$`)
+	languageRe   = regexp.MustCompile(`^[A-Za-z0-9_+#.-]+$`)
+)
+
+// Render turns markdown into sanitized HTML
+func Render(source string) string {
+	r := &renderer{ids: map[string]int{}}
+	r.blocks(splitLines(source), false)
+	return Sanitize(r.out.String())
+}
+
+type renderer struct {
+	out strings.Builder
+	// Heading IDs handed out so far, to keep them unique
+	ids map[string]int
+}
+
+// blocks renders a sequence of lines as block elements. Paragraphs of tight
+// list items are written without <p>.
+func (r *renderer) blocks(lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		switch {
+		case isBlank(line):
+			i++
+		case fenceRe.MatchString(line) && isFence(line):
+			i = r.fencedCode(lines, i)
+		case indentOf(line) >= 4:
+			i = r.indentedCode(lines, i)
+		case atxHeadingRe.MatchString(line):
+			m := atxHeadingRe.FindStringSubmatch(line)
+			r.heading(len(m[1]), strings.TrimSpace(m[2]))
+			i++
+		case breakRe.MatchString(line):
+			r.out.WriteString("<hr>\n")
+			i++
+		case quoteRe.MatchString(line):
+			i = r.blockQuote(lines, i)
+		case listItemRe.MatchString(line):
+			i = r.list(lines, i)
+		default:
+			i = r.paragraph(lines, i, tight)
+		}
+	}
+}
+
+func (r *renderer) heading(level int, text string) {
+	content := r.inline(text, false)
+	fmt.Fprintf(&r.out, "<h%d id=\"%s\">%s</h%d>\n", level, r.headingID(plainText(content)), content, level)
+}
+
+// headingIDPrefix keeps heading anchors apart from the IDs of the page the
+// HTML is embedded in
+const headingIDPrefix = "user-content-"
+
+// headingID derives an anchor from the heading text, the way most markdown
+// hosts do: lower case, spaces become dashes, punctuation is dropped. A
+// repeated heading gets the first numbered suffix no other heading has.
+func (r *renderer) headingID(text string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '-':
+			b.WriteRune(c)
+		case unicode.IsSpace(c):
+			b.WriteByte('-')
+		}
+	}
+
+	id := b.String()
+	if id == "" {
+		id = "section"
+	}
+	if n, seen := r.ids[id]; seen {
+		base := id
+		for {
+			n++
+			id = base + "-" + strconv.Itoa(n)
+			if _, taken := r.ids[id]; !taken {
+				break
+			}
+		}
+		r.ids[base] = n
+	}
+	r.ids[id] = 0
+	return headingIDPrefix + id
+}
+
+func (r *renderer) fencedCode(lines []string, start int) int {
+	m := fenceRe.FindStringSubmatch(lines[start])
+	indent, fence := len(m[1]), m[2]
+	language := strings.Fields(html.UnescapeString(strings.TrimSpace(m[3])))
+
+	var code []string
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if c := fenceRe.FindStringSubmatch(line); c != nil && c[2][0] == fence[0] &&
+			len(c[2]) >= len(fence) && strings.TrimSpace(c[3]) == "" {
+			i++
+			break
+		}
+		code = append(code, stripIndent(line, indent))
+	}
+
+	r.out.WriteString("<pre><code")
+	if len(language) > 0 && languageRe.MatchString(language[0]) {
+		fmt.Fprintf(&r.out, " class=\"language-%s\"", language[0])
+	}
+	r.out.WriteString(">")
+	r.writeCode(code)
+	r.out.WriteString("</code></pre>\n")
+	return i
+}
+
+func (r *renderer) indentedCode(lines []string, start int) int {
+	var code []string
+	i := start
+	for ; i < len(lines) && (isBlank(lines[i]) || indentOf(lines[i]) >= 4); i++ {
+		code = append(code, stripIndent(lines[i], 4))
+	}
+	for len(code) > 0 && isBlank(code[len(code)-1]) {
+		code = code[:len(code)-1]
+	}
+
+	r.out.WriteString("<pre><code>")
+	r.writeCode(code)
+	r.out.WriteString("</code></pre>\n")
+	return start + len(code)
+}
+
+func (r *renderer) writeCode(lines []string) {
+	for _, line := range lines {
+		r.out.WriteString(html.EscapeString(line))
+		r.out.WriteByte('\n')
+	}
+}
+
+// blockQuote collects the quoted lines, and lines continuing a quoted
+// paragraph without the marker, and renders them as blocks of their own
+func (r *renderer) blockQuote(lines []string, start int) int {
+	var inner []string
+	i := start
+	for ; i < len(lines); i++ {
+		if m := quoteRe.FindStringSubmatch(lines[i]); m != nil {
+			inner = append(inner, m[1])
+			continue
+		}
+		if isBlank(lines[i]) || isBlank(inner[len(inner)-1]) || startsBlock(lines[i]) {
+			break
+		}
+		inner = append(inner, lines[i])
+	}
+
+	r.out.WriteString("<blockquote>\n")
+	r.blocks(inner, false)
+	r.out.WriteString("</blockquote>\n")
+	return i
+}
+
+type listItem struct {
+	ordered bool
+	// The bullet character, or the delimiter after the number
+	marker  byte
+	number  int
+	indent  int
+	content string
+}
+
+func parseListItem(line string) (listItem, bool) {
+	m := listItemRe.FindStringSubmatch(line)
+	if m == nil {
+		return listItem{}, false
+	}
+
+	item := listItem{marker: m[2][len(m[2])-1]}
+	width := len(m[1]) + len(m[2])
+	if len(m[2]) > 1 || (m[2][0] >= '0' && m[2][0] <= '9') {
+		item.ordered = true
+		item.number, _ = strconv.Atoi(m[2][:len(m[2])-1])
+	}
+
+	spaces := len(expandTabs(m[3]))
+	switch {
+	case m[4] == "":
+		item.indent = width + 1
+	case spaces > 4:
+		// The content is indented code, only one space belongs to the marker
+		item.indent = width + 1
+		item.content = strings.Repeat(" ", spaces-1) + m[4]
+	default:
+		item.indent = width + spaces
+		item.content = m[4]
+	}
+	return item, true
+}
+
+func (r *renderer) list(lines []string, start int) int {
+	first, _ := parseListItem(lines[start])
+	var items [][]string
+	loose := false
+
+	i := start
+	for {
+		item, _ := parseListItem(lines[i])
+		content := []string{item.content}
+
+		j := i + 1
+		for ; j < len(lines); j++ {
+			line := lines[j]
+			if isBlank(line) {
+				content = append(content, "")
+				continue
+			}
+			if indentOf(line) >= item.indent {
+				content = append(content, stripIndent(line, item.indent))
+				continue
+			}
+			// A line that is not indented enough only continues a paragraph
+			if isBlank(content[len(content)-1]) || listItemRe.MatchString(line) || startsBlock(line) {
+				break
+			}
+			content = append(content, line)
+		}
+
+		trailing := 0
+		for len(content) > 1 && isBlank(content[len(content)-1]) {
+			content = content[:len(content)-1]
+			trailing++
+		}
+		if hasInnerBlankLine(content) {
+			loose = true
+		}
+		items = append(items, content)
+
+		next, ok := listItem{}, false
+		if j < len(lines) {
+			next, ok = parseListItem(lines[j])
+		}
+		if !ok || next.ordered != first.ordered || next.marker != first.marker || breakRe.MatchString(lines[j]) {
+			i = j
+			break
+		}
+		if trailing > 0 {
+			loose = true
+		}
+		i = j
+	}
+
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+	if first.ordered && first.number != 1 {
+		fmt.Fprintf(&r.out, "<ol start=\"%d\">\n", first.number)
+	} else {
+		fmt.Fprintf(&r.out, "<%s>\n", tag)
+	}
+	for _, content := range items {
+		r.out.WriteString("<li>")
+		r.blocks(content, !loose)
+		r.out.WriteString("</li>\n")
+	}
+	fmt.Fprintf(&r.out, "</%s>\n", tag)
+
+	return i
+}
+
+// paragraph collects lines up to a blank line or another block. A following
+// underline of = or - turns the paragraph into a heading.
+func (r *renderer) paragraph(lines []string, start int, tight bool) int {
+	// Trailing spaces stay on the lines, two of them make a hard break
+	text := []string{strings.TrimLeft(lines[start], " \t")}
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) {
+			break
+		}
+		if setextH1Re.MatchString(line) || setextH2Re.MatchString(line) {
+			level := 1
+			if setextH2Re.MatchString(line) {
+				level = 2
+			}
+			r.heading(level, strings.TrimSpace(strings.Join(text, "\n")))
+			return i + 1
+		}
+		if startsBlock(line) && !canContinueParagraph(line) {
+			break
+		}
+		text = append(text, strings.TrimLeft(line, " \t"))
+	}
+
+	content := r.inline(strings.TrimRight(strings.Join(text, "\n"), " \t"), false)
+	if tight {
+		r.out.WriteString(content)
+		return i
+	}
+	r.out.WriteString("<p>" + content + "</p>\n")
+	return i
+}
+
+// startsBlock reports whether the line opens a block other than a paragraph
+// or indented code
+func startsBlock(line string) bool {
+	return (fenceRe.MatchString(line) && isFence(line)) || atxHeadingRe.MatchString(line) ||
+		breakRe.MatchString(line) || quoteRe.MatchString(line) || listItemRe.MatchString(line)
+}
+
+// canContinueParagraph reports whether a line that looks like a list item
+// stays part of a paragraph. Only non-empty bullets and lists starting at 1
+// interrupt one.
+func canContinueParagraph(line string) bool {
+	if !listItemRe.MatchString(line) || breakRe.MatchString(line) {
+		return false
+	}
+	item, _ := parseListItem(line)
+	return strings.TrimSpace(item.content) == "" || (item.ordered && item.number != 1)
+}
+
+func isFence(line string) bool {
+	m := fenceRe.FindStringSubmatch(line)
+	return m != nil && !(m[2][0] == '`' && strings.Contains(m[3], "`"))
+}
+
+// hasInnerBlankLine reports a blank line between the blocks of a list item,
+// which makes the list loose. Blank lines inside fenced code don't count.
+func hasInnerBlankLine(lines []string) bool {
+	fence := ""
+	for _, line := range lines {
+		if m := fenceRe.FindStringSubmatch(line); m != nil && isFence(line) {
+			switch {
+			case fence == "":
+				fence = m[2]
+			case m[2][0] == fence[0] && len(m[2]) >= len(fence):
+				fence = ""
+			}
+			continue
+		}
+		if fence == "" && isBlank(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitLines(source string) []string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+	source = strings.ReplaceAll(source, "\x00", "�")
+
+	lines := strings.Split(source, "\n")
+	for i, line := range lines {
+		rest := strings.TrimLeft(line, " \t")
+		lines[i] = expandTabs(line[:len(line)-len(rest)]) + rest
+	}
+	return lines
+}
+
+// expandTabs turns leading whitespace into spaces, tab stops are 4 wide
+func expandTabs(s string) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	var b strings.Builder
+	for _, c := range s {
+		if c == '\t' {
+			b.WriteString(strings.Repeat(" ", 4-b.Len()%4))
+		} else {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func stripIndent(line string, n int) string {
+	return line[min(n, indentOf(line)):]
+}
@@ -0,0 +1,149 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+type renderTest struct {
+	name     string
+	markdown string
+	want     string
+}
+
+func runRenderTests(t *testing.T, tests []renderTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.markdown); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.markdown, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderDropsUnsafeURLs(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"javascript link", "[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"mixed case scheme", "[x](JavaScript:alert(1))", "<p>x</p>\n"},
+		{"leading space", "[x]( javascript:alert(1))", "<p>x</p>\n"},
+		{"entity colon", "[x](javascript&#58;alert(1))", "<p>x</p>\n"},
+		{"entity letter", "[x](&#106;avascript:alert(1))", "<p>x</p>\n"},
+		{"entity tab inside scheme", "[x](java&#x09;script:alert(1))", "<p>x</p>\n"},
+		{"vbscript", "[x](vbscript:msgbox)", "<p>x</p>\n"},
+		{"data link", "[x](data:text/html,<script>alert(1)</script>)", "<p>x</p>\n"},
+		{"data image", "![x](data:image/png;base64,AAAA)", "<p>x</p>\n"},
+		{"javascript autolink", "<javascript:alert(1)>", "<p>&lt;javascript:alert(1)&gt;</p>\n"},
+		{"mailto image", "![x](mailto:a@example.com)", "<p>x</p>\n"},
+		{"mailto link", "[x](mailto:a@example.com)", `<p><a href="mailto:a@example.com" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"https link", "[x](https://example.com)", `<p><a href="https://example.com" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"relative link", "[x](/ideas)", `<p><a href="/ideas" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"https image", "![x](https://example.com/i.png)", `<p><img src="https://example.com/i.png" alt="x"></p>` + "\n"},
+	})
+}
+
+func TestRenderShowsRawHTMLAsText(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"script", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"event handler", "<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+		{"inline tag", `hello <b onclick="x">bold</b>`, "<p>hello &lt;b onclick=&#34;x&#34;&gt;bold&lt;/b&gt;</p>\n"},
+		{"code block", "```\n<script>\n```", "<pre><code>&lt;script&gt;\n</code></pre>\n"},
+		{"code span", "`<b>`", "<p><code>&lt;b&gt;</code></p>\n"},
+		{"in heading", "# <script>x</script>", `<h1 id="user-content-scriptxscript">&lt;script&gt;x&lt;/script&gt;</h1>` + "\n"},
+	})
+}
+
+func TestRenderQuotesAttributes(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{
+			"escaped quotes in title",
+			`[x](https://example.com "a \"quoted\" title")`,
+			`<p><a href="https://example.com" title="a &#34;quoted&#34; title" rel="nofollow noopener noreferrer">x</a></p>` + "\n",
+		},
+		{
+			"markup in title",
+			`[x](https://example.com "<b>&amp;'")`,
+			`<p><a href="https://example.com" title="&lt;b&gt;&amp;&#39;" rel="nofollow noopener noreferrer">x</a></p>` + "\n",
+		},
+		{
+			"image alt and title",
+			`![a "b"](https://example.com/i.png "c'd")`,
+			`<p><img src="https://example.com/i.png" alt="a &#34;b&#34;" title="c&#39;d"></p>` + "\n",
+		},
+		{
+			"quote in autolink",
+			`<https://example.com/a?b="c">`,
+			`<p><a href="https://example.com/a?b=&#34;c&#34;" rel="nofollow noopener noreferrer">https://example.com/a?b=&#34;c&#34;</a></p>` + "\n",
+		},
+		{
+			"attribute injection in autolink",
+			`<https://example.com/"onmouseover="alert(1)>`,
+			`<p><a href="https://example.com/&#34;onmouseover=&#34;alert(1)" rel="nofollow noopener noreferrer">https://example.com/&#34;onmouseover=&#34;alert(1)</a></p>` + "\n",
+		},
+	})
+}
+
+func TestRenderHeadingIDs(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"simple", "# Hello World", `<h1 id="user-content-hello-world">Hello World</h1>` + "\n"},
+		{"punctuation dropped", "## What's new?", `<h2 id="user-content-whats-new">What&#39;s new?</h2>` + "\n"},
+		{"unicode kept", "## Über Café", `<h2 id="user-content-über-café">Über Café</h2>` + "\n"},
+		{"nothing left", "# !!!", `<h1 id="user-content-section">!!!</h1>` + "\n"},
+		{
+			"repeated",
+			"# Hello World\n# Hello World\n# Hello World-1",
+			`<h1 id="user-content-hello-world">Hello World</h1>` + "\n" +
+				`<h1 id="user-content-hello-world-1">Hello World</h1>` + "\n" +
+				`<h1 id="user-content-hello-world-1-1">Hello World-1</h1>` + "\n",
+		},
+		{
+			"suffix already taken",
+			"# Hello World-1\n# Hello World\n# Hello World",
+			`<h1 id="user-content-hello-world-1">Hello World-1</h1>` + "\n" +
+				`<h1 id="user-content-hello-world">Hello World</h1>` + "\n" +
+				`<h1 id="user-content-hello-world-2">Hello World</h1>` + "\n",
+		},
+	})
+}
+
+func TestRenderHeadingIDsAreUnique(t *testing.T) {
+	source := strings.Repeat("# a\n# a-1\n# a-2\n## a\n", 5)
+	seen := map[string]bool{}
+	for _, part := range strings.Split(Render(source), `id="`)[1:] {
+		id := part[:strings.IndexByte(part, '"')]
+		if seen[id] {
+			t.Errorf("id %q handed out twice", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != 20 {
+		t.Errorf("got %d ids, want 20", len(seen))
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment string
+		want     string
+	}{
+		{"unprefixed id", `<h1 id="main">x</h1>`, "<h1>x</h1>"},
+		{"prefixed id", `<h1 id="user-content-main">x</h1>`, `<h1 id="user-content-main">x</h1>`},
+		{"event handler", `<p onclick="x">t</p>`, "<p>t</p>"},
+		{"style", `<p style="color:red">t</p>`, "<p>t</p>"},
+		{"javascript href", `<a href="javascript:x">z</a>`, `<a rel="nofollow noopener noreferrer">z</a>`},
+		{"entity encoded href", `<a href="&#106;avascript:x">z</a>`, `<a rel="nofollow noopener noreferrer">z</a>`},
+		{"own rel replaced", `<a href="/x" rel="opener">z</a>`, `<a href="/x" rel="nofollow noopener noreferrer">z</a>`},
+		{"script dropped with content", "<p>a<script>bad()</script>b</p>", "<p>ab</p>"},
+		{"unknown tag keeps text", "<div><span>t</span></div>", "t"},
+		{"code class", `<code class="language-go">x</code><code class="x onclick">y</code>`, `<code class="language-go">x</code><code>y</code>`},
+		{"text escaped", "<p>a &lt; b</p>", "<p>a &lt; b</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.fragment); got != tt.want {
+				t.Errorf("Sanitize(%q)\n got %q\nwant %q", tt.fragment, got, tt.want)
+			}
+		})
+	}
+}
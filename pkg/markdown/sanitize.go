@@ -0,0 +1,118 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// Links open with this rel, user content should not pass on referrers or
+// ranking
+const linkRel = "nofollow noopener noreferrer"
+
+// allowedTags maps the tags kept by Sanitize to the attributes they may have
+var allowedTags = map[string][]string{
+	"p": nil, "br": nil, "hr": nil,
+	"h1": {"id"}, "h2": {"id"}, "h3": {"id"}, "h4": {"id"}, "h5": {"id"}, "h6": {"id"},
+	"strong": nil, "em": nil, "code": {"class"}, "pre": nil, "blockquote": nil,
+	"ul": nil, "ol": {"start"}, "li": nil,
+	"a":   {"href", "title"},
+	"img": {"src", "alt", "title"},
+}
+
+// Elements dropped together with their content
+var droppedTags = map[string]bool{"script": true, "style": true, "iframe": true, "object": true, "textarea": true, "title": true}
+
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true}
+
+var (
+	idRe    = regexp.MustCompile(`^` + headingIDPrefix + `[\pL\pN_-]+$`)
+	classRe = regexp.MustCompile(`^language-[A-Za-z0-9_+#.-]+$`)
+	startRe = regexp.MustCompile(`^[0-9]{1,9}$`)
+)
+
+// Sanitize keeps only allowlisted tags and attributes of an HTML fragment.
+// Other tags are removed but their text is kept, links and images must point
+// at safe URLs.
+func Sanitize(fragment string) string {
+	z := xhtml.NewTokenizer(strings.NewReader(fragment))
+	var out strings.Builder
+	dropped := 0
+
+	for {
+		switch z.Next() {
+		case xhtml.ErrorToken:
+			return out.String()
+
+		case xhtml.TextToken:
+			if dropped == 0 {
+				out.WriteString(html.EscapeString(string(z.Text())))
+			}
+
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			token := z.Token()
+			if droppedTags[token.Data] {
+				if token.Type == xhtml.StartTagToken {
+					dropped++
+				}
+				continue
+			}
+			attributes, ok := allowedTags[token.Data]
+			if dropped > 0 || !ok {
+				continue
+			}
+
+			out.WriteString("<" + token.Data)
+			for _, attr := range token.Attr {
+				if attr.Namespace == "" && allowedAttribute(token.Data, attr.Key, attr.Val, attributes) {
+					out.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+				}
+			}
+			if token.Data == "a" {
+				out.WriteString(` rel="` + linkRel + `"`)
+			}
+			out.WriteString(">")
+
+		case xhtml.EndTagToken:
+			token := z.Token()
+			if droppedTags[token.Data] {
+				if dropped > 0 {
+					dropped--
+				}
+				continue
+			}
+			if _, ok := allowedTags[token.Data]; ok && dropped == 0 && !voidTags[token.Data] {
+				out.WriteString("</" + token.Data + ">")
+			}
+		}
+	}
+}
+
+func allowedAttribute(tag, key, value string, allowed []string) bool {
+	found := false
+	for _, a := range allowed {
+		if a == key {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+
+	switch key {
+	case "href":
+		return safeURL(value, false)
+	case "src":
+		return safeURL(value, true)
+	case "id":
+		return idRe.MatchString(value)
+	case "class":
+		return classRe.MatchString(value)
+	case "start":
+		return startRe.MatchString(value)
+	}
+	return true
+}
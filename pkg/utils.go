@@ -1,9 +1,14 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"strings"
 	"test_project/test/internal/model"
 
 	"github.com/google/uuid"
@@ -13,6 +18,12 @@ func GenId() string {
 	return uuid.NewString()
 }
 
+// HashSecret is for high-entropy secrets like recovery codes, not passwords
+func HashSecret(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
 func GetEnvOrDefault(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -21,14 +32,18 @@ func GetEnvOrDefault(key, defaultValue string) string {
 	return value
 }
 
-func IsValidTechStack(stack model.TechStack) bool {
-	switch stack {
-	case model.Rust, model.Go, model.Next, model.React, model.Axum, model.Postgres,
-		model.MySQL, model.Docker, model.ActixWeb, model.ChiRouter, model.Node:
-		return true
-	default:
-		return false
+// ClientIP prefers the first X-Forwarded-For entry since the API usually runs
+// behind a proxy, and falls back to the connection address
+func ClientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
+	return host
 }
 
 func IsValidRequestStatus(status model.RequestStatus) bool {